@@ -8,31 +8,54 @@
 //
 // • поддерживает работу с пользователями;
 //
-// • поддерживает массовую загрузку URL для сокращения;
+// • поддерживает массовую загрузку URL для сокращения, в том числе больших списков в формате JSON массива или NDJSON,
+// с результатом для каждого URL, либо с сохранением всех URL или ни одного;
 //
 // • позволяет получить сразу все сохраненные URL;
 //
-// • позволяет удалить сохраненный URL.
+// • позволяет удалить сохраненный URL;
+//
+// • предоставляет API администратора для модерации и блокировки URL;
+//
+// • проверяет сокращаемые URL: допустимые схемы, максимальную длину, ссылки на сам сервис и частные адреса, запрещенные домены.
+//
+// • показывает промежуточную страницу с адресом назначения, датой создания и количеством переходов
+// по запросу /{key}+ или /{key}?preview=1, а так же для URL с включенным признаком interstitial;
+//
+// • формирует QR код сокращенного URL в формате PNG или SVG;
+//
+// • позволяет изменить исходный URL сокращенного URL с сохранением истории изменений;
+//
+// • позволяет задавать название, заметки и теги сокращенных URL и фильтровать URL по тегу;
+//
+// • позволяет импортировать URL из CSV файла и экспортировать URL пользователя в CSV или JSONL;
 //
 // # Описание сервиса
 //
-// Для хранения данных, сервис поддерживает работу с базой данных (Postgresql), может хранить данные в файле, а так же в памяти.
+// Для хранения данных, сервис поддерживает работу с базой данных (Postgresql или SQLite), встроенной базой данных в файле (bbolt),
+// может хранить данные в файле, а так же в памяти.
+//
+// Файл конфигурации перечитывается без перезапуска сервиса по сигналу SIGHUP или при его изменении.
+// Команда "shortener config print" выводит итоговую конфигурацию с источниками значений, секреты маскируются.
 //
 // Сервис лишен возможности регистрации пользователя.
 // В момент обращения он ожидает специальным образом подписанную cookie, по которой попытается авторизовать пользователя.
 // Если авторизация не произойдет, то сервис выдаст в ответе новую cookie.
+// Вместо cookie токен можно передать в заголовке "Authorization: Bearer <token>", а вместо подписи HMAC использовать JWT.
 //
 // Сервис поддерживает сжатие (Gzip) при взаимодействии по протоколу HTTPS
 package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/Alheor/shorturl/internal/adminauth"
 	"github.com/Alheor/shorturl/internal/config"
 	"github.com/Alheor/shorturl/internal/http/handler"
 	"github.com/Alheor/shorturl/internal/http/server"
@@ -40,6 +63,7 @@ import (
 	"github.com/Alheor/shorturl/internal/repository"
 	"github.com/Alheor/shorturl/internal/service"
 	"github.com/Alheor/shorturl/internal/shutdown"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
 	"github.com/Alheor/shorturl/internal/userauth"
 )
 
@@ -53,10 +77,6 @@ var shutdownTimeout = 5 * time.Second
 
 func main() {
 
-	fmt.Printf("Build version: %s \n", buildVersion)
-	fmt.Printf("Build date: %s \n", buildDate)
-	fmt.Printf("Build commit: %s \n", buildCommit)
-
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Error(``, err.(error))
@@ -70,6 +90,14 @@ func main() {
 	shutdown.Init()
 	cfg := config.Load()
 
+	if args := flag.Args(); len(args) > 0 {
+		os.Exit(runCommand(args, &cfg))
+	}
+
+	fmt.Printf("Build version: %s \n", buildVersion)
+	fmt.Printf("Build date: %s \n", buildDate)
+	fmt.Printf("Build commit: %s \n", buildCommit)
+
 	var err error
 
 	err = logger.Init(nil)
@@ -77,16 +105,40 @@ func main() {
 		panic(err)
 	}
 
-	if cfg.SignatureKey == config.DefaultLSignatureKey {
+	err = cfg.Validate()
+	if err != nil {
+		logger.Fatal(`invalid configuration`, err)
+	}
+
+	err = logger.SetLevel(cfg.LogLevel)
+	if err != nil {
+		logger.Fatal(`error while set log level`, err)
+	}
+
+	if cfg.GetSignatureKeys()[0] == config.DefaultLSignatureKey {
 		logger.Error(`Used default signature key! Please change the key!`, nil)
 	}
 
-	if len(cfg.SignatureKey) == 0 {
-		logger.Fatal(`Signature key is empty`, nil)
+	err = userauth.Init(&cfg)
+	if err != nil {
+		logger.Fatal(`error while initialize user authentication`, err)
+	}
+
+	err = adminauth.Init(&cfg)
+	if err != nil {
+		logger.Fatal(`error while initialize admin authentication`, err)
+	}
+
+	err = urlpolicy.Init(ctx, &cfg)
+	if err != nil {
+		logger.Fatal(`error while initialize url policy`, err)
+	}
+
+	err = handler.Init(&cfg)
+	if err != nil {
+		logger.Fatal(`error while initialize HTTP handlers`, err)
 	}
 
-	userauth.Init(&cfg)
-	handler.Init(&cfg)
 	service.Init(&cfg)
 
 	shutdown.GetCloser().Add(func(ctx context.Context) error {
@@ -110,6 +162,22 @@ func main() {
 
 	server.StartServer(&cfg)
 
+	watcher := config.NewWatcher(cfg)
+	watcher.Subscribe(`logger`, func(options *config.Options) error {
+		return logger.SetLevel(options.LogLevel)
+	})
+	watcher.Subscribe(`userauth`, userauth.Init)
+	watcher.Subscribe(`urlpolicy`, func(options *config.Options) error {
+		return urlpolicy.Init(ctx, options)
+	})
+	watcher.Subscribe(`handler`, handler.Init)
+	watcher.Subscribe(`service`, func(options *config.Options) error {
+		service.Init(options)
+		return nil
+	})
+	watcher.Subscribe(`tls`, server.ReloadCertificate)
+	watcher.Watch(ctx, config.DefaultWatchInterval)
+
 	<-ctx.Done()
 
 	println("shutting down ...")
@@ -119,3 +187,25 @@ func main() {
 
 	shutdown.GetCloser().Close(shutdownCtx)
 }
+
+// runCommand выполнение команды вместо запуска сервиса. Возвращает код завершения.
+//
+// config print - вывод итоговой конфигурации с источниками значений, секреты маскируются.
+func runCommand(args []string, cfg *config.Options) int {
+	if len(args) != 2 || args[0] != `config` || args[1] != `print` {
+		fmt.Fprintln(os.Stderr, `unknown command, supported: config print`)
+		return 2
+	}
+
+	if err := config.Print(os.Stdout, cfg, config.GetSources()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, `invalid configuration: `+err.Error())
+		return 1
+	}
+
+	return 0
+}
@@ -1,21 +1,35 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Alheor/shorturl/internal/adminauth"
+	"github.com/Alheor/shorturl/internal/compress"
 	"github.com/Alheor/shorturl/internal/config"
 	"github.com/Alheor/shorturl/internal/http/handler"
+	"github.com/Alheor/shorturl/internal/http/router"
 	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/qrcode"
 	"github.com/Alheor/shorturl/internal/repository"
 	"github.com/Alheor/shorturl/internal/service"
 	"github.com/Alheor/shorturl/internal/shutdown"
 	"github.com/Alheor/shorturl/internal/urlhasher"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
+	"github.com/Alheor/shorturl/internal/userauth"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -141,6 +155,8 @@ func TestApiAddBatchUrlsSuccess(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
+	_ = os.Remove(cfg.FileStoragePath)
+
 	err = repository.Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
@@ -155,8 +171,8 @@ func TestApiAddBatchUrlsSuccess(t *testing.T) {
 			URL:    `/api/shorten/batch`,
 			cookie: getCookie(),
 			want: want{
-				code:     http.StatusCreated,
-				response: `[{"correlation_id":"id1","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/test1`) + `"},{"correlation_id":"id2","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/test2`) + `"}]`,
+				code:     http.StatusMultiStatus,
+				response: `[{"correlation_id":"id1","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/test1`) + `","status":"created"},{"correlation_id":"id2","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/test2`) + `","status":"created"}]`,
 				headers: map[string]string{
 					handler.HeaderContentType: handler.HeaderContentTypeJSON,
 				},
@@ -180,6 +196,8 @@ func TestApiAddAndGetBatchUrlsSuccess(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
+	_ = os.Remove(cfg.FileStoragePath)
+
 	err = repository.Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
@@ -194,8 +212,8 @@ func TestApiAddAndGetBatchUrlsSuccess(t *testing.T) {
 			URL:    `/api/shorten/batch`,
 			cookie: getCookie(),
 			want: want{
-				code:     http.StatusCreated,
-				response: `[{"correlation_id":"id1","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/test1`) + `"}]`,
+				code:     http.StatusMultiStatus,
+				response: `[{"correlation_id":"id1","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/test1`) + `","status":"created"}]`,
 				headers: map[string]string{
 					handler.HeaderContentType: handler.HeaderContentTypeJSON,
 				},
@@ -255,7 +273,7 @@ func TestApiAddBatchUrlsError(t *testing.T) {
 				handler.HeaderContentType: handler.HeaderContentTypeJSON,
 			},
 			method: http.MethodPost,
-			URL:    `/api/shorten/batch`,
+			URL:    `/api/shorten/batch?atomic=true`,
 			want: want{
 				code:     http.StatusBadRequest,
 				response: `{"error":"empty url list"}`,
@@ -270,7 +288,7 @@ func TestApiAddBatchUrlsError(t *testing.T) {
 				handler.HeaderContentType: handler.HeaderContentTypeJSON,
 			},
 			method: http.MethodPost,
-			URL:    `/api/shorten/batch`,
+			URL:    `/api/shorten/batch?atomic=true`,
 			want: want{
 				code:     http.StatusBadRequest,
 				response: `{"error":"Url '' invalid"}`,
@@ -285,7 +303,7 @@ func TestApiAddBatchUrlsError(t *testing.T) {
 				handler.HeaderContentType: handler.HeaderContentTypeJSON,
 			},
 			method: http.MethodPost,
-			URL:    `/api/shorten/batch`,
+			URL:    `/api/shorten/batch?atomic=true`,
 			want: want{
 				code:     http.StatusBadRequest,
 				response: `{"error":"Url 'invalid_url' invalid"}`,
@@ -300,7 +318,7 @@ func TestApiAddBatchUrlsError(t *testing.T) {
 				handler.HeaderContentType: handler.HeaderContentTypeJSON,
 			},
 			method: http.MethodPost,
-			URL:    `/api/shorten/batch`,
+			URL:    `/api/shorten/batch?atomic=true`,
 			want: want{
 				code:     http.StatusBadRequest,
 				response: `{"error":"Url '' invalid"}`,
@@ -315,7 +333,7 @@ func TestApiAddBatchUrlsError(t *testing.T) {
 				handler.HeaderContentType: handler.HeaderContentTypeJSON,
 			},
 			method: http.MethodPost,
-			URL:    `/api/shorten/batch`,
+			URL:    `/api/shorten/batch?atomic=true`,
 			want: want{
 				code:     http.StatusBadRequest,
 				response: `{"error":"empty correlation_id"}`,
@@ -353,7 +371,7 @@ func TestApiAddUrlUniqIndexError(t *testing.T) {
 	_, err = repository.Connection.Exec(ctx, `TRUNCATE short_url`)
 	require.NoError(t, err)
 
-	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test`)
+	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test`, ``)
 	require.NoError(t, err)
 
 	tests := []testData{
@@ -403,10 +421,10 @@ func TestApiGetAllUrlsFromDBSuccess(t *testing.T) {
 	_, err = repository.Connection.Exec(ctx, `TRUNCATE short_url`)
 	require.NoError(t, err)
 
-	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test1`)
+	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test1`, ``)
 	require.NoError(t, err)
 
-	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test2`)
+	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test2`, ``)
 	require.NoError(t, err)
 
 	tests := []testData{
@@ -518,13 +536,13 @@ func TestApiRemoveBatch(t *testing.T) {
 
 	var user1 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
 
-	hash1, err := repository.GetRepository().Add(context.Background(), user, targetURL+`/test1`)
+	hash1, err := repository.GetRepository().Add(context.Background(), user, targetURL+`/test1`, ``)
 	require.NoError(t, err)
 
-	hash2, err := repository.GetRepository().Add(context.Background(), user, targetURL+`/test2`)
+	hash2, err := repository.GetRepository().Add(context.Background(), user, targetURL+`/test2`, ``)
 	require.NoError(t, err)
 
-	hash3, err := repository.GetRepository().Add(context.Background(), user1, targetURL+`/test3`)
+	hash3, err := repository.GetRepository().Add(context.Background(), user1, targetURL+`/test3`, ``)
 	require.NoError(t, err)
 
 	tests := []testData{
@@ -581,3 +599,1487 @@ func TestApiRemoveBatch(t *testing.T) {
 
 	runTests(t, tests)
 }
+
+func TestApiRemoveBatchInMemory(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user1 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash1, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := repository.GetRepository().Add(ctx, user1, targetURL+`/test2`, ``)
+	require.NoError(t, err)
+
+	tests := []testData{
+		{
+			name:        `API remove batch urls success`,
+			requestBody: []byte(`["` + hash1 + `", "` + hash2 + `"]`),
+			headers: map[string]string{
+				handler.HeaderContentType: handler.HeaderContentTypeJSON,
+			},
+			method: http.MethodDelete,
+			URL:    `/api/user/urls`,
+			cookie: getCookie(),
+			want: want{
+				code: http.StatusAccepted,
+			},
+		},
+		{
+			name:   `get removed url`,
+			method: http.MethodGet,
+			URL:    `/` + hash1,
+			want: want{
+				code: http.StatusGone,
+			},
+		},
+		{
+			name:   `get url of another user`,
+			method: http.MethodGet,
+			URL:    `/` + hash2,
+			want: want{
+				code: http.StatusTemporaryRedirect,
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiUserInfoAndLogout(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	_, err = repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	_, err = repository.GetRepository().Add(ctx, user, targetURL+`/test2`, ``)
+	require.NoError(t, err)
+
+	tests := []testData{
+		{
+			name:    `API get user info success`,
+			headers: map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:  http.MethodGet,
+			URL:     `/api/user/me`,
+			cookie:  getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"user_id":"` + user.ID + `","url_count":2}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:    `API get user info new user`,
+			headers: map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:  http.MethodGet,
+			URL:     `/api/user/me`,
+			cookie: &http.Cookie{
+				Name:  models.CookiesName,
+				Value: `aW52YWxpZF92YWx1ZV9pbnZhbGlkX3ZhbHVlX2ludmFsaWRfdmFsdWUK`,
+			},
+			want: want{
+				code:     http.StatusUnauthorized,
+				response: `{"error":"Unauthorized"}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:   `API logout success`,
+			method: http.MethodPost,
+			URL:    `/api/user/logout`,
+			cookie: getCookie(),
+			want: want{
+				code:    http.StatusNoContent,
+				headers: map[string]string{`Set-Cookie`: models.CookiesName + `=; Path=/; Max-Age=0; HttpOnly; SameSite=Lax`},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiTransferAndClaim(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user2 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	token, _ := userauth.NewTransferToken(user)
+
+	tests := []testData{
+		{
+			name:        `API claim with invalid token`,
+			requestBody: []byte(`{"token":"invalid"}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPost,
+			URL:         `/api/user/claim`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"invalid or expired transfer token"}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:        `API claim own token`,
+			requestBody: []byte(`{"token":"` + token + `"}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPost,
+			URL:         `/api/user/claim`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"token belongs to current user"}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:        `API claim success`,
+			requestBody: []byte(`{"token":"` + token + `"}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPost,
+			URL:         `/api/user/claim`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"moved":1}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:        `API claim used token`,
+			requestBody: []byte(`{"token":"` + token + `"}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPost,
+			URL:         `/api/user/claim`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"invalid or expired transfer token"}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:    `API get user info after claim`,
+			headers: map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:  http.MethodGet,
+			URL:     `/api/user/me`,
+			cookie:  getUserCookie(user2),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"user_id":"` + user2.ID + `","url_count":1}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:   `get moved url success`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			cookie: getCookie(),
+			want: want{
+				code:    http.StatusTemporaryRedirect,
+				headers: map[string]string{handler.HeaderLocation: targetURL + `/test1`},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiAdmin(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+	cfg.AdminToken = `admin_token`
+	cfg.TrustedSubnet = `127.0.0.0/8`
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	err = adminauth.Init(&cfg)
+	require.NoError(t, err)
+	defer adminauth.Init(&config.Options{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	adminHeaders := map[string]string{
+		adminauth.HeaderAuthorization: `Bearer admin_token`,
+		handler.HeaderContentType:     handler.HeaderContentTypeJSON,
+	}
+
+	info, err := repository.GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+
+	createdAt, err := json.Marshal(info.CreatedAt)
+	require.NoError(t, err)
+
+	urlInfo := `{"short_key":"` + hash + `","original_url":"` + targetURL + `/test1","user_id":"` + user.ID + `","is_deleted":false,"is_blocked":%s%s,"redirect":{"status_code":0,"pass_query":false},"created_at":` + string(createdAt) + `,"clicks":%d}`
+
+	tests := []testData{
+		{
+			name:   `admin API without token`,
+			method: http.MethodGet,
+			URL:    `/api/admin/urls/` + hash,
+			want:   want{code: http.StatusUnauthorized},
+		},
+		{
+			name:   `admin API key usage without token`,
+			method: http.MethodGet,
+			URL:    `/api/admin/keys/usage`,
+			want:   want{code: http.StatusUnauthorized},
+		},
+		{
+			name:    `admin API get unknown url`,
+			headers: adminHeaders,
+			method:  http.MethodGet,
+			URL:     `/api/admin/urls/unknown`,
+			want: want{
+				code:     http.StatusNotFound,
+				response: `{"error":"Unknown identifier"}`,
+			},
+		},
+		{
+			name:    `admin API get url`,
+			headers: adminHeaders,
+			method:  http.MethodGet,
+			URL:     `/api/admin/urls/` + hash,
+			want: want{
+				code:     http.StatusOK,
+				response: fmt.Sprintf(urlInfo, `false`, ``, 0),
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:        `admin API block url without reason`,
+			requestBody: []byte(`{}`),
+			headers:     adminHeaders,
+			method:      http.MethodPut,
+			URL:         `/api/admin/urls/` + hash + `/block`,
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"reason required"}`,
+			},
+		},
+		{
+			name:        `admin API block url`,
+			requestBody: []byte(`{"reason":"phishing"}`),
+			headers:     adminHeaders,
+			method:      http.MethodPut,
+			URL:         `/api/admin/urls/` + hash + `/block`,
+			want: want{
+				code:     http.StatusOK,
+				response: fmt.Sprintf(urlInfo, `true`, `,"block_reason":"phishing"`, 0),
+			},
+		},
+		{
+			name:   `get blocked url`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusUnavailableForLegalReasons,
+				response: "Link blocked by moderator: phishing\n",
+			},
+		},
+		{
+			name:    `admin API unblock url`,
+			headers: adminHeaders,
+			method:  http.MethodDelete,
+			URL:     `/api/admin/urls/` + hash + `/block`,
+			want: want{
+				code:     http.StatusOK,
+				response: fmt.Sprintf(urlInfo, `false`, ``, 0),
+			},
+		},
+		{
+			name:   `get unblocked url`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			cookie: getCookie(),
+			want: want{
+				code:    http.StatusTemporaryRedirect,
+				headers: map[string]string{handler.HeaderLocation: targetURL + `/test1`},
+			},
+		},
+		{
+			name:    `admin API get user urls with invalid id`,
+			headers: adminHeaders,
+			method:  http.MethodGet,
+			URL:     `/api/admin/users/invalid/urls`,
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"invalid user id"}`,
+			},
+		},
+		{
+			name:    `admin API get user urls`,
+			headers: adminHeaders,
+			method:  http.MethodGet,
+			URL:     `/api/admin/users/` + user.ID + `/urls`,
+			want: want{
+				code:     http.StatusOK,
+				response: `[` + fmt.Sprintf(urlInfo, `false`, ``, 1) + `]`,
+			},
+		},
+		{
+			name:    `admin API purge user`,
+			headers: adminHeaders,
+			method:  http.MethodDelete,
+			URL:     `/api/admin/users/` + user.ID,
+			want: want{
+				code:     http.StatusOK,
+				response: `{"removed":1}`,
+			},
+		},
+		{
+			name:   `get purged url`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "Unknown identifier\n",
+			},
+		},
+	}
+
+	routes := router.GetRoutes()
+	routes.Mount(`/api/admin`, router.GetAdminRoutes())
+
+	runTestsWithRoutes(t, routes, tests)
+}
+
+func TestApiAddUrlPolicy(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = urlpolicy.Init(ctx, &cfg)
+	require.NoError(t, err)
+	defer urlpolicy.Init(context.Background(), &config.Options{})
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	tests := []testData{
+		{
+			name:        `generate short url with javascript scheme error`,
+			requestBody: []byte(`javascript:alert(1)`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeTextPlain},
+			method:      http.MethodPost,
+			URL:         `/`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: "url rejected: scheme \"javascript\" is not allowed\n",
+			},
+		},
+		{
+			name:        `API generate short url to service itself error`,
+			requestBody: []byte(`{"url":"` + cfg.BaseHost + `/abc"}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPost,
+			URL:         `/api/shorten`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"url rejected: links to the service itself are not allowed"}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name: `API batch with private host error`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `"},` +
+				`{"correlation_id":"2","original_url":"http://10.0.0.1/admin"}]`),
+			headers: map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:  http.MethodPost,
+			URL:     `/api/shorten/batch?atomic=true`,
+			cookie:  getCookie(),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"correlation_id '2': url rejected: private or loopback host \"10.0.0.1\" is not allowed"}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiRedirectSettings(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user2 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1?id=1`, ``)
+	require.NoError(t, err)
+
+	settingsURL := `/api/user/urls/` + hash + `/redirect`
+
+	tests := []testData{
+		{
+			name:   `get url with query and default settings`,
+			method: http.MethodGet,
+			URL:    `/` + hash + `?ref=mail`,
+			cookie: getCookie(),
+			want: want{
+				code:    http.StatusTemporaryRedirect,
+				headers: map[string]string{handler.HeaderLocation: targetURL + `/test1?id=1`},
+			},
+		},
+		{
+			name:   `API get default redirect settings`,
+			method: http.MethodGet,
+			URL:    settingsURL,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"status_code":0,"pass_query":false}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:   `API get redirect settings of another user`,
+			method: http.MethodGet,
+			URL:    settingsURL,
+			cookie: getUserCookie(user2),
+			want: want{
+				code:     http.StatusNotFound,
+				response: `{"error":"Unknown identifier"}`,
+			},
+		},
+		{
+			name:        `API set invalid redirect status`,
+			requestBody: []byte(`{"status_code":200}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPut,
+			URL:         settingsURL,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"status code 200 is not allowed, use 301, 302, 307 or 308"}`,
+			},
+		},
+		{
+			name:        `API set redirect settings of another user`,
+			requestBody: []byte(`{"status_code":301}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPut,
+			URL:         settingsURL,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusNotFound,
+				response: `{"error":"Unknown identifier"}`,
+			},
+		},
+		{
+			name:        `API set redirect settings`,
+			requestBody: []byte(`{"status_code":301,"pass_query":true,"utm_params":{"utm_source":"short"}}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPut,
+			URL:         settingsURL,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"status_code":301,"pass_query":true,"utm_params":{"utm_source":"short"}}`,
+				headers:  map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			},
+		},
+		{
+			name:   `get url with query and custom settings`,
+			method: http.MethodGet,
+			URL:    `/` + hash + `?ref=mail`,
+			cookie: getCookie(),
+			want: want{
+				code:    http.StatusMovedPermanently,
+				headers: map[string]string{handler.HeaderLocation: targetURL + `/test1?id=1&ref=mail&utm_source=short`},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiPreview(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+	cfg.TemplatesDir = t.TempDir()
+
+	err := os.WriteFile(cfg.TemplatesDir+`/preview.html`, []byte(`{{.ShortKey}} {{.Destination}} {{.Clicks}}`), 0644)
+	require.NoError(t, err)
+
+	err = logger.Init(nil)
+	require.NoError(t, err)
+
+	err = handler.Init(&cfg)
+	require.NoError(t, err)
+	defer handler.Init(&config.Options{BaseHost: cfg.BaseHost})
+
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	htmlHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeTextHTML + `; charset=utf-8`}
+
+	tests := []testData{
+		{
+			name:   `preview by suffix`,
+			method: http.MethodGet,
+			URL:    `/` + hash + `+`,
+			want: want{
+				code:     http.StatusOK,
+				response: hash + ` ` + targetURL + `/test1 0`,
+				headers:  htmlHeaders,
+			},
+		},
+		{
+			name:   `get url`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			want: want{
+				code:    http.StatusTemporaryRedirect,
+				headers: map[string]string{handler.HeaderLocation: targetURL + `/test1`},
+			},
+		},
+		{
+			name:   `preview by query param`,
+			method: http.MethodGet,
+			URL:    `/` + hash + `?preview=1`,
+			want: want{
+				code:     http.StatusOK,
+				response: hash + ` ` + targetURL + `/test1 1`,
+				headers:  htmlHeaders,
+			},
+		},
+		{
+			name:   `preview of unknown url`,
+			method: http.MethodGet,
+			URL:    `/unknown+`,
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "Unknown identifier\n",
+			},
+		},
+		{
+			name:        `API enable interstitial`,
+			requestBody: []byte(`{"interstitial":true}`),
+			headers:     map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON},
+			method:      http.MethodPut,
+			URL:         `/api/user/urls/` + hash + `/redirect`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: `{"status_code":0,"pass_query":false,"interstitial":true}`,
+			},
+		},
+		{
+			name:   `get url with interstitial`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			want: want{
+				code:     http.StatusOK,
+				response: hash + ` ` + targetURL + `/test1 1`,
+				headers:  htmlHeaders,
+			},
+		},
+		{
+			name:   `preview after interstitial`,
+			method: http.MethodGet,
+			URL:    `/` + hash + `+`,
+			want: want{
+				code:     http.StatusOK,
+				response: hash + ` ` + targetURL + `/test1 2`,
+				headers:  htmlHeaders,
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiPreviewDefaultTemplate(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	err = handler.Init(&cfg)
+	require.NoError(t, err)
+
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1?a=1&b=<2>`, ``)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(router.GetRoutes())
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + `/` + hash + `+`)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `<a href="`+targetURL+`/test1?a=1&amp;b=%3c2%3e"`)
+	assert.Contains(t, string(body), `<dd>0</dd>`)
+	assert.NotContains(t, string(body), `<2>`)
+}
+
+func TestApiQRCode(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user2 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	blockedHash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test3`, ``)
+	require.NoError(t, err)
+
+	_, err = repository.GetRepository().SetBlocked(ctx, blockedHash, true, `spam`)
+	require.NoError(t, err)
+
+	png, err := qrcode.Encode(cfg.BaseHost+`/`+hash, &qrcode.Options{Format: qrcode.FormatPNG, Size: qrcode.DefaultSize, ECC: qrcode.DefaultECC})
+	require.NoError(t, err)
+
+	svg, err := qrcode.Encode(cfg.BaseHost+`/`+hash, &qrcode.Options{Format: qrcode.FormatSVG, Size: 512, ECC: `H`})
+	require.NoError(t, err)
+
+	tests := []testData{
+		{
+			name:   `API get png qr code`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/` + hash + `/qr`,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: string(png),
+				headers:  map[string]string{handler.HeaderContentType: `image/png`, handler.HeaderCacheControl: `private, max-age=86400`},
+			},
+		},
+		{
+			name:   `API get svg qr code`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/` + hash + `/qr?format=svg&size=512&ecc=H`,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: string(svg),
+				headers:  map[string]string{handler.HeaderContentType: `image/svg+xml`},
+			},
+		},
+		{
+			name:   `API get qr code with invalid size`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/` + hash + `/qr?size=1`,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"size must be between 64 and 2048"}`,
+			},
+		},
+		{
+			name:   `API get qr code of another user url`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/` + hash + `/qr`,
+			cookie: getUserCookie(user2),
+			want: want{
+				code:     http.StatusNotFound,
+				response: `{"error":"Unknown identifier"}`,
+			},
+		},
+		{
+			name:   `API get qr code of blocked url`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/` + blockedHash + `/qr`,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusUnavailableForLegalReasons,
+				response: `{"error":"Link blocked by moderator: spam"}`,
+			},
+		},
+	}
+
+	runTests(t, tests)
+
+	ts := httptest.NewServer(router.GetRoutes())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+`/api/user/urls/`+hash+`/qr`, nil)
+	require.NoError(t, err)
+	req.AddCookie(getCookie())
+
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	etag := resp.Header.Get(handler.HeaderETag)
+	require.NotEmpty(t, etag)
+
+	req.Header.Set(handler.HeaderIfNoneMatch, etag)
+
+	resp, err = ts.Client().Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Equal(t, etag, resp.Header.Get(handler.HeaderETag))
+}
+
+func TestApiUpdateURL(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user2 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test1`, ``)
+	require.NoError(t, err)
+
+	otherHash, err := repository.GetRepository().Add(ctx, user, targetURL+`/test2`, ``)
+	require.NoError(t, err)
+
+	jsonHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON}
+
+	tests := []testData{
+		{
+			name:        `API update url without changes`,
+			requestBody: []byte(`{}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPatch,
+			URL:         `/api/user/urls/` + hash,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"nothing to update"}`,
+			},
+		},
+		{
+			name:        `API update url of another user`,
+			requestBody: []byte(`{"url":"` + targetURL + `/new"}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPatch,
+			URL:         `/api/user/urls/` + hash,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusNotFound,
+				response: `{"error":"Unknown identifier"}`,
+			},
+		},
+		{
+			name:        `API update url to existing url`,
+			requestBody: []byte(`{"url":"` + targetURL + `/test2"}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPatch,
+			URL:         `/api/user/urls/` + hash,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusConflict,
+				response: `{"result":"` + cfg.BaseHost + `/` + otherHash + `"}`,
+			},
+		},
+		{
+			name:        `API update url to rejected url`,
+			requestBody: []byte(`{"url":"ftp://example.com/test"}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPatch,
+			URL:         `/api/user/urls/` + hash,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"url rejected: scheme \"ftp\" is not allowed"}`,
+			},
+		},
+		{
+			name:   `API get empty url history`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/` + hash + `/history`,
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusOK,
+				response: `[]`,
+			},
+		},
+	}
+
+	runTests(t, tests)
+
+	// Ответ содержит дату изменения, поэтому проверяется отдельно
+	ts := httptest.NewServer(router.GetRoutes())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPatch, ts.URL+`/api/user/urls/`+hash, strings.NewReader(`{"url":"`+targetURL+`/new"}`))
+	require.NoError(t, err)
+	req.AddCookie(getCookie())
+	req.Header.Set(handler.HeaderContentType, handler.HeaderContentTypeJSON)
+
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+
+	var response models.APIUpdateURLResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, cfg.BaseHost+`/`+hash, response.ShortURL)
+	assert.Equal(t, targetURL+`/new`, response.OriginalURL)
+	require.Len(t, response.History, 1)
+	assert.Equal(t, targetURL+`/test1`, response.History[0].PreviousURL)
+	assert.Equal(t, targetURL+`/new`, response.History[0].NewURL)
+
+	tests = []testData{
+		{
+			name:   `get updated url`,
+			method: http.MethodGet,
+			URL:    `/` + hash,
+			want: want{
+				code:    http.StatusTemporaryRedirect,
+				headers: map[string]string{handler.HeaderLocation: targetURL + `/new`},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiURLMeta(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user2 = &models.User{ID: `2a32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash := urlhasher.GetHash(targetURL + `/meta1`)
+	batchHash := urlhasher.GetHash(targetURL + `/meta2`)
+	jsonHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON}
+
+	tests := []testData{
+		{
+			name:        `API add url with invalid tag`,
+			requestBody: []byte(`{"url":"` + targetURL + `/meta1","tags":["bad tag"]}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"tag \"bad tag\" contains invalid characters"}`,
+			},
+		},
+		{
+			name:        `API add url with meta`,
+			requestBody: []byte(`{"url":"` + targetURL + `/meta1","title":"Spring","notes":"print","tags":["Print","spring"]}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusCreated,
+				response: `{"result":"` + cfg.BaseHost + `/` + hash + `"}`,
+			},
+		},
+		{
+			name:        `API add batch with invalid meta`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/meta2","tags":[""]}]`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch?atomic=true`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"correlation_id '1': tag length must be between 1 and 50 characters"}`,
+			},
+		},
+		{
+			name:        `API add batch with meta`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/meta2","title":"Batch","tags":["batch"]}]`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch`,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusMultiStatus,
+				response: `[{"correlation_id":"1","short_url":"` + cfg.BaseHost + `/` + batchHash + `","status":"created"}]`,
+			},
+		},
+		{
+			name:   `API get urls by tag`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls?tag=PRINT`,
+			cookie: getUserCookie(user2),
+			want: want{
+				code: http.StatusOK,
+				response: `[{"original_url":"` + targetURL + `/meta1","short_url":"` + cfg.BaseHost + `/` + hash +
+					`","title":"Spring","notes":"print","tags":["print","spring"]}]`,
+			},
+		},
+		{
+			name:   `API get urls by unknown tag`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls?tag=unknown`,
+			cookie: getUserCookie(user2),
+			want: want{
+				code: http.StatusNoContent,
+			},
+		},
+		{
+			name:        `API update meta`,
+			requestBody: []byte(`{"notes":"","tags":["batch"]}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPatch,
+			URL:         `/api/user/urls/` + hash,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code: http.StatusOK,
+				response: `{"short_url":"` + cfg.BaseHost + `/` + hash + `","original_url":"` + targetURL + `/meta1","history":[],` +
+					`"title":"Spring","tags":["batch"]}`,
+			},
+		},
+		{
+			name:        `API update meta with invalid title`,
+			requestBody: []byte(`{"title":"` + strings.Repeat(`a`, 201) + `"}`),
+			headers:     jsonHeaders,
+			method:      http.MethodPatch,
+			URL:         `/api/user/urls/` + hash,
+			cookie:      getUserCookie(user2),
+			want: want{
+				code:     http.StatusUnprocessableEntity,
+				response: `{"error":"title is longer than 200 characters"}`,
+			},
+		},
+		{
+			name:   `API get urls by updated tag`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls?tag=spring`,
+			cookie: getUserCookie(user2),
+			want: want{
+				code: http.StatusNoContent,
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestApiImportExport(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	var user3 = &models.User{ID: `3b43bb66-c3cb-74cb-af2d-6eb17f2c721f`}
+
+	hash1 := urlhasher.GetHash(targetURL + `/import1`)
+	hash2 := urlhasher.GetHash(targetURL + `/import2`)
+
+	csvHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeCSV}
+	importCSV := "\uFEFFurl,alias,tags,expires_at,title\n" +
+		targetURL + "/import1,," + `"Docs, team"` + ",,Import\n" +
+		",,,,\n" +
+		"ftp://example.com/import,,,,\n" +
+		targetURL + "/import3,,,2030-01-01T00:00:00Z,\n" +
+		targetURL + "/import4,wrong,,,\n" +
+		targetURL + "/import2," + hash2 + ",team,,\n"
+
+	tests := []testData{
+		{
+			name:        `API import without url column`,
+			requestBody: []byte("link\n" + targetURL + "\n"),
+			headers:     csvHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/user/urls/import`,
+			cookie:      getUserCookie(user3),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"url column required"}`,
+			},
+		},
+		{
+			name:        `API import`,
+			requestBody: []byte(importCSV),
+			headers:     csvHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/user/urls/import`,
+			cookie:      getUserCookie(user3),
+			want: want{
+				code: http.StatusOK,
+				response: `{"created":2,"existing":0,"failed":4,"rows":[` +
+					`{"line":2,"status":"created","short_url":"` + cfg.BaseHost + `/` + hash1 + `"},` +
+					`{"line":3,"status":"error","error":"url required"},` +
+					`{"line":4,"status":"error","error":"url rejected: scheme \"ftp\" is not allowed"},` +
+					`{"line":5,"status":"error","error":"expires_at is not supported, url expiration is not implemented"},` +
+					`{"line":6,"status":"error","error":"alias \"wrong\" is not supported, short key is generated from the url"},` +
+					`{"line":7,"status":"created","short_url":"` + cfg.BaseHost + `/` + hash2 + `"}]}`,
+			},
+		},
+		{
+			name:   `API export with unknown format`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/export?format=xml`,
+			cookie: getUserCookie(user3),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"format must be csv or jsonl"}`,
+			},
+		},
+		{
+			name:   `API export csv`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/export?tag=docs`,
+			cookie: getUserCookie(user3),
+			want: want{
+				code: http.StatusOK,
+				response: "url,alias,short_url,title,notes,tags\n" +
+					targetURL + "/import1," + hash1 + "," + cfg.BaseHost + "/" + hash1 + ",Import,," + `"docs,team"` + "\n",
+				headers: map[string]string{
+					handler.HeaderContentType:        handler.HeaderContentTypeCSV,
+					handler.HeaderContentDisposition: `attachment; filename="urls.csv"`,
+				},
+			},
+		},
+		{
+			name:        `API import exported csv`,
+			requestBody: []byte("url,alias\n" + targetURL + "/import1," + hash1 + "\n" + targetURL + "/import6," + hash2 + "\n"),
+			headers:     csvHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/user/urls/import`,
+			cookie:      getUserCookie(user3),
+			want: want{
+				code: http.StatusOK,
+				response: `{"created":0,"existing":1,"failed":1,"rows":[` +
+					`{"line":2,"status":"exists","short_url":"` + cfg.BaseHost + `/` + hash1 + `"},` +
+					`{"line":3,"status":"error","error":"alias \"` + hash2 + `\" is not supported, short key is generated from the url"}]}`,
+			},
+		},
+		{
+			name:   `API export jsonl`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/export?format=jsonl&tag=docs`,
+			cookie: getUserCookie(user3),
+			want: want{
+				code: http.StatusOK,
+				response: `{"original_url":"` + targetURL + `/import1","short_url":"` + cfg.BaseHost + `/` + hash1 +
+					`","title":"Import","tags":["docs","team"]}` + "\n",
+				headers: map[string]string{handler.HeaderContentType: handler.HeaderContentTypeNDJSON},
+			},
+		},
+		{
+			name:   `API export without urls`,
+			method: http.MethodGet,
+			URL:    `/api/user/urls/export`,
+			cookie: getUserCookie(&models.User{ID: `4c54cc77-d4dc-85dc-b03e-7fc28a3d832a`}),
+			want: want{
+				code:     http.StatusOK,
+				response: "url,alias,short_url,title,notes,tags\n",
+			},
+		},
+	}
+
+	runTests(t, tests)
+
+	// Тело, сжатое без Content-Type application/x-gzip, разжимается потоково
+	body, err := compress.Compress([]byte("url\n" + targetURL + "/import5\n"))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(router.GetRoutes())
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+`/api/user/urls/import`, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.AddCookie(getUserCookie(user3))
+	req.Header.Set(handler.HeaderContentType, handler.HeaderContentTypeCSV)
+	req.Header.Set(handler.HeaderContentEncoding, handler.HeaderContentEncodingGzip)
+
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+
+	var response models.APIImportResponse
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, response.Created)
+	require.Len(t, response.Rows, 1)
+	assert.Equal(t, cfg.BaseHost+`/`+urlhasher.GetHash(targetURL+`/import5`), response.Rows[0].ShortURL)
+
+	cfg.BatchMaxBodySize = 16
+	require.NoError(t, handler.Init(&cfg))
+
+	defer func() {
+		cfg = config.Load()
+		require.NoError(t, handler.Init(&cfg))
+	}()
+
+	runTests(t, []testData{
+		{
+			name:        `API import body too large`,
+			requestBody: []byte("url\n" + targetURL + "/import7\n"),
+			headers:     csvHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/user/urls/import`,
+			cookie:      getUserCookie(user3),
+			want: want{
+				code:     http.StatusRequestEntityTooLarge,
+				response: `{"error":"request body is larger than 16 bytes"}`,
+			},
+		},
+	})
+}
+
+func TestApiAddBatchStreaming(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	service.Init(&cfg)
+
+	cfg.BatchMaxItems = 3
+	cfg.BatchMaxBodySize = 300
+	require.NoError(t, handler.Init(&cfg))
+
+	defer func() {
+		cfg = config.Load()
+		require.NoError(t, handler.Init(&cfg))
+	}()
+
+	ndjsonHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeNDJSON}
+	jsonHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON}
+
+	tests := []testData{
+		{
+			name: `API add batch ndjson`,
+			requestBody: []byte(`{"correlation_id":"1","original_url":"` + targetURL + `/nd1"}` + "\n" +
+				`{"correlation_id":"2","original_url":"` + targetURL + `/nd2"}` + "\n"),
+			headers: ndjsonHeaders,
+			method:  http.MethodPost,
+			URL:     `/api/shorten/batch`,
+			cookie:  getCookie(),
+			want: want{
+				code: http.StatusMultiStatus,
+				response: `{"correlation_id":"1","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/nd1`) + `","status":"created"}` + "\n" +
+					`{"correlation_id":"2","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/nd2`) + `","status":"created"}` + "\n",
+				headers: ndjsonHeaders,
+			},
+		},
+		{
+			name:        `API add batch ndjson empty`,
+			requestBody: []byte("\n"),
+			headers:     ndjsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"empty url list"}`,
+			},
+		},
+		{
+			name: `API add batch too many urls`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/m1"},` +
+				`{"correlation_id":"2","original_url":"` + targetURL + `/m2"},` +
+				`{"correlation_id":"3","original_url":"` + targetURL + `/m3"},` +
+				`{"correlation_id":"4","original_url":"` + targetURL + `/m4"}]`),
+			headers: jsonHeaders,
+			method:  http.MethodPost,
+			URL:     `/api/shorten/batch`,
+			cookie:  getCookie(),
+			want: want{
+				code:     http.StatusRequestEntityTooLarge,
+				response: `{"error":"too many urls, max 3"}`,
+			},
+		},
+		{
+			name:        `API add batch body too large`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/` + strings.Repeat(`a`, 300) + `"}]`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusRequestEntityTooLarge,
+				response: `{"error":"request body is larger than 300 bytes"}`,
+			},
+		},
+	}
+
+	runTests(t, tests)
+
+	// Ошибка после сохранения первой части URL возвращается последним элементом ответа
+	cfg.BatchMaxItems = 0
+	cfg.BatchMaxBodySize = 0
+	require.NoError(t, handler.Init(&cfg))
+
+	var request, response strings.Builder
+
+	request.WriteString(`[`)
+	response.WriteString(`[`)
+
+	for i := 0; i < 1000; i++ {
+		id := strconv.Itoa(i)
+		URL := targetURL + `/stream` + id
+
+		request.WriteString(`{"correlation_id":"` + id + `","original_url":"` + URL + `"},`)
+
+		if i > 0 {
+			response.WriteString(`,`)
+		}
+
+		response.WriteString(`{"correlation_id":"` + id + `","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(URL) + `","status":"created"}`)
+	}
+
+	request.WriteString(`{"correlation_id":"1000","original_url":}]`)
+	response.WriteString(`,{"error":"invalid body"}]`)
+
+	runTests(t, []testData{
+		{
+			name:        `API add batch error after first chunk`,
+			requestBody: []byte(request.String()),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusMultiStatus,
+				response: response.String(),
+			},
+		},
+	})
+}
+
+func TestApiAddBatchPartial(t *testing.T) {
+	shutdown.Init()
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	handler.Init(&cfg)
+	service.Init(&cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	jsonHeaders := map[string]string{handler.HeaderContentType: handler.HeaderContentTypeJSON}
+
+	tests := []testData{
+		{
+			name:        `API add batch with invalid atomic param`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/partial1"}]`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch?atomic=maybe`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"atomic must be true or false"}`,
+			},
+		},
+		{
+			name: `API add batch partial success`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/partial1"},` +
+				`{"correlation_id":"2","original_url":"invalid_url"},` +
+				`{"correlation_id":"3","original_url":"ftp://example.com/partial"},` +
+				`{"correlation_id":"","original_url":"` + targetURL + `/partial2"},` +
+				`{"correlation_id":"5","original_url":"` + targetURL + `/partial3","tags":["bad tag"]},` +
+				`{"correlation_id":"6","original_url":"` + targetURL + `/partial4"}]`),
+			headers: jsonHeaders,
+			method:  http.MethodPost,
+			URL:     `/api/shorten/batch`,
+			cookie:  getCookie(),
+			want: want{
+				code: http.StatusMultiStatus,
+				response: `[{"correlation_id":"1","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/partial1`) + `","status":"created"},` +
+					`{"correlation_id":"2","status":"invalid","error":"Url 'invalid_url' invalid"},` +
+					`{"correlation_id":"3","status":"blocked","error":"url rejected: scheme \"ftp\" is not allowed"},` +
+					`{"correlation_id":"","status":"invalid","error":"empty correlation_id"},` +
+					`{"correlation_id":"5","status":"invalid","error":"tag \"bad tag\" contains invalid characters"},` +
+					`{"correlation_id":"6","short_url":"` + cfg.BaseHost + `/` + urlhasher.GetHash(targetURL+`/partial4`) + `","status":"created"}]`,
+			},
+		},
+		{
+			name:        `API add batch atomic with invalid url`,
+			requestBody: []byte(`[{"correlation_id":"1","original_url":"` + targetURL + `/partial5"},{"correlation_id":"2","original_url":"invalid_url"}]`),
+			headers:     jsonHeaders,
+			method:      http.MethodPost,
+			URL:         `/api/shorten/batch?atomic=true`,
+			cookie:      getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: `{"error":"Url 'invalid_url' invalid"}`,
+			},
+		},
+		{
+			name:   `API get url of rejected atomic batch`,
+			method: http.MethodGet,
+			URL:    `/` + urlhasher.GetHash(targetURL+`/partial5`),
+			cookie: getCookie(),
+			want: want{
+				code:     http.StatusBadRequest,
+				response: "Unknown identifier\n",
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
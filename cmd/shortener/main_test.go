@@ -131,7 +131,7 @@ func TestGetUrl(t *testing.T) {
 	err = repository.Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
-	_, err = repository.GetRepository().Add(ctx, user, targetURL+`/test`)
+	_, err = repository.GetRepository().Add(ctx, user, targetURL+`/test`, ``)
 	require.NoError(t, err)
 
 	tests := []testData{
@@ -241,7 +241,7 @@ func TestAddUrlUniqIndexError(t *testing.T) {
 	_, err = repository.Connection.Exec(ctx, `TRUNCATE short_url`)
 	require.NoError(t, err)
 
-	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test`)
+	_, err = repository.GetRepository().Add(context.Background(), user, targetURL+`/test`, ``)
 	require.NoError(t, err)
 
 	tests := []testData{
@@ -264,8 +264,12 @@ func TestAddUrlUniqIndexError(t *testing.T) {
 }
 
 func runTests(t *testing.T, tests []testData) {
+	runTestsWithRoutes(t, router.GetRoutes(), tests)
+}
+
+func runTestsWithRoutes(t *testing.T, routes http.Handler, tests []testData) {
 
-	ts := httptest.NewServer(router.GetRoutes())
+	ts := httptest.NewServer(routes)
 	defer ts.Close()
 
 	for _, test := range tests {
@@ -323,6 +327,10 @@ func runTests(t *testing.T, tests []testData) {
 }
 
 func getCookie() *http.Cookie {
+	return getUserCookie(user)
+}
+
+func getUserCookie(user *models.User) *http.Cookie {
 	cookiesValue := string(userauth.GetSignature(user.ID)) + user.ID
 
 	return &http.Cookie{
@@ -0,0 +1,110 @@
+// Package adminauth - сервис авторизации администратора.
+//
+// # Описание
+//
+// Авторизует администратора по токену из заголовка "Authorization: Bearer <token>".
+// Если задана доверенная подсеть, то дополнительно проверяется, что запрос пришел из нее.
+// Если токен администратора не задан конфигурацией, то API администратора отключено.
+package adminauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+type contextKeyAdmin string
+
+// contextValueName - имя ключа администратора при передаче через контекст.
+const contextValueName contextKeyAdmin = `xAdmin`
+
+// HeaderAuthorization header "Authorization" name.
+const HeaderAuthorization = `Authorization`
+
+const bearerPrefix = `Bearer `
+
+var token []byte
+
+var trustedSubnet *net.IPNet
+
+// Init Подготовка авторизации администратора к работе.
+func Init(cfg *config.Options) error {
+	token = []byte(cfg.AdminToken)
+	trustedSubnet = nil
+
+	if cfg.TrustedSubnet == `` {
+		return nil
+	}
+
+	_, subnet, err := net.ParseCIDR(cfg.TrustedSubnet)
+	if err != nil {
+		return errors.New(`invalid trusted subnet: ` + err.Error())
+	}
+
+	trustedSubnet = subnet
+
+	return nil
+}
+
+// IsEnabled Включено ли API администратора.
+func IsEnabled() bool {
+	return len(token) > 0
+}
+
+// AdminHTTPHandler - middleware для авторизации администратора.
+func AdminHTTPHandler(f http.HandlerFunc) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if !IsEnabled() {
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		ip := clientIP(req)
+
+		if trustedSubnet != nil && (ip == nil || !trustedSubnet.Contains(ip)) {
+			logger.Info(`admin API access from untrusted address`, zap.String("remote_addr", req.RemoteAddr))
+			resp.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		header := req.Header.Get(HeaderAuthorization)
+		if !strings.HasPrefix(header, bearerPrefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, bearerPrefix)), token) != 1 {
+
+			logger.Info(`admin API access with invalid token`, zap.String("remote_addr", req.RemoteAddr))
+			resp.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		actor := req.RemoteAddr
+		if ip != nil {
+			actor = ip.String()
+		}
+
+		f(resp, req.WithContext(context.WithValue(req.Context(), contextValueName, actor)))
+	}
+}
+
+// GetActor Получение идентификатора администратора (адреса клиента) из контекста.
+func GetActor(ctx context.Context) string {
+	actor, _ := ctx.Value(contextValueName).(string)
+	return actor
+}
+
+// clientIP адрес клиента. Заголовки прокси не учитываются, так как их может подделать клиент.
+func clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
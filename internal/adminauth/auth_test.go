@@ -0,0 +1,100 @@
+package adminauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHTTPHandler(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		cfg        config.Options
+		remoteAddr string
+		header     string
+		wantCode   int
+		wantActor  string
+	}{
+		{
+			name:       `disabled`,
+			cfg:        config.Options{},
+			remoteAddr: `127.0.0.1:1234`,
+			header:     `Bearer `,
+			wantCode:   http.StatusNotFound,
+		},
+		{
+			name:       `without token`,
+			cfg:        config.Options{AdminToken: `admin_token`},
+			remoteAddr: `127.0.0.1:1234`,
+			wantCode:   http.StatusUnauthorized,
+		},
+		{
+			name:       `invalid token`,
+			cfg:        config.Options{AdminToken: `admin_token`},
+			remoteAddr: `127.0.0.1:1234`,
+			header:     `Bearer invalid`,
+			wantCode:   http.StatusUnauthorized,
+		},
+		{
+			name:       `untrusted subnet`,
+			cfg:        config.Options{AdminToken: `admin_token`, TrustedSubnet: `10.0.0.0/8`},
+			remoteAddr: `127.0.0.1:1234`,
+			header:     `Bearer admin_token`,
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:       `trusted subnet`,
+			cfg:        config.Options{AdminToken: `admin_token`, TrustedSubnet: `10.0.0.0/8`},
+			remoteAddr: `10.1.2.3:1234`,
+			header:     `Bearer admin_token`,
+			wantCode:   http.StatusOK,
+			wantActor:  `10.1.2.3`,
+		},
+		{
+			name:       `without subnet`,
+			cfg:        config.Options{AdminToken: `admin_token`},
+			remoteAddr: `192.168.1.1:1234`,
+			header:     `Bearer admin_token`,
+			wantCode:   http.StatusOK,
+			wantActor:  `192.168.1.1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Init(&tt.cfg)
+			require.NoError(t, err)
+
+			var actor string
+			h := AdminHTTPHandler(func(resp http.ResponseWriter, req *http.Request) {
+				actor = GetActor(req.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, `/`, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.header != `` {
+				req.Header.Set(HeaderAuthorization, tt.header)
+			}
+
+			resp := httptest.NewRecorder()
+			h(resp, req)
+
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Equal(t, tt.wantActor, actor)
+		})
+	}
+}
+
+func TestInitInvalidTrustedSubnet(t *testing.T) {
+	err := Init(&config.Options{AdminToken: `admin_token`, TrustedSubnet: `invalid`})
+	assert.Error(t, err)
+}
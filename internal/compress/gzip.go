@@ -29,39 +29,52 @@ func (w gzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// gzipBody - тело запроса, разжимаемое по мере чтения. При закрытии закрывается и исходное тело.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+// Close реализация интерфейса Closer
+func (b *gzipBody) Close() error {
+	err := b.Reader.Close()
+	if bodyErr := b.body.Close(); err == nil {
+		err = bodyErr
+	}
+
+	return err
+}
+
 // GzipHTTPHandler Gzip обработчик сжатых запросов.
+// Тело запроса с заголовком Content-Encoding: gzip или Content-Type: application/x-gzip разжимается потоково
+// для любого обработчика, ответ сжимается, если клиент его принимает.
 func GzipHTTPHandler(f http.HandlerFunc) http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
+		ct := req.Header.Get(handler.HeaderContentType)
+
+		if req.Header.Get(handler.HeaderContentEncoding) == handler.HeaderContentEncodingGzip || ct == handler.HeaderContentTypeXGzip {
+			gz, err := gzip.NewReader(req.Body)
+			if err != nil {
+				logger.Error(`gzip decompress error:`, err)
+				http.Error(resp, `invalid gzip body`, http.StatusBadRequest)
+				return
+			}
+
+			req.Body = &gzipBody{Reader: gz, body: req.Body}
+			req.Header.Del(handler.HeaderContentEncoding)
+			req.ContentLength = -1
+		}
+
 		if !strings.Contains(req.Header.Get(handler.HeaderAcceptEncoding), handler.HeaderContentEncodingGzip) {
 			f(resp, req)
 			return
 		}
 
-		ct := req.Header.Get(handler.HeaderContentType)
 		if ct != handler.HeaderContentTypeJSON && ct != handler.HeaderContentTypeTextHTML && ct != handler.HeaderContentTypeXGzip {
 			f(resp, req)
 			return
 		}
 
-		if ct == handler.HeaderContentTypeXGzip {
-			var data []byte
-
-			data, err := io.ReadAll(req.Body)
-			if err != nil {
-				f(resp, req)
-				return
-			}
-
-			data, err = GzipDecompress(data)
-			if err != nil {
-				logger.Error(`gzip decompress error:`, err)
-				f(resp, req)
-				return
-			}
-
-			req.Body = io.NopCloser(bytes.NewReader(data))
-		}
-
 		gz, err := gzip.NewWriterLevel(resp, gzip.BestSpeed)
 		if err != nil {
 			f(resp, req)
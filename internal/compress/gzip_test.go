@@ -1,8 +1,16 @@
 package compress
 
 import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/Alheor/shorturl/internal/http/handler"
+	"github.com/Alheor/shorturl/internal/logger"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -26,3 +34,42 @@ func TestDecompressSuccess(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, in, out)
 }
+
+func TestGzipHTTPHandlerDecompressesBody(t *testing.T) {
+	body, err := Compress([]byte("url\nhttps://example.com\n"))
+	require.NoError(t, err)
+
+	var got []byte
+	h := GzipHTTPHandler(func(resp http.ResponseWriter, req *http.Request) {
+		got, err = io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Empty(t, req.Header.Get(handler.HeaderContentEncoding))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, `/`, bytes.NewReader(body))
+	req.Header.Set(handler.HeaderContentType, handler.HeaderContentTypeCSV)
+	req.Header.Set(handler.HeaderContentEncoding, handler.HeaderContentEncodingGzip)
+
+	resp := httptest.NewRecorder()
+	h(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "url\nhttps://example.com\n", string(got))
+}
+
+func TestGzipHTTPHandlerInvalidBody(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	h := GzipHTTPHandler(func(resp http.ResponseWriter, req *http.Request) {
+		t.Fatal(`handler must not be called`)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, `/`, strings.NewReader(`not gzip`))
+	req.Header.Set(handler.HeaderContentEncoding, handler.HeaderContentEncodingGzip)
+
+	resp := httptest.NewRecorder()
+	h(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -148,6 +150,9 @@ func TestLoadConfigFromEnvWithEmptyFile(t *testing.T) {
 	err = os.Setenv(`CONFIG`, filePath)
 	assert.NoError(t, err)
 
+	// Флаг -c, явно заданный в предыдущем тесте, имеет приоритет над переменной окружения
+	os.Args = append(os.Args, `-c=`+filePath)
+
 	Load()
 
 	assert.Equal(t, `addr_test_value`, options.Addr)
@@ -178,7 +183,7 @@ func TestLoadFromFileWithoutFile(t *testing.T) {
 	options.EnableHTTPS = true
 	options.FileConfig = ``
 
-	err := loadFromFile(&options)
+	_, err := loadFromFile(&options)
 	assert.NoError(t, err)
 
 	assert.Equal(t, `Addr value not changed`, options.Addr)
@@ -211,7 +216,7 @@ func TestLoadFromFileWithEmptyFile(t *testing.T) {
 	options.EnableHTTPS = true
 	options.FileConfig = filePath
 
-	err = loadFromFile(&options)
+	_, err = loadFromFile(&options)
 	assert.NoError(t, err)
 
 	assert.Equal(t, `Addr value not changed`, options.Addr)
@@ -247,7 +252,7 @@ func TestLoadFromFileFileWithConfig(t *testing.T) {
 
 	options.FileConfig = filePath
 
-	err = loadFromFile(&options)
+	_, err = loadFromFile(&options)
 	assert.NoError(t, err)
 
 	assert.Equal(t, `Addr value is changed`, options.Addr)
@@ -312,14 +317,90 @@ func TestPriorityLoadingConfig(t *testing.T) {
 
 	Load()
 
-	assert.Equal(t, `addr_test_value_from_env`, options.Addr)
-	assert.Equal(t, `base_host_test_value_from_env`, options.BaseHost)
-	assert.Equal(t, `file-storage-path_test_value_from_env`, options.FileStoragePath)
-	assert.Equal(t, `database-dsn_test_value_from_env`, options.DatabaseDsn)
-	assert.Equal(t, `signature_key_test_value_from_env`, options.SignatureKey)
-	assert.Equal(t, `tls_cert_test_value_from_env`, options.TLSCert)
-	assert.Equal(t, `tls_key_test_value_from_env`, options.TLSKey)
+	assert.Equal(t, `addr_test_value_from_flags`, options.Addr)
+	assert.Equal(t, `base_host_test_value_from_flags`, options.BaseHost)
+	assert.Equal(t, `file-storage-path_test_value_from_flags`, options.FileStoragePath)
+	assert.Equal(t, `database-dsn_test_value_from_flags`, options.DatabaseDsn)
+	assert.Equal(t, `signature_key_test_value_from_flags`, options.SignatureKey)
+	assert.Equal(t, `tls_cert_test_value_from_flags`, options.TLSCert)
+	assert.Equal(t, `tls_key_test_value_from_flags`, options.TLSKey)
+	assert.Equal(t, SourceFlag, GetSources()[`server_address`])
 
 	err = os.Remove(filePath)
 	require.NoError(t, err)
 }
+
+func TestLoadLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `config.json`)
+
+	configStr := `{
+    "cookie_domain": "file.ru",
+    "max_url_length": 100,
+    "enable_https": true,
+    "log_level": "debug",
+    "batch_max_items": 5
+}`
+
+	err := os.WriteFile(path, []byte(configStr), 0600)
+	require.NoError(t, err)
+
+	t.Setenv(`CONFIG`, path)
+	t.Setenv(`COOKIE_DOMAIN`, `env.ru`)
+	t.Setenv(`MAX_URL_LENGTH`, `200`)
+	t.Setenv(`ENABLE_HTTPS`, ``)
+	t.Setenv(`LOG_LEVEL`, ``)
+	t.Setenv(`BATCH_MAX_ITEMS`, ``)
+	t.Setenv(`TEMPLATES_DIR`, ``)
+
+	defaults := Options{MaxURLLength: 2048, LogLevel: `info`, BatchMaxItems: 100}
+	flags := Options{MaxURLLength: 300, LogLevel: `warn`}
+
+	result, src, err := loadLayers(defaults, flags, []string{`max-url-length`})
+	require.NoError(t, err)
+
+	assert.Equal(t, path, result.FileConfig)
+	assert.Equal(t, `env.ru`, result.CookieDomain)
+	assert.Equal(t, 300, result.MaxURLLength)
+	assert.True(t, result.EnableHTTPS)
+	assert.Equal(t, `debug`, result.LogLevel)
+	assert.Equal(t, 5, result.BatchMaxItems)
+	assert.Equal(t, ``, result.TemplatesDir)
+
+	assert.Equal(t, SourceEnv, src[`cookie_domain`])
+	assert.Equal(t, SourceFlag, src[`max_url_length`])
+	assert.Equal(t, SourceFile, src[`enable_https`])
+	assert.Equal(t, SourceFile, src[`log_level`])
+	assert.Equal(t, SourceDefault, src[`templates_dir`])
+	assert.Equal(t, SourceEnv, src[`FileConfig`])
+}
+
+func TestLoadLayersWithInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `config.json`)
+
+	err := os.WriteFile(path, []byte(`{"max_url_length": "long"}`), 0600)
+	require.NoError(t, err)
+
+	t.Setenv(`CONFIG`, path)
+
+	_, _, err = loadLayers(Options{}, Options{}, nil)
+	assert.Error(t, err)
+}
+
+func TestGetJWTKeys(t *testing.T) {
+	o := Options{JWTKeys: []string{`new@key`, `old-key@2024-05-01T10:00:00Z`}}
+
+	keys := o.GetJWTKeys()
+	require.Len(t, keys, 2)
+
+	assert.Equal(t, `new@key`, keys[0].Key)
+	assert.True(t, keys[0].RotatedAt.IsZero())
+
+	assert.Equal(t, `old-key`, keys[1].Key)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), keys[1].RotatedAt.UTC())
+
+	o = Options{JWTAlgorithm: `HS256`, SignatureKeys: []string{`first`, `second`}}
+	assert.Equal(t, []JWTKey{{Key: `first`}}, o.GetJWTKeys())
+
+	o.JWTAlgorithm = `ES256`
+	assert.Empty(t, o.GetJWTKeys())
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// secretFileSuffix - суффикс параметра с путем к файлу, из которого читается значение параметра без суффикса.
+// В файле конфигурации используется суффикс _file, в переменных окружения - _FILE.
+const secretFileSuffix = `_file`
+
+// envReference - ссылка на переменную окружения вида ${NAME} в строковых значениях файла конфигурации.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// decodeConfigFile разбор файла конфигурации path в формате, определяемом по расширению: .yaml и .yml - YAML,
+// .toml - TOML, иначе JSON. Возвращает данные в формате JSON с подставленными переменными окружения
+// и значениями из файлов секретов. Длительности задаются строкой в формате time.ParseDuration (например, 24h)
+// или числом наносекунд.
+func decodeConfigFile(path string, data []byte) ([]byte, error) {
+	var values map[string]any
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case `.yaml`, `.yml`:
+		err = yaml.Unmarshal(data, &values)
+	case `.toml`:
+		err = toml.Unmarshal(data, &values)
+	default:
+		// Числа сохраняются без преобразования в float64, чтобы не терять точность длительностей в наносекундах
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range values {
+		if values[key], err = expandEnv(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = loadSecretFiles(values); err != nil {
+		return nil, err
+	}
+
+	if err = parseDurations(values); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(values)
+}
+
+// expandEnv подстановка переменных окружения вида ${NAME} в строковые значения, в том числе в списках.
+// Если переменная окружения не задана, то возвращается ошибка.
+func expandEnv(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		var err error
+
+		expanded := envReference.ReplaceAllStringFunc(v, func(ref string) string {
+			name := envReference.FindStringSubmatch(ref)[1]
+
+			val, exists := os.LookupEnv(name)
+			if !exists && err == nil {
+				err = errors.New(`environment variable "` + name + `" is not set`)
+			}
+
+			return val
+		})
+
+		return expanded, err
+
+	case []any:
+		for i := range v {
+			expanded, err := expandEnv(v[i])
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = expanded
+		}
+	}
+
+	return value, nil
+}
+
+// parseDurations замена строковых значений параметров-длительностей числом наносекунд.
+func parseDurations(values map[string]any) error {
+	durationType := reflect.TypeOf(time.Duration(0))
+
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Options{})) {
+		if field.Type != durationType {
+			continue
+		}
+
+		key := optionKey(field)
+
+		value, isString := values[key].(string)
+		if !isString {
+			continue
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New(`config parameter "` + key + `" must be a duration: ` + err.Error())
+		}
+
+		values[key] = int64(duration)
+	}
+
+	return nil
+}
+
+// loadSecretFiles замена параметров вида key_file значениями строковых параметров key, прочитанными из указанных файлов.
+func loadSecretFiles(values map[string]any) error {
+	stringKeys := make(map[string]struct{})
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Options{})) {
+		if field.Type.Kind() == reflect.String {
+			stringKeys[optionKey(field)] = struct{}{}
+		}
+	}
+
+	for fileKey, value := range values {
+		key, isSecretFile := strings.CutSuffix(fileKey, secretFileSuffix)
+		if _, isOption := stringKeys[key]; !isSecretFile || !isOption {
+			continue
+		}
+
+		path, isString := value.(string)
+		if !isString {
+			return errors.New(`config parameter "` + fileKey + `" must be a file path`)
+		}
+
+		if _, exists := values[key]; exists {
+			return errors.New(`config parameters "` + key + `" and "` + fileKey + `" are both set`)
+		}
+
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return err
+		}
+
+		values[key] = secret
+		delete(values, fileKey)
+	}
+
+	return nil
+}
+
+// secretFileEnv окружение процесса, в котором для переменных names заданные переменные вида NAME_FILE
+// заменены переменными NAME со значениями, прочитанными из указанных файлов.
+func secretFileEnv(names []string) (map[string]string, error) {
+	environ := make(map[string]string)
+	for _, item := range os.Environ() {
+		if name, value, found := strings.Cut(item, `=`); found {
+			environ[name] = value
+		}
+	}
+
+	for _, name := range names {
+		fileName := name + strings.ToUpper(secretFileSuffix)
+
+		path := environ[fileName]
+		if path == `` {
+			continue
+		}
+
+		if environ[name] != `` {
+			return nil, errors.New(`environment variables ` + name + ` and ` + fileName + ` are both set`)
+		}
+
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		environ[name] = secret
+	}
+
+	return environ, nil
+}
+
+// readSecretFile чтение секрета из файла без завершающего перевода строки.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ``, err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
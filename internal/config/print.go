@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+)
+
+// secretMask - значение, которым заменяются секреты при выводе конфигурации.
+const secretMask = `******`
+
+// secretKeys - параметры, значения которых не выводятся.
+var secretKeys = map[string]struct{}{
+	`signature_key`:  {},
+	`signature_keys`: {},
+	`tls_key`:        {},
+	`jwt_keys`:       {},
+	`admin_token`:    {},
+}
+
+// dsnPassword - пароль в DSN вида "key=value".
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// Print вывод итоговой конфигурации options с источником значения каждого параметра. Секреты маскируются.
+func Print(w io.Writer, options *Options, sources Sources) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE"); err != nil {
+		return err
+	}
+
+	ov := reflect.ValueOf(options).Elem()
+	for i, field := range reflect.VisibleFields(ov.Type()) {
+		key := optionKey(field)
+
+		value := formatValue(ov.Field(i))
+		if _, isSecret := secretKeys[key]; isSecret && value != `` {
+			value = secretMask
+		}
+
+		if key == `database_dsn` {
+			value = maskDSN(value)
+		}
+
+		source := sources[key]
+		if source == `` {
+			source = SourceDefault
+		}
+
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\n", key, value, source); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// formatValue строковое представление значения параметра, списки выводятся через запятую.
+func formatValue(value reflect.Value) string {
+	if value.Kind() != reflect.Slice {
+		return fmt.Sprint(value.Interface())
+	}
+
+	items := make([]string, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		items = append(items, fmt.Sprint(value.Index(i).Interface()))
+	}
+
+	return strings.Join(items, `,`)
+}
+
+// maskDSN замена пароля в DSN вида URL (на xxxxx) или "key=value".
+func maskDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+
+	return dsnPassword.ReplaceAllString(dsn, `${1}`+secretMask)
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Alheor/shorturl/internal/tlscerts"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MinSignatureKeyLength - минимальная длина ключа подписи cookie и секрета JWT HS256 в байтах.
+const MinSignatureKeyLength = 16
+
+// sqliteDSNPrefix - префикс DSN базы данных SQLite, совпадает с repository.SQLiteDSNPrefix.
+const sqliteDSNPrefix = `sqlite://`
+
+// Validate - проверка конфигурации. Возвращает все найденные ошибки.
+func (o *Options) Validate() error {
+	var errs []error
+
+	if err := validateAddr(o.Addr); err != nil {
+		errs = append(errs, errors.New(`invalid server address: `+err.Error()))
+	}
+
+	if o.AdminAddr != `` {
+		if err := validateAddr(o.AdminAddr); err != nil {
+			errs = append(errs, errors.New(`invalid admin address: `+err.Error()))
+		}
+	}
+
+	if err := validateBaseHost(o.BaseHost); err != nil {
+		errs = append(errs, errors.New(`invalid base host: `+err.Error()))
+	}
+
+	if err := validateDSN(o.DatabaseDsn); err != nil {
+		errs = append(errs, errors.New(`invalid database dsn: `+err.Error()))
+	}
+
+	for i, key := range o.GetSignatureKeys() {
+		if len(key) < MinSignatureKeyLength {
+			errs = append(errs, errors.New(`signature key #`+strconv.Itoa(i+1)+` is shorter than `+strconv.Itoa(MinSignatureKeyLength)+` bytes`))
+		}
+	}
+
+	if o.AuthTokenFormat == AuthTokenFormatJWT {
+		for i, key := range o.GetJWTKeys() {
+			if o.JWTAlgorithm == `HS256` && len(key.Key) < MinSignatureKeyLength {
+				errs = append(errs, errors.New(`JWT key #`+strconv.Itoa(i+1)+` is shorter than `+strconv.Itoa(MinSignatureKeyLength)+` bytes`))
+			}
+
+			if i > 0 && key.RotatedAt.IsZero() {
+				errs = append(errs, errors.New(`JWT key #`+strconv.Itoa(i+1)+` requires rotation time`))
+			}
+		}
+	}
+
+	if err := validateTLS(o.TLSCert, o.TLSKey); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := validateTLSFiles(o.TLSCertFiles, o.TLSKeyFiles); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateAddr проверка адреса вида host:port, хост может быть пустым.
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return errors.New(`invalid port "` + port + `"`)
+	}
+
+	return nil
+}
+
+// validateBaseHost проверка хоста сервиса: абсолютный URL со схемой http или https.
+func validateBaseHost(baseHost string) error {
+	u, err := url.Parse(baseHost)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != `http` && u.Scheme != `https` {
+		return errors.New(`scheme must be http or https`)
+	}
+
+	if u.Host == `` {
+		return errors.New(`host required`)
+	}
+
+	return nil
+}
+
+// validateDSN проверка DSN базы данных PostgreSQL или SQLite, пустой DSN допустим.
+func validateDSN(dsn string) error {
+	if dsn == `` {
+		return nil
+	}
+
+	if path, isSQLite := strings.CutPrefix(dsn, sqliteDSNPrefix); isSQLite {
+		if path == `` {
+			return errors.New(`sqlite database path required`)
+		}
+
+		return nil
+	}
+
+	_, err := pgx.ParseConfig(dsn)
+
+	return err
+}
+
+// validateTLS проверка пары TLS сертификата и ключа в формате base64: заданы оба и соответствуют друг другу, либо не заданы.
+func validateTLS(certBase64, keyBase64 string) error {
+	if certBase64 == `` && keyBase64 == `` {
+		return nil
+	}
+
+	if certBase64 == `` || keyBase64 == `` {
+		return errors.New(`TLS certificate and key must be set together`)
+	}
+
+	if _, err := tlscerts.ParseCert(certBase64, keyBase64); err != nil {
+		return errors.New(`invalid TLS certificate and key pair: ` + err.Error())
+	}
+
+	return nil
+}
+
+// validateTLSFiles проверка списков файлов TLS сертификатов и ключей: одинаковой длины, пары соответствуют друг другу.
+func validateTLSFiles(certFiles, keyFiles []string) error {
+	if len(certFiles) != len(keyFiles) {
+		return errors.New(`TLS certificate and key files must be set in pairs`)
+	}
+
+	for i := range certFiles {
+		if _, err := tls.LoadX509KeyPair(certFiles[i], keyFiles[i]); err != nil {
+			return errors.New(`invalid TLS certificate and key files ` + certFiles[i] + `: ` + err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// DefaultWatchInterval - период проверки изменений файла конфигурации по умолчанию.
+const DefaultWatchInterval = 10 * time.Second
+
+// staticKeys - параметры, изменение которых требует перезапуска сервиса.
+var staticKeys = map[string]struct{}{
+	`server_address`:    {},
+	`file_storage_path`: {},
+	`database_dsn`:      {},
+	`storage_mode`:      {},
+	`bolt_storage_path`: {},
+	`enable_https`:      {},
+	`admin_token`:       {},
+	`admin_address`:     {},
+	`trusted_subnet`:    {},
+}
+
+// Subscriber - получатель новой конфигурации. При ошибке подписчик должен продолжать работу с прежней конфигурацией.
+type Subscriber func(options *Options) error
+
+type subscriber struct {
+	name  string
+	apply Subscriber
+}
+
+// Watcher - перезагрузка конфигурации из файла без перезапуска сервиса.
+type Watcher struct {
+	// load - загрузка конфигурации из всех источников.
+	load        func() (Options, error)
+	current     atomic.Pointer[Options]
+	mu          sync.Mutex
+	subscribers []subscriber
+}
+
+// NewWatcher создание Watcher для загруженной функцией Load конфигурации options.
+func NewWatcher(options Options) *Watcher {
+	w := &Watcher{load: func() (Options, error) {
+		loaded, _, err := loadLayers(defaults, flagValues, explicitFlags())
+		return loaded, err
+	}}
+	w.current.Store(&options)
+
+	return w
+}
+
+// Subscribe добавление подписчика name. Подписчики получают новую конфигурацию в порядке добавления.
+func (w *Watcher) Subscribe(name string, apply Subscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, subscriber{name: name, apply: apply})
+}
+
+// Current текущая конфигурация.
+func (w *Watcher) Current() *Options {
+	return w.current.Load()
+}
+
+// Reload перечитывание файла конфигурации. Изменения параметров, требующих перезапуска, отклоняются.
+// Если новая конфигурация не прошла проверку или ее не принял один из подписчиков,
+// то подписчики возвращаются к прежней конфигурации.
+func (w *Watcher) Reload() error {
+	return w.reload(false)
+}
+
+// reload перечитывание файла конфигурации. Если force, то подписчики получают конфигурацию, даже если она
+// не изменилась, чтобы перечитать свои файлы, например, TLS сертификаты.
+func (w *Watcher) reload(force bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	prev := w.current.Load()
+
+	next, err := w.load()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keepStatic(prev, &next) {
+		logger.Warn(`config parameter requires restart, change rejected`, zap.String(`key`, key))
+	}
+
+	changed := diffOptions(prev, &next)
+	if len(changed) == 0 && !force {
+		return nil
+	}
+
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	for i, sub := range w.subscribers {
+		if err := sub.apply(&next); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rollbackErr := w.subscribers[j].apply(prev); rollbackErr != nil {
+					logger.Error(`config rollback error in "`+w.subscribers[j].name+`"`, rollbackErr)
+				}
+			}
+
+			return errors.New(`config rejected by "` + sub.name + `": ` + err.Error())
+		}
+	}
+
+	w.current.Store(&next)
+
+	logger.Info(`configuration reloaded`, zap.Strings(`changed`, changed))
+
+	return nil
+}
+
+// Watch перезагрузка конфигурации по сигналу SIGHUP и при изменении файла конфигурации,
+// пока не будет отменен контекст ctx. Изменения файла проверяются с периодом interval.
+// По сигналу SIGHUP подписчики получают конфигурацию, даже если она не изменилась.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	path := w.Current().FileConfig
+	modTime, size := fileState(path)
+
+	go func() {
+		defer signal.Stop(hup)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			force := false
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				modTime, size = fileState(path)
+				force = true
+			case <-ticker.C:
+				if path == `` {
+					continue
+				}
+
+				newModTime, newSize := fileState(path)
+				if newModTime.Equal(modTime) && newSize == size {
+					continue
+				}
+
+				modTime, size = newModTime, newSize
+			}
+
+			if err := w.reload(force); err != nil {
+				logger.Error(`config reload error`, err)
+			}
+		}
+	}()
+}
+
+// fileState время изменения и размер файла path, нулевые значения, если файл не задан или недоступен.
+func fileState(path string) (time.Time, int64) {
+	if path == `` {
+		return time.Time{}, 0
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+
+	return info.ModTime(), info.Size()
+}
+
+// keepStatic возврат прежних значений параметров, требующих перезапуска. Возвращает ключи отклоненных изменений.
+func keepStatic(prev, next *Options) []string {
+	var rejected []string
+
+	pv, nv := reflect.ValueOf(prev).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < pv.NumField(); i++ {
+		key := optionKey(pv.Type().Field(i))
+		if _, isStatic := staticKeys[key]; !isStatic {
+			continue
+		}
+
+		if !reflect.DeepEqual(pv.Field(i).Interface(), nv.Field(i).Interface()) {
+			nv.Field(i).Set(pv.Field(i))
+			rejected = append(rejected, key)
+		}
+	}
+
+	return rejected
+}
+
+// diffOptions ключи параметров, значения которых различаются.
+func diffOptions(prev, next *Options) []string {
+	var changed []string
+
+	pv, nv := reflect.ValueOf(prev).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < pv.NumField(); i++ {
+		if !reflect.DeepEqual(pv.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, optionKey(pv.Type().Field(i)))
+		}
+	}
+
+	return changed
+}
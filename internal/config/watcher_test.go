@@ -0,0 +1,186 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initWatcherTest запуск Watcher для файла конфигурации с содержимым data.
+func initWatcherTest(t *testing.T, data string) (*Watcher, string) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), `config.json`)
+	writeConfigFile(t, path, data)
+
+	load := func() (Options, error) {
+		loaded := Options{
+			Addr:         `localhost:8080`,
+			FileConfig:   path,
+			SignatureKey: `signature-key-test-value`,
+		}
+
+		_, err := loadFromFile(&loaded)
+
+		return loaded, err
+	}
+
+	loaded, err := load()
+	require.NoError(t, err)
+
+	w := NewWatcher(loaded)
+	w.load = load
+
+	return w, path
+}
+
+func writeConfigFile(t *testing.T, path string, data string) {
+	err := os.WriteFile(path, []byte(data), 0600)
+	require.NoError(t, err)
+}
+
+func TestWatcherReload(t *testing.T) {
+	w, path := initWatcherTest(t, `{"base_url": "http://first.ru", "max_url_length": 100}`)
+
+	var got *Options
+	w.Subscribe(`test`, func(options *Options) error {
+		got = options
+		return nil
+	})
+
+	writeConfigFile(t, path, `{"base_url": "http://second.ru", "max_url_length": 100, "log_level": "debug"}`)
+
+	err := w.Reload()
+	require.NoError(t, err)
+
+	require.NotNil(t, got)
+	assert.Equal(t, `http://second.ru`, got.BaseHost)
+	assert.Equal(t, `debug`, got.LogLevel)
+	assert.Equal(t, `http://second.ru`, w.Current().BaseHost)
+	assert.Equal(t, 100, w.Current().MaxURLLength)
+}
+
+func TestWatcherReloadWithoutChanges(t *testing.T) {
+	w, _ := initWatcherTest(t, `{"base_url": "http://first.ru"}`)
+
+	calls := 0
+	w.Subscribe(`test`, func(options *Options) error {
+		calls++
+		return nil
+	})
+
+	err := w.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, 0, calls)
+}
+
+func TestWatcherSignalWithoutChanges(t *testing.T) {
+	w, _ := initWatcherTest(t, `{"base_url": "http://first.ru"}`)
+
+	var calls atomic.Int32
+	w.Subscribe(`test`, func(options *Options) error {
+		calls.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w.Watch(ctx, time.Hour)
+
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+
+	err = process.Signal(syscall.SIGHUP)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return calls.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, `http://first.ru`, w.Current().BaseHost)
+}
+
+func TestWatcherRejectStatic(t *testing.T) {
+	w, path := initWatcherTest(t, `{"base_url": "http://first.ru", "database_dsn": "host=first dbname=app"}`)
+
+	writeConfigFile(t, path, `{"base_url": "http://second.ru", "database_dsn": "host=second dbname=app", "enable_https": true}`)
+
+	err := w.Reload()
+	require.NoError(t, err)
+
+	assert.Equal(t, `http://second.ru`, w.Current().BaseHost)
+	assert.Equal(t, `host=first dbname=app`, w.Current().DatabaseDsn)
+	assert.False(t, w.Current().EnableHTTPS)
+}
+
+func TestWatcherInvalidConfig(t *testing.T) {
+	w, path := initWatcherTest(t, `{"base_url": "http://first.ru"}`)
+
+	calls := 0
+	w.Subscribe(`test`, func(options *Options) error {
+		calls++
+		return nil
+	})
+
+	writeConfigFile(t, path, `{"base_url": "http://[::1"}`)
+
+	err := w.Reload()
+	require.Error(t, err)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, `http://first.ru`, w.Current().BaseHost)
+
+	writeConfigFile(t, path, `{"base_url": `)
+
+	err = w.Reload()
+	require.Error(t, err)
+	assert.Equal(t, `http://first.ru`, w.Current().BaseHost)
+}
+
+func TestWatcherRollback(t *testing.T) {
+	w, path := initWatcherTest(t, `{"base_url": "http://first.ru"}`)
+
+	var applied []string
+	w.Subscribe(`first`, func(options *Options) error {
+		applied = append(applied, options.BaseHost)
+		return nil
+	})
+
+	w.Subscribe(`second`, func(options *Options) error {
+		return errors.New(`test error`)
+	})
+
+	writeConfigFile(t, path, `{"base_url": "http://second.ru"}`)
+
+	err := w.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `second`)
+
+	assert.Equal(t, []string{`http://second.ru`, `http://first.ru`}, applied)
+	assert.Equal(t, `http://first.ru`, w.Current().BaseHost)
+}
+
+func TestWatcherWatchFileChange(t *testing.T) {
+	w, path := initWatcherTest(t, `{"base_url": "http://first.ru"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w.Watch(ctx, 10*time.Millisecond)
+
+	writeConfigFile(t, path, `{"base_url": "http://second-host.ru"}`)
+
+	assert.Eventually(t, func() bool {
+		return w.Current().BaseHost == `http://second-host.ru`
+	}, time.Second, 10*time.Millisecond)
+}
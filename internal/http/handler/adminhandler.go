@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/adminauth"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Параметры маршрутов API администратора.
+const (
+	// URLParamShortKey - короткий ключ URL.
+	URLParamShortKey = `shortKey`
+
+	// URLParamUserID - идентификатор пользователя.
+	URLParamUserID = `userID`
+)
+
+// AdminGetURL API обработчик запроса администратора на получение URL по короткому ключу независимо от владельца.
+func AdminGetURL(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "AdminGetURL" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	info, err := service.AdminGetURL(ctx, adminauth.GetActor(ctx), chi.URLParam(req, URLParamShortKey))
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if info == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, info)
+}
+
+// AdminBlockURL API обработчик запроса администратора на блокировку URL.
+func AdminBlockURL(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "AdminBlockURL" handler`)
+
+	var body []byte
+	var err error
+	var request models.APIAdminBlockRequest
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	defer req.Body.Close()
+	if body, err = io.ReadAll(req.Body); err != nil || len(body) == 0 {
+		sendAPIResponse(resp, &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err = json.Unmarshal(body, &request); err != nil || strings.TrimSpace(request.Reason) == `` {
+		sendAPIResponse(resp, &models.APIResponse{Error: `reason required`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	shortKey := chi.URLParam(req, URLParamShortKey)
+
+	found, err := service.BlockURL(ctx, adminauth.GetActor(ctx), shortKey, strings.TrimSpace(request.Reason))
+	sendAdminURLResponse(ctx, resp, shortKey, found, err)
+}
+
+// AdminUnblockURL API обработчик запроса администратора на снятие блокировки URL.
+func AdminUnblockURL(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "AdminUnblockURL" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	shortKey := chi.URLParam(req, URLParamShortKey)
+
+	found, err := service.UnblockURL(ctx, adminauth.GetActor(ctx), shortKey)
+	sendAdminURLResponse(ctx, resp, shortKey, found, err)
+}
+
+// AdminGetUserURLs API обработчик запроса администратора на получение всех URL пользователя.
+func AdminGetUserURLs(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "AdminGetUserURLs" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+	defer cancel()
+
+	user := getAdminUserParam(req)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `invalid user id`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	list, err := service.AdminGetUserURLs(ctx, adminauth.GetActor(ctx), user)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, list)
+}
+
+// AdminPurgeUser API обработчик запроса администратора на удаление всех URL пользователя.
+func AdminPurgeUser(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "AdminPurgeUser" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+	defer cancel()
+
+	user := getAdminUserParam(req)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `invalid user id`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	removed, err := service.PurgeUser(ctx, adminauth.GetActor(ctx), user)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, models.APIAdminPurgeResponse{Removed: removed})
+}
+
+// AdminGetKeyUsage API обработчик запроса администратора на получение количества проверок токенов по ключам подписи.
+func AdminGetKeyUsage(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "AdminGetKeyUsage" handler`)
+
+	sendJSON(resp, http.StatusOK, service.AdminGetKeyUsage(adminauth.GetActor(req.Context())))
+}
+
+// Ответ на изменение URL администратором: актуальное состояние URL.
+func sendAdminURLResponse(ctx context.Context, resp http.ResponseWriter, shortKey string, found bool, err error) {
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if !found {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	info, err := service.GetURLInfo(ctx, shortKey)
+	if err != nil || info == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, info)
+}
+
+// Пользователь из параметра маршрута, nil если идентификатор невалиден.
+func getAdminUserParam(req *http.Request) *models.User {
+	userID, err := uuid.Parse(chi.URLParam(req, URLParamUserID))
+	if err != nil {
+		return nil
+	}
+
+	return &models.User{ID: userID.String()}
+}
@@ -7,15 +7,21 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
 	"github.com/Alheor/shorturl/internal/service"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
 	"github.com/Alheor/shorturl/internal/userauth"
 )
 
+// QueryParamTag - параметр запроса для фильтрации URL пользователя по тегу.
+const QueryParamTag = `tag`
+
 // AddShorten API обработчик запроса на добавление URL пользователя.
 func AddShorten(resp http.ResponseWriter, req *http.Request) {
 
@@ -51,6 +57,12 @@ func AddShorten(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err = urlpolicy.Check(request.URL); err != nil {
+		response = models.APIResponse{Error: err.Error(), StatusCode: http.StatusUnprocessableEntity}
+		sendAPIResponse(resp, &response)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
 	defer cancel()
 
@@ -62,12 +74,19 @@ func AddShorten(resp http.ResponseWriter, req *http.Request) {
 	}
 
 	var shortURL string
-	if shortURL, err = service.Add(ctx, user, request.URL); err != nil {
+	if shortURL, err = service.AddWithMeta(ctx, user, request.URL, &request.URLMeta); err != nil {
+
+		var metaErr *models.URLMetaErr
+		if errors.As(err, &metaErr) {
+			response = models.APIResponse{Error: err.Error(), StatusCode: http.StatusUnprocessableEntity}
+			sendAPIResponse(resp, &response)
+			return
+		}
 
 		var uniqErr *models.UniqueErr
 		if errors.As(err, &uniqErr) {
 			response = models.APIResponse{
-				Result:     baseHost + `/` + uniqErr.ShortKey,
+				Result:     settings.Load().baseHost + `/` + uniqErr.ShortKey,
 				StatusCode: http.StatusConflict,
 			}
 
@@ -81,7 +100,7 @@ func AddShorten(resp http.ResponseWriter, req *http.Request) {
 	}
 
 	response = models.APIResponse{
-		Result:     baseHost + `/` + shortURL,
+		Result:     settings.Load().baseHost + `/` + shortURL,
 		StatusCode: http.StatusCreated,
 	}
 
@@ -89,75 +108,150 @@ func AddShorten(resp http.ResponseWriter, req *http.Request) {
 }
 
 // AddShortenBatch API обработчик запроса на массовое добавление URL пользователя.
+// Тело запроса - JSON массив, либо JSON объект на каждой строке при Content-Type application/x-ndjson.
+// Тело читается потоково, ответ возвращается в том же формате.
+//
+// По умолчанию URL проверяются и сохраняются частями, для каждого URL возвращается результат со статусом
+// created, exists, invalid или blocked и кодом ответа 207. Ошибка сохранения части отклоняет запрос кодом 500,
+// либо, если ответ уже начал отправляться, добавляется в его конец. С параметром atomic=true сохраняются все URL, либо ни одного: первый невалидный URL
+// отклоняет весь запрос, при успехе возвращается код ответа 201.
 func AddShortenBatch(resp http.ResponseWriter, req *http.Request) {
 
 	logger.Info(`Used "AddShortenBatch" handler`)
 
-	var body []byte
-	var err error
-	var request []models.APIBatchRequestEl
-	var response []models.APIBatchResponseEl
-
 	defer req.Body.Close()
-	if body, err = io.ReadAll(req.Body); err != nil || len(body) == 0 {
-		sendAPIResponse(resp, &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest})
+
+	ctx := req.Context()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		resp.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
-	if err = json.Unmarshal(body, &request); err != nil {
-		sendAPIResponse(resp, &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest})
-		return
+	atomic := false
+	if param := req.URL.Query().Get(QueryParamAtomic); param != `` {
+		var err error
+		if atomic, err = strconv.ParseBool(param); err != nil {
+			sendAPIResponse(resp, &models.APIResponse{Error: `atomic must be true or false`, StatusCode: http.StatusBadRequest})
+			return
+		}
 	}
 
-	if len(request) == 0 {
-		sendAPIResponse(resp, &models.APIResponse{Error: `empty url list`, StatusCode: http.StatusBadRequest})
-		return
+	stream := &batchStream{resp: resp, status: http.StatusMultiStatus, ndjson: isNDJSON(req)}
+	if atomic {
+		stream.status = http.StatusCreated
+	}
+
+	limits := settings.Load()
+
+	body := req.Body
+	if limits.batchMaxBodySize > 0 {
+		body = http.MaxBytesReader(resp, req.Body, limits.batchMaxBodySize)
 	}
 
-	for _, v := range request {
-		if _, err = url.ParseRequestURI(v.OriginalURL); err != nil {
-			sendAPIResponse(resp, &models.APIResponse{Error: `Url '` + v.OriginalURL + `' invalid`, StatusCode: http.StatusBadRequest})
+	decoder := json.NewDecoder(body)
+
+	if !stream.ndjson {
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			stream.fail(batchDecodeError(err))
 			return
 		}
+	}
 
-		if v.CorrelationID == `` {
-			sendAPIResponse(resp, &models.APIResponse{Error: `empty correlation_id`, StatusCode: http.StatusBadRequest})
-			return
+	var batch []models.APIBatchRequestEl
+	var results []models.APIBatchResultEl
+	items := 0
+
+	// addChunk сохранение проверенных URL части и отправка результата для каждого URL части
+	addChunk := func() bool {
+		added, err := service.AddBatchEach(ctx, user, batch)
+		if err != nil {
+			logger.Error(`Add batch error`, err)
+			stream.fail(&models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+			return false
 		}
+
+		for _, res := range results {
+			if res.Status == `` {
+				res, added = added[0], added[1:]
+			}
+
+			if err := stream.writeEl(res); err != nil {
+				logger.Error(`write response error`, err)
+				return false
+			}
+		}
+
+		stream.flush()
+
+		batch, results = batch[:0], results[:0]
+		return true
 	}
 
-	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
-	defer cancel()
+	for decoder.More() {
+		var el models.APIBatchRequestEl
 
-	user := userauth.GetUser(ctx)
-	if user == nil {
-		resp.WriteHeader(http.StatusUnauthorized)
-		return
+		if err := decoder.Decode(&el); err != nil {
+			stream.fail(batchDecodeError(err))
+			return
+		}
+
+		if items++; limits.batchMaxItems > 0 && items > limits.batchMaxItems {
+			stream.fail(&models.APIResponse{
+				Error:      `too many urls, max ` + strconv.Itoa(limits.batchMaxItems),
+				StatusCode: http.StatusRequestEntityTooLarge,
+			})
+
+			return
+		}
+
+		if atomic {
+			if response := validateBatchEl(&el); response != nil {
+				stream.fail(response)
+				return
+			}
+
+			batch = append(batch, el)
+			continue
+		}
+
+		res := models.APIBatchResultEl{CorrelationID: el.CorrelationID}
+		if status, err := checkBatchEl(&el); err != nil {
+			res.Status, res.Error = status, err.Error()
+		} else {
+			batch = append(batch, el)
+		}
+
+		results = append(results, res)
+
+		if len(results) == batchChunkSize && !addChunk() {
+			return
+		}
 	}
 
-	response, err = service.AddBatch(ctx, user, request)
-	if err != nil {
-		logger.Error(`Add batch error`, err)
-		resp.WriteHeader(http.StatusInternalServerError)
-		return
+	if !stream.ndjson {
+		if _, err := decoder.Token(); err != nil {
+			stream.fail(batchDecodeError(err))
+			return
+		}
 	}
 
-	rawByte, err := json.Marshal(response)
-	if err != nil {
-		logger.Error(`response marshal error`, err)
-		resp.WriteHeader(http.StatusInternalServerError)
+	if items == 0 {
+		sendAPIResponse(resp, &models.APIResponse{Error: `empty url list`, StatusCode: http.StatusBadRequest})
 		return
 	}
 
-	resp.Header().Add(HeaderContentType, HeaderContentTypeJSON)
-	resp.WriteHeader(http.StatusCreated)
+	if atomic {
+		addBatchAtomic(ctx, stream, user, batch)
+		return
+	}
 
-	_, err = resp.Write(rawByte)
-	if err != nil {
-		logger.Error(`write response error`, err)
-		resp.WriteHeader(http.StatusInternalServerError)
+	if len(results) > 0 && !addChunk() {
 		return
 	}
+
+	stream.close()
 }
 
 // GetAllShorten API обработчик запроса на получение всех URL пользователя.
@@ -179,11 +273,17 @@ func GetAllShorten(resp http.ResponseWriter, req *http.Request) {
 
 	resp.Header().Add(HeaderContentType, HeaderContentTypeJSON)
 
+	tag := service.NormalizeTag(req.URL.Query().Get(QueryParamTag))
+
 	chList, chErr := service.GetAll(ctx, user)
 	first := true
 	hasEls := false
 
 	for el := range chList {
+		if tag != `` && !slices.Contains(el.Tags, tag) {
+			continue
+		}
+
 		hasEls = true
 
 		if first {
@@ -206,8 +306,8 @@ func GetAllShorten(resp http.ResponseWriter, req *http.Request) {
 		}
 		first = false
 
-		short := strings.TrimRight(baseHost, `/`) + `/` + el.ShortURL
-		h := models.HistoryEl{OriginalURL: el.OriginalURL, ShortURL: short}
+		short := strings.TrimRight(settings.Load().baseHost, `/`) + `/` + el.ShortURL
+		h := models.HistoryEl{OriginalURL: el.OriginalURL, ShortURL: short, InputURL: el.InputURL, URLMeta: el.URLMeta}
 		rawByte, err := json.Marshal(h)
 		if err != nil {
 			logger.Error(`response marshal error`, err)
@@ -289,6 +389,24 @@ func DeleteShorten(resp http.ResponseWriter, req *http.Request) {
 	resp.WriteHeader(http.StatusAccepted)
 }
 
+// Подготовка ответа из произвольной структуры.
+func sendJSON(respWr http.ResponseWriter, statusCode int, resp any) {
+	rawByte, err := json.Marshal(resp)
+	if err != nil {
+		logger.Error(`response marshal error`, err)
+		respWr.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	respWr.Header().Add(HeaderContentType, HeaderContentTypeJSON)
+	respWr.WriteHeader(statusCode)
+
+	_, err = respWr.Write(rawByte)
+	if err != nil {
+		logger.Error(`write response error`, err)
+	}
+}
+
 // Подготовка ответа.
 func sendAPIResponse(respWr http.ResponseWriter, resp *models.APIResponse) {
 	rawByte, err := json.Marshal(resp)
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/service"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
+)
+
+// batchChunkSize - количество URL массового добавления, сохраняемых за одно обращение к репозиторию.
+const batchChunkSize = 1000
+
+// QueryParamAtomic - параметр запроса массового добавления URL: true - добавить все URL, либо ни одного.
+const QueryParamAtomic = `atomic`
+
+// batchStream потоковый ответ на запрос массового добавления URL: JSON массив, либо JSON объект на каждой строке.
+// Ответ начинает отправляться после сохранения первой части URL. Ошибка, возникшая после этого,
+// добавляется в конец ответа объектом с полем error, и обработка запроса прекращается.
+type batchStream struct {
+	resp   http.ResponseWriter
+	status int
+	ndjson bool
+	sent   bool
+}
+
+// isNDJSON проверка запроса в формате NDJSON.
+func isNDJSON(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get(HeaderContentType), HeaderContentTypeNDJSON)
+}
+
+// flush отправка клиенту записанной части ответа.
+func (s *batchStream) flush() {
+	if flusher, ok := s.resp.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeEl отправка элемента ответа. Перед первым элементом отправляются заголовки.
+func (s *batchStream) writeEl(el any) error {
+	rawByte, err := json.Marshal(el)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case s.ndjson:
+		rawByte = append(rawByte, '\n')
+	case s.sent:
+		rawByte = append([]byte(`,`), rawByte...)
+	default:
+		rawByte = append([]byte(`[`), rawByte...)
+	}
+
+	if !s.sent {
+		if s.ndjson {
+			s.resp.Header().Add(HeaderContentType, HeaderContentTypeNDJSON)
+		} else {
+			s.resp.Header().Add(HeaderContentType, HeaderContentTypeJSON)
+		}
+
+		s.resp.WriteHeader(s.status)
+		s.sent = true
+	}
+
+	_, err = s.resp.Write(rawByte)
+
+	return err
+}
+
+// close завершение ответа.
+func (s *batchStream) close() {
+	if !s.sent || s.ndjson {
+		return
+	}
+
+	if _, err := s.resp.Write([]byte(`]`)); err != nil {
+		logger.Error(`write response error`, err)
+	}
+}
+
+// fail отправка ошибки: кодом ответа, если ответ еще не начал отправляться, иначе последним элементом ответа.
+func (s *batchStream) fail(response *models.APIResponse) {
+	if !s.sent {
+		sendAPIResponse(s.resp, response)
+		return
+	}
+
+	if err := s.writeEl(models.APIResponse{Error: response.Error}); err != nil {
+		logger.Error(`write response error`, err)
+		return
+	}
+
+	s.close()
+}
+
+// checkBatchEl проверка элемента запроса массового добавления URL. Для невалидного элемента возвращает статус и ошибку.
+func checkBatchEl(el *models.APIBatchRequestEl) (string, error) {
+	if _, err := url.ParseRequestURI(el.OriginalURL); err != nil {
+		return models.BatchStatusInvalid, errors.New(`Url '` + el.OriginalURL + `' invalid`)
+	}
+
+	if el.CorrelationID == `` {
+		return models.BatchStatusInvalid, errors.New(`empty correlation_id`)
+	}
+
+	if err := urlpolicy.Check(el.OriginalURL); err != nil {
+		return models.BatchStatusBlocked, err
+	}
+
+	return ``, nil
+}
+
+// validateBatchEl проверка элемента запроса массового добавления URL, все URL которого добавляются целиком.
+func validateBatchEl(el *models.APIBatchRequestEl) *models.APIResponse {
+	status, err := checkBatchEl(el)
+
+	switch status {
+	case ``:
+		return nil
+	case models.BatchStatusBlocked:
+		return &models.APIResponse{
+			Error:      `correlation_id '` + el.CorrelationID + `': ` + err.Error(),
+			StatusCode: http.StatusUnprocessableEntity,
+		}
+	default:
+		return &models.APIResponse{Error: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+}
+
+// addBatchAtomic сохранение всех URL запроса одним обращением к репозиторию и отправка результата.
+func addBatchAtomic(ctx context.Context, stream *batchStream, user *models.User, batch []models.APIBatchRequestEl) {
+	list, err := service.AddBatch(ctx, user, batch)
+	if err != nil {
+		var metaErr *models.URLMetaErr
+		if errors.As(err, &metaErr) {
+			stream.fail(&models.APIResponse{Error: err.Error(), StatusCode: http.StatusUnprocessableEntity})
+			return
+		}
+
+		logger.Error(`Add batch error`, err)
+		stream.fail(&models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	for _, el := range list {
+		if err = stream.writeEl(el); err != nil {
+			logger.Error(`write response error`, err)
+			return
+		}
+	}
+
+	stream.close()
+}
+
+// batchDecodeError ответ на ошибку чтения тела запроса массового добавления URL.
+func batchDecodeError(err error) *models.APIResponse {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &models.APIResponse{
+			Error:      `request body is larger than ` + strconv.FormatInt(maxBytesErr.Limit, 10) + ` bytes`,
+			StatusCode: http.StatusRequestEntityTooLarge,
+		}
+	}
+
+	return &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest}
+}
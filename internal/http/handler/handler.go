@@ -3,16 +3,19 @@ package handler
 import (
 	"context"
 	"errors"
+	"html/template"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Alheor/shorturl/internal/config"
 	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
 	"github.com/Alheor/shorturl/internal/service"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
 	"github.com/Alheor/shorturl/internal/userauth"
 )
 
@@ -30,6 +33,18 @@ const (
 	// HeaderLocation header "Location" name.
 	HeaderLocation = `Location`
 
+	// HeaderCacheControl header "Cache-Control" name.
+	HeaderCacheControl = `Cache-Control`
+
+	// HeaderETag header "ETag" name.
+	HeaderETag = `ETag`
+
+	// HeaderIfNoneMatch header "If-None-Match" name.
+	HeaderIfNoneMatch = `If-None-Match`
+
+	// HeaderContentDisposition header "Content-Disposition" name.
+	HeaderContentDisposition = `Content-Disposition`
+
 	// HeaderContentTypeJSON header Content-Type value application/json.
 	HeaderContentTypeJSON = `application/json`
 
@@ -42,15 +57,48 @@ const (
 	// HeaderContentTypeTextHTML header Content-Type value text/html.
 	HeaderContentTypeTextHTML = `text/html`
 
+	// HeaderContentTypeCSV header Content-Type value text/csv.
+	HeaderContentTypeCSV = `text/csv; charset=utf-8`
+
+	// HeaderContentTypeNDJSON header Content-Type value application/x-ndjson.
+	HeaderContentTypeNDJSON = `application/x-ndjson`
+
 	// HeaderContentEncodingGzip header Content-Encoding value gzip.
 	HeaderContentEncodingGzip = `gzip`
 )
 
-var baseHost string
+// handlerSettings - параметры обработчиков. При повторном вызове Init заменяются целиком.
+type handlerSettings struct {
+	baseHost string
+	// Ограничения запроса массового добавления URL, 0 - без ограничения.
+	batchMaxBodySize int64
+	batchMaxItems    int
+	templates        *template.Template
+}
+
+// settings - текущие параметры обработчиков.
+var settings atomic.Pointer[handlerSettings]
+
+func init() {
+	settings.Store(&handlerSettings{})
+}
 
 // Init Подготовка HTTP обработчиков к работе.
-func Init(config *config.Options) {
-	baseHost = config.BaseHost
+// Может вызываться повторно при изменении конфигурации: при ошибке продолжают действовать прежние параметры.
+func Init(config *config.Options) error {
+	tmpl, err := loadTemplates(config.TemplatesDir)
+	if err != nil {
+		return err
+	}
+
+	settings.Store(&handlerSettings{
+		baseHost:         config.BaseHost,
+		batchMaxBodySize: config.BatchMaxBodySize,
+		batchMaxItems:    config.BatchMaxItems,
+		templates:        tmpl,
+	})
+
+	return nil
 }
 
 // AddURL Обработчик запроса на добавление URL пользователя.
@@ -78,6 +126,11 @@ func AddURL(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if err = urlpolicy.Check(URL); err != nil {
+		http.Error(resp, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
 	defer cancel()
 
@@ -97,7 +150,7 @@ func AddURL(resp http.ResponseWriter, req *http.Request) {
 
 			resp.WriteHeader(http.StatusConflict)
 
-			_, err = resp.Write([]byte(baseHost + `/` + uniqErr.ShortKey))
+			_, err = resp.Write([]byte(settings.Load().baseHost + `/` + uniqErr.ShortKey))
 			if err != nil {
 				logger.Error(`error while response write`, err)
 				resp.WriteHeader(http.StatusInternalServerError)
@@ -112,7 +165,7 @@ func AddURL(resp http.ResponseWriter, req *http.Request) {
 
 	resp.WriteHeader(http.StatusCreated)
 
-	_, err = resp.Write([]byte(baseHost + `/` + shortURL))
+	_, err = resp.Write([]byte(settings.Load().baseHost + `/` + shortURL))
 	if err != nil {
 		logger.Error(`error while response write`, err)
 		resp.WriteHeader(http.StatusInternalServerError)
@@ -124,7 +177,7 @@ func GetURL(resp http.ResponseWriter, req *http.Request) {
 
 	logger.Info(`Used "GetURL" handler`)
 
-	shortName := strings.TrimLeft(strings.TrimSpace(req.RequestURI), `/`)
+	shortName, rawQuery, preview := parsePreviewRequest(req)
 	if len(shortName) == 0 {
 		http.Error(resp, `Identifier required`, http.StatusBadRequest)
 		return
@@ -133,19 +186,36 @@ func GetURL(resp http.ResponseWriter, req *http.Request) {
 	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
 	defer cancel()
 
-	URL, isRemoved := service.Get(ctx, nil, shortName)
-	if len(URL) == 0 {
+	info, err := service.GetURLInfo(ctx, shortName)
+	if err != nil || info == nil {
 		http.Error(resp, `Unknown identifier`, http.StatusBadRequest)
 		return
 	}
 
-	if isRemoved {
+	if info.IsDeleted {
 		resp.WriteHeader(http.StatusGone)
 		return
 	}
 
-	resp.Header().Set(HeaderLocation, URL)
-	resp.WriteHeader(http.StatusTemporaryRedirect)
+	if info.IsBlocked {
+		http.Error(resp, `Link blocked by moderator: `+info.BlockReason, http.StatusUnavailableForLegalReasons)
+		return
+	}
+
+	if preview {
+		renderPreview(resp, info, rawQuery)
+		return
+	}
+
+	service.AddClick(ctx, info.ShortKey)
+
+	if info.Redirect.Interstitial {
+		renderPreview(resp, info, rawQuery)
+		return
+	}
+
+	resp.Header().Set(HeaderLocation, service.RedirectLocation(info, rawQuery))
+	resp.WriteHeader(service.RedirectStatus(&info.Redirect))
 }
 
 // Ping Обработчик запроса на проверку работоспособности сервиса.
@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/service"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
+	"github.com/Alheor/shorturl/internal/userauth"
+)
+
+// Колонки CSV файлов импорта и экспорта URL.
+const (
+	csvColumnURL       = `url`
+	csvColumnAlias     = `alias`
+	csvColumnShortURL  = `short_url`
+	csvColumnTitle     = `title`
+	csvColumnNotes     = `notes`
+	csvColumnTags      = `tags`
+	csvColumnExpiresAt = `expires_at`
+)
+
+// Форматы экспорта URL.
+const (
+	// ExportFormatCSV - CSV файл с заголовком.
+	ExportFormatCSV = `csv`
+
+	// ExportFormatJSONL - JSON объект на каждой строке.
+	ExportFormatJSONL = `jsonl`
+)
+
+// QueryParamFormat - параметр запроса формата экспорта.
+const QueryParamFormat = `format`
+
+// exportFlushRows - количество строк экспорта, после подготовки которых они отправляются клиенту.
+const exportFlushRows = 100
+
+// exportColumns - колонки CSV файла экспорта. Файл экспорта можно импортировать повторно.
+var exportColumns = []string{csvColumnURL, csvColumnAlias, csvColumnShortURL, csvColumnTitle, csvColumnNotes, csvColumnTags}
+
+// ImportURLs API обработчик запроса на импорт URL пользователя из CSV файла.
+// Первая строка файла - заголовок с названиями колонок, обязательна колонка url.
+// Файл читается потоково и добавляется частями, в ответе возвращается результат для каждой строки.
+// Строки с колонкой expires_at или alias, отличным от ключа URL, не импортируются.
+// Размер тела запроса ограничен так же, как у запроса массового добавления URL.
+func ImportURLs(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "ImportURLs" handler`)
+
+	var response models.APIResponse
+
+	defer req.Body.Close()
+
+	ctx := req.Context()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		response = models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized}
+		sendAPIResponse(resp, &response)
+		return
+	}
+
+	body := req.Body
+	if limits := settings.Load(); limits.batchMaxBodySize > 0 {
+		body = http.MaxBytesReader(resp, req.Body, limits.batchMaxBodySize)
+	}
+
+	// Сжатое тело запроса разжимает compress.GzipHTTPHandler
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		sendAPIResponse(resp, batchDecodeError(err))
+		return
+	}
+
+	if err != nil {
+		response = models.APIResponse{Error: `csv header required`, StatusCode: http.StatusBadRequest}
+		sendAPIResponse(resp, &response)
+		return
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Табличные редакторы добавляют BOM в начало файла
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\uFEFF")
+		}
+
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, exists := columns[csvColumnURL]; !exists {
+		response = models.APIResponse{Error: `url column required`, StatusCode: http.StatusBadRequest}
+		sendAPIResponse(resp, &response)
+		return
+	}
+
+	result := models.APIImportResponse{Rows: []models.APIImportRowResult{}}
+	chunk := make([]models.ImportRow, 0, service.ImportChunkSize)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			result.Rows = append(result.Rows, importError(parseErr.Line, parseErr.Err))
+			continue
+		}
+
+		if errors.As(err, &maxBytesErr) {
+			sendAPIResponse(resp, batchDecodeError(err))
+			return
+		}
+
+		if err != nil {
+			logger.Error(`read csv error`, err)
+			response = models.APIResponse{Error: `read csv error`, StatusCode: http.StatusBadRequest}
+			sendAPIResponse(resp, &response)
+			return
+		}
+
+		row := importRow(columns, record)
+		row.Line, _ = reader.FieldPos(0)
+
+		if row.URL != `` {
+			if _, err = url.ParseRequestURI(row.URL); err != nil {
+				result.Rows = append(result.Rows, importError(row.Line, errors.New(`url invalid`)))
+				continue
+			}
+
+			if err = urlpolicy.Check(row.URL); err != nil {
+				result.Rows = append(result.Rows, importError(row.Line, err))
+				continue
+			}
+		}
+
+		chunk = append(chunk, row)
+
+		if len(chunk) == service.ImportChunkSize {
+			if !importChunk(ctx, resp, user, chunk, &result) {
+				return
+			}
+
+			chunk = chunk[:0]
+		}
+	}
+
+	if len(chunk) > 0 && !importChunk(ctx, resp, user, chunk, &result) {
+		return
+	}
+
+	slices.SortStableFunc(result.Rows, func(a, b models.APIImportRowResult) int {
+		return a.Line - b.Line
+	})
+
+	for _, row := range result.Rows {
+		switch row.Status {
+		case models.ImportStatusCreated:
+			result.Created++
+		case models.ImportStatusExists:
+			result.Existing++
+		default:
+			result.Failed++
+		}
+	}
+
+	sendJSON(resp, http.StatusOK, result)
+}
+
+// importChunk импорт части строк и добавление их результатов в result.
+// При ошибке репозитория отправляет ответ с ошибкой и возвращает false.
+func importChunk(ctx context.Context, resp http.ResponseWriter, user *models.User, chunk []models.ImportRow, result *models.APIImportResponse) bool {
+	rows, err := service.ImportURLs(ctx, user, chunk)
+	if err != nil {
+		logger.Error(`import urls error`, err)
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return false
+	}
+
+	result.Rows = append(result.Rows, rows...)
+
+	return true
+}
+
+// ExportURLs API обработчик запроса на экспорт URL пользователя в формате CSV или JSONL.
+// URL отправляются клиенту по мере получения из репозитория.
+func ExportURLs(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "ExportURLs" handler`)
+
+	var response models.APIResponse
+
+	format := strings.ToLower(req.URL.Query().Get(QueryParamFormat))
+	if format == `` {
+		format = ExportFormatCSV
+	}
+
+	if format != ExportFormatCSV && format != ExportFormatJSONL {
+		response = models.APIResponse{Error: `format must be csv or jsonl`, StatusCode: http.StatusBadRequest}
+		sendAPIResponse(resp, &response)
+		return
+	}
+
+	ctx := req.Context()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		response = models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized}
+		sendAPIResponse(resp, &response)
+		return
+	}
+
+	tag := service.NormalizeTag(req.URL.Query().Get(QueryParamTag))
+
+	var buf bytes.Buffer
+	var encode func(el *models.HistoryEl) error
+	var writeErr error
+
+	switch format {
+	case ExportFormatCSV:
+		writer := csv.NewWriter(&buf)
+		write := func(record []string) error {
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+
+			writer.Flush()
+			return writer.Error()
+		}
+
+		encode = func(el *models.HistoryEl) error {
+			return write(exportRecord(el))
+		}
+
+		writeErr = write(exportColumns)
+
+	case ExportFormatJSONL:
+		encoder := json.NewEncoder(&buf)
+		encode = func(el *models.HistoryEl) error {
+			short := *el
+			short.ShortURL = settings.Load().baseHost + `/` + el.ShortURL
+			return encoder.Encode(short)
+		}
+	}
+
+	sent := false
+	flush := func() error {
+		if !sent {
+			resp.Header().Set(HeaderContentType, exportContentType(format))
+			resp.Header().Set(HeaderContentDisposition, `attachment; filename="urls.`+format+`"`)
+			resp.WriteHeader(http.StatusOK)
+			sent = true
+		}
+
+		_, err := resp.Write(buf.Bytes())
+		buf.Reset()
+
+		return err
+	}
+
+	chList, chErr := service.GetAll(ctx, user)
+
+	rows := 0
+
+	for el := range chList {
+		// После ошибки записи список дочитывается, чтобы не блокировать репозиторий
+		if writeErr != nil || (tag != `` && !slices.Contains(el.Tags, tag)) {
+			continue
+		}
+
+		if writeErr = encode(&el); writeErr != nil {
+			continue
+		}
+
+		if rows++; rows%exportFlushRows == 0 {
+			writeErr = flush()
+		}
+	}
+
+	for err := range chErr {
+		var notFoundErr *models.HistoryNotFoundErr
+		if errors.As(err, &notFoundErr) {
+			continue
+		}
+
+		logger.Error(`export urls error`, err)
+
+		if !sent {
+			response = models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError}
+			sendAPIResponse(resp, &response)
+		}
+
+		return
+	}
+
+	if writeErr == nil {
+		writeErr = flush()
+	}
+
+	if writeErr != nil {
+		logger.Error(`write response error`, writeErr)
+	}
+}
+
+// importRow получение строки импорта из записи CSV файла.
+func importRow(columns map[string]int, record []string) models.ImportRow {
+	field := func(name string) string {
+		i, exists := columns[name]
+		if !exists || i >= len(record) {
+			return ``
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	return models.ImportRow{
+		URL:       field(csvColumnURL),
+		Alias:     field(csvColumnAlias),
+		ExpiresAt: field(csvColumnExpiresAt),
+		Meta: models.URLMeta{
+			Title: field(csvColumnTitle),
+			Notes: field(csvColumnNotes),
+			// Теги разделяются запятыми, точками с запятой или пробелами
+			Tags: strings.FieldsFunc(field(csvColumnTags), func(r rune) bool {
+				return r == ',' || r == ';' || unicode.IsSpace(r)
+			}),
+		},
+	}
+}
+
+// importError результат импорта строки с ошибкой.
+func importError(line int, err error) models.APIImportRowResult {
+	return models.APIImportRowResult{Line: line, Status: models.ImportStatusError, Error: err.Error()}
+}
+
+// exportRecord запись CSV файла экспорта. В колонку url выводится URL в том виде, в котором его передал пользователь.
+func exportRecord(el *models.HistoryEl) []string {
+	original := el.OriginalURL
+	if el.InputURL != `` {
+		original = el.InputURL
+	}
+
+	return []string{original, el.ShortURL, settings.Load().baseHost + `/` + el.ShortURL, el.Title, el.Notes, strings.Join(el.Tags, `,`)}
+}
+
+// exportContentType заголовок Content-Type формата экспорта.
+func exportContentType(format string) string {
+	if format == ExportFormatJSONL {
+		return HeaderContentTypeNDJSON
+	}
+
+	return HeaderContentTypeCSV
+}
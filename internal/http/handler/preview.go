@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/service"
+)
+
+// Признаки запроса промежуточной страницы.
+const (
+	// previewSuffix - суффикс короткого ключа.
+	previewSuffix = `+`
+
+	// previewParam - параметр запроса.
+	previewParam = `preview`
+)
+
+// previewTemplate - имя шаблона промежуточной страницы.
+const previewTemplate = `preview.html`
+
+//go:embed templates/*.html
+var embeddedTemplates embed.FS
+
+// previewPage - данные промежуточной страницы.
+type previewPage struct {
+	ShortKey    string
+	ShortURL    string
+	Destination string
+	CreatedAt   time.Time
+	Clicks      int64
+}
+
+// loadTemplates загрузка встроенных шаблонов. Шаблоны из каталога dir переопределяют встроенные шаблоны с тем же именем.
+func loadTemplates(dir string) (*template.Template, error) {
+	tmpl, err := template.ParseFS(embeddedTemplates, `templates/*.html`)
+	if err != nil {
+		return nil, err
+	}
+
+	if dir == `` {
+		return tmpl, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, `*.html`))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return tmpl, nil
+	}
+
+	return tmpl.ParseFiles(files...)
+}
+
+// parsePreviewRequest получение короткого ключа и признака запроса промежуточной страницы.
+// Параметр preview удаляется из параметров запроса, чтобы не попасть в адрес перенаправления.
+func parsePreviewRequest(req *http.Request) (shortName string, rawQuery string, preview bool) {
+	shortName = strings.TrimLeft(strings.TrimSpace(req.URL.Path), `/`)
+	rawQuery = req.URL.RawQuery
+
+	if strings.HasSuffix(shortName, previewSuffix) {
+		shortName = strings.TrimSuffix(shortName, previewSuffix)
+		preview = true
+	}
+
+	query := req.URL.Query()
+	if query.Has(previewParam) {
+		preview = preview || query.Get(previewParam) == `1`
+		query.Del(previewParam)
+		rawQuery = query.Encode()
+	}
+
+	return shortName, rawQuery, preview
+}
+
+// renderPreview вывод промежуточной страницы с информацией о сокращенном URL.
+func renderPreview(resp http.ResponseWriter, info *models.URLInfo, rawQuery string) {
+	page := previewPage{
+		ShortKey:    info.ShortKey,
+		ShortURL:    settings.Load().baseHost + `/` + info.ShortKey,
+		Destination: service.RedirectLocation(info, rawQuery),
+		CreatedAt:   info.CreatedAt,
+		Clicks:      info.Clicks,
+	}
+
+	var buf strings.Builder
+
+	if err := settings.Load().templates.ExecuteTemplate(&buf, previewTemplate, page); err != nil {
+		logger.Error(`render preview error: `, err)
+		http.Error(resp, `Internal server error`, http.StatusInternalServerError)
+		return
+	}
+
+	resp.Header().Set(HeaderContentType, HeaderContentTypeTextHTML+`; charset=utf-8`)
+	resp.WriteHeader(http.StatusOK)
+
+	_, err := resp.Write([]byte(buf.String()))
+	if err != nil {
+		logger.Error(`error while writing response`, err)
+	}
+}
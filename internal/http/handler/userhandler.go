@@ -0,0 +1,435 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/qrcode"
+	"github.com/Alheor/shorturl/internal/service"
+	"github.com/Alheor/shorturl/internal/urlpolicy"
+	"github.com/Alheor/shorturl/internal/userauth"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetUserInfo API обработчик запроса на получение информации о текущем пользователе.
+func GetUserInfo(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "GetUserInfo" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	count, err := service.CountURLs(ctx, user)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, models.APIUserResponse{UserID: user.ID, URLCount: count})
+}
+
+// Logout API обработчик запроса на выход пользователя, удаляет cookie пользователя.
+func Logout(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "Logout" handler`)
+
+	userauth.ClearToken(resp)
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTransferToken API обработчик запроса на выпуск токена переноса URL пользователя на другой клиент.
+func CreateTransferToken(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "CreateTransferToken" handler`)
+
+	user := userauth.GetUser(req.Context())
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	token, expiresAt := userauth.NewTransferToken(user)
+
+	sendJSON(resp, http.StatusCreated, models.APITransferTokenResponse{Token: token, ExpiresAt: expiresAt.UTC().Format(time.RFC3339)})
+}
+
+// ClaimURLs API обработчик запроса на перенос URL пользователя, выпустившего токен, текущему пользователю.
+func ClaimURLs(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "ClaimURLs" handler`)
+
+	var body []byte
+	var err error
+	var request models.APIClaimRequest
+
+	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	defer req.Body.Close()
+	if body, err = io.ReadAll(req.Body); err != nil || len(body) == 0 {
+		sendAPIResponse(resp, &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err = json.Unmarshal(body, &request); err != nil || request.Token == `` {
+		sendAPIResponse(resp, &models.APIResponse{Error: `token required`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	token, err := userauth.ParseTransferToken(request.Token, user)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	moved, err := service.MoveUserURLs(ctx, token, user)
+	if errors.Is(err, userauth.ErrInvalidTransferToken) {
+		sendAPIResponse(resp, &models.APIResponse{Error: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, models.APIClaimResponse{Moved: moved})
+}
+
+// GetRedirectSettings API обработчик запроса на получение настроек перенаправления URL пользователя.
+func GetRedirectSettings(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "GetRedirectSettings" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	settings, err := service.GetRedirectSettings(ctx, user, chi.URLParam(req, URLParamShortKey))
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if settings == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, settings)
+}
+
+// SetRedirectSettings API обработчик запроса на изменение настроек перенаправления URL пользователя.
+func SetRedirectSettings(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "SetRedirectSettings" handler`)
+
+	var body []byte
+	var err error
+	var request models.RedirectSettings
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	defer req.Body.Close()
+	if body, err = io.ReadAll(req.Body); err != nil || len(body) == 0 {
+		sendAPIResponse(resp, &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err = json.Unmarshal(body, &request); err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `invalid body`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	found, err := service.SetRedirectSettings(ctx, user, chi.URLParam(req, URLParamShortKey), &request)
+	if err != nil {
+		var settingsErr *models.RedirectSettingsErr
+		if errors.As(err, &settingsErr) {
+			sendAPIResponse(resp, &models.APIResponse{Error: err.Error(), StatusCode: http.StatusUnprocessableEntity})
+			return
+		}
+
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if !found {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, request)
+}
+
+// UpdateURL API обработчик запроса на изменение исходного URL и метаданных сокращенного URL пользователя.
+func UpdateURL(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "UpdateURL" handler`)
+
+	var body []byte
+	var err error
+	var request models.APIUpdateURLRequest
+
+	defer req.Body.Close()
+	if body, err = io.ReadAll(req.Body); err != nil || len(body) == 0 {
+		sendAPIResponse(resp, &models.APIResponse{Error: `nothing to update`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if err = json.Unmarshal(body, &request); err != nil || (request.URL == `` && !request.HasMeta()) {
+		sendAPIResponse(resp, &models.APIResponse{Error: `nothing to update`, StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if request.URL != `` {
+		if _, err = url.ParseRequestURI(request.URL); err != nil {
+			sendAPIResponse(resp, &models.APIResponse{Error: `Url invalid`, StatusCode: http.StatusBadRequest})
+			return
+		}
+
+		if err = urlpolicy.Check(request.URL); err != nil {
+			sendAPIResponse(resp, &models.APIResponse{Error: err.Error(), StatusCode: http.StatusUnprocessableEntity})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	shortKey := chi.URLParam(req, URLParamShortKey)
+
+	info, err := service.GetUserURLInfo(ctx, user, shortKey)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if info == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if info.IsDeleted {
+		sendAPIResponse(resp, &models.APIResponse{Error: `URL deleted`, StatusCode: http.StatusGone})
+		return
+	}
+
+	// Метаданные проверяются до изменения исходного URL, чтобы не применять запрос частично
+	var meta *models.URLMeta
+	if request.HasMeta() {
+		if meta, err = service.MergeURLMeta(info.URLMeta, &request); err != nil {
+			sendAPIResponse(resp, &models.APIResponse{Error: err.Error(), StatusCode: http.StatusUnprocessableEntity})
+			return
+		}
+	}
+
+	found := true
+
+	if request.URL != `` {
+		found, err = service.UpdateURL(ctx, user, shortKey, request.URL)
+		if err != nil {
+			var uniqErr *models.UniqueErr
+			if errors.As(err, &uniqErr) {
+				sendAPIResponse(resp, &models.APIResponse{Result: settings.Load().baseHost + `/` + uniqErr.ShortKey, StatusCode: http.StatusConflict})
+				return
+			}
+
+			sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+			return
+		}
+	}
+
+	if found && meta != nil {
+		if found, err = service.SetURLMeta(ctx, user, shortKey, meta); err != nil {
+			sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+			return
+		}
+	}
+
+	if !found {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	info, err = service.GetUserURLInfo(ctx, user, shortKey)
+	if err != nil || info == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	history, err := service.GetURLHistory(ctx, user, shortKey)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, models.APIUpdateURLResponse{
+		ShortURL:    settings.Load().baseHost + `/` + shortKey,
+		OriginalURL: info.OriginalURL,
+		History:     history,
+		URLMeta:     info.URLMeta,
+	})
+}
+
+// GetURLHistory API обработчик запроса на получение истории изменений исходного URL сокращенного URL пользователя.
+func GetURLHistory(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "GetURLHistory" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	shortKey := chi.URLParam(req, URLParamShortKey)
+
+	info, err := service.GetUserURLInfo(ctx, user, shortKey)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if info == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	history, err := service.GetURLHistory(ctx, user, shortKey)
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	sendJSON(resp, http.StatusOK, history)
+}
+
+// qrCodeCacheControl - заголовок Cache-Control QR кода. Изображение зависит только от короткого ключа и параметров,
+// поэтому может кэшироваться, но только клиентом, так как запрос требует авторизации.
+const qrCodeCacheControl = `private, max-age=86400`
+
+// GetQRCode API обработчик запроса на получение QR кода сокращенного URL пользователя.
+func GetQRCode(resp http.ResponseWriter, req *http.Request) {
+
+	logger.Info(`Used "GetQRCode" handler`)
+
+	ctx, cancel := context.WithTimeout(req.Context(), 1*time.Second)
+	defer cancel()
+
+	user := userauth.GetUser(ctx)
+	if user == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unauthorized`, StatusCode: http.StatusUnauthorized})
+		return
+	}
+
+	opts, err := qrcode.ParseOptions(req.URL.Query())
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	info, err := service.GetUserURLInfo(ctx, user, chi.URLParam(req, URLParamShortKey))
+	if err != nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	if info == nil {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Unknown identifier`, StatusCode: http.StatusNotFound})
+		return
+	}
+
+	if info.IsDeleted {
+		sendAPIResponse(resp, &models.APIResponse{Error: `URL deleted`, StatusCode: http.StatusGone})
+		return
+	}
+
+	if info.IsBlocked {
+		sendAPIResponse(resp, &models.APIResponse{Error: `Link blocked by moderator: ` + info.BlockReason, StatusCode: http.StatusUnavailableForLegalReasons})
+		return
+	}
+
+	shortURL := settings.Load().baseHost + `/` + info.ShortKey
+
+	sum := sha256.Sum256([]byte(shortURL + `|` + opts.Key()))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	resp.Header().Set(HeaderCacheControl, qrCodeCacheControl)
+	resp.Header().Set(HeaderETag, etag)
+
+	if matchETag(req.Header.Get(HeaderIfNoneMatch), etag) {
+		resp.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	image, err := qrcode.Encode(shortURL, opts)
+	if err != nil {
+		logger.Error(`qr code encode error: `, err)
+		sendAPIResponse(resp, &models.APIResponse{Error: `Internal error`, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	resp.Header().Set(HeaderContentType, opts.ContentType())
+	resp.WriteHeader(http.StatusOK)
+
+	_, err = resp.Write(image)
+	if err != nil {
+		logger.Error(`error while writing response`, err)
+	}
+}
+
+// matchETag проверка совпадения заголовка If-None-Match с etag.
+func matchETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, `,`) {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), `W/`)
+		if candidate == etag || candidate == `*` {
+			return true
+		}
+	}
+
+	return false
+}
@@ -8,6 +8,7 @@ package router
 import (
 	"net/http"
 
+	"github.com/Alheor/shorturl/internal/adminauth"
 	"github.com/Alheor/shorturl/internal/compress"
 	"github.com/Alheor/shorturl/internal/http/handler"
 	"github.com/Alheor/shorturl/internal/logger"
@@ -35,6 +36,39 @@ func GetRoutes() chi.Router {
 	r.Delete(`/api/user/urls`,
 		middlewareConveyor(handler.DeleteShorten, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
 
+	r.Post(`/api/user/urls/import`,
+		middlewareConveyor(handler.ImportURLs, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Get(`/api/user/urls/export`,
+		middlewareConveyor(handler.ExportURLs, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Get(`/api/user/urls/{`+handler.URLParamShortKey+`}/redirect`,
+		middlewareConveyor(handler.GetRedirectSettings, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Put(`/api/user/urls/{`+handler.URLParamShortKey+`}/redirect`,
+		middlewareConveyor(handler.SetRedirectSettings, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Patch(`/api/user/urls/{`+handler.URLParamShortKey+`}`,
+		middlewareConveyor(handler.UpdateURL, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Get(`/api/user/urls/{`+handler.URLParamShortKey+`}/history`,
+		middlewareConveyor(handler.GetURLHistory, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Get(`/api/user/urls/{`+handler.URLParamShortKey+`}/qr`,
+		middlewareConveyor(handler.GetQRCode, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Get(`/api/user/me`,
+		middlewareConveyor(handler.GetUserInfo, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Post(`/api/user/logout`,
+		middlewareConveyor(handler.Logout, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Post(`/api/user/transfer-token`,
+		middlewareConveyor(handler.CreateTransferToken, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
+	r.Post(`/api/user/claim`,
+		middlewareConveyor(handler.ClaimURLs, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
+
 	r.Post(`/`,
 		middlewareConveyor(handler.AddURL, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, userauth.AuthHTTPHandler))
 
@@ -47,6 +81,32 @@ func GetRoutes() chi.Router {
 	return r
 }
 
+// GetAdminRoutes Загрузка маршрутизации API администратора.
+// Маршруты подключаются к отдельному серверу, либо к основному по пути /api/admin.
+func GetAdminRoutes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get(`/urls/{`+handler.URLParamShortKey+`}`,
+		middlewareConveyor(handler.AdminGetURL, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, adminauth.AdminHTTPHandler))
+
+	r.Put(`/urls/{`+handler.URLParamShortKey+`}/block`,
+		middlewareConveyor(handler.AdminBlockURL, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, adminauth.AdminHTTPHandler))
+
+	r.Delete(`/urls/{`+handler.URLParamShortKey+`}/block`,
+		middlewareConveyor(handler.AdminUnblockURL, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, adminauth.AdminHTTPHandler))
+
+	r.Get(`/users/{`+handler.URLParamUserID+`}/urls`,
+		middlewareConveyor(handler.AdminGetUserURLs, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, adminauth.AdminHTTPHandler))
+
+	r.Delete(`/users/{`+handler.URLParamUserID+`}`,
+		middlewareConveyor(handler.AdminPurgeUser, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, adminauth.AdminHTTPHandler))
+
+	r.Get(`/keys/usage`,
+		middlewareConveyor(handler.AdminGetKeyUsage, logger.LoggingHTTPHandler, compress.GzipHTTPHandler, adminauth.AdminHTTPHandler))
+
+	return r
+}
+
 // Функция - конвейер.
 func middlewareConveyor(h http.HandlerFunc, middlewares ...HTTPMiddleware) http.HandlerFunc {
 	for _, middleware := range middlewares {
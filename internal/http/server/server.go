@@ -3,6 +3,13 @@
 // # Описание
 //
 // Конфигурация и запуск HTTP сервера.
+//
+// API администратора запускается на отдельном HTTP сервере, если задан его адрес,
+// либо подключается к основному серверу по пути /api/admin, если задана доверенная подсеть.
+// При включенном HTTPS отдельный сервер API администратора также работает по HTTPS.
+//
+// При включенном HTTPS сертификат выбирается по имени сервера (SNI), сертификаты перезагружаются
+// по сигналу SIGHUP, при изменении их файлов или конфигурации без разрыва установленных соединений.
 package server
 
 import (
@@ -20,12 +27,51 @@ import (
 	"go.uber.org/zap"
 )
 
+// certificates - TLS сертификаты сервера, выбираемые по имени сервера (SNI).
+var certificates = tlscerts.NewManager()
+
+// ReloadCertificate загрузка TLS сертификатов сервера из конфигурации. Новые сертификаты используются для новых соединений.
+// Если свои сертификаты не заданы, то формируется самоподписанный сертификат.
+func ReloadCertificate(cfg *config.Options) error {
+	if !cfg.EnableHTTPS {
+		return nil
+	}
+
+	var pairs []tlscerts.KeyPair
+
+	if cfg.TLSCert != `` && cfg.TLSKey != `` {
+		pairs = append(pairs, tlscerts.KeyPair{CertBase64: cfg.TLSCert, KeyBase64: cfg.TLSKey})
+	}
+
+	if len(cfg.TLSCertFiles) != len(cfg.TLSKeyFiles) {
+		return errors.New(`TLS certificate and key files must be set in pairs`)
+	}
+
+	for i := range cfg.TLSCertFiles {
+		pairs = append(pairs, tlscerts.KeyPair{CertFile: cfg.TLSCertFiles[i], KeyFile: cfg.TLSKeyFiles[i]})
+	}
+
+	return certificates.SetPairs(pairs)
+}
+
 // StartServer запуск http сервера
 func StartServer(cfg *config.Options) {
 
+	routes := router.GetRoutes()
+
+	if cfg.EnableHTTPS {
+		startCertificates(cfg)
+	}
+
+	if cfg.AdminToken != `` && cfg.AdminAddr != `` {
+		startAdminServer(cfg)
+	} else if cfg.AdminToken != `` && cfg.TrustedSubnet != `` {
+		routes.Mount(`/api/admin`, router.GetAdminRoutes())
+	}
+
 	srv := &http.Server{
 		Addr:    cfg.Addr,
-		Handler: router.GetRoutes(),
+		Handler: routes,
 	}
 
 	shutdown.GetCloser().Add(func(ctx context.Context) error {
@@ -41,31 +87,9 @@ func StartServer(cfg *config.Options) {
 		if cfg.EnableHTTPS {
 			logger.Info("Starting HTTPS server", zap.String("addr", cfg.Addr))
 
-			// Создаем TLS конфигурацию для безопасности
-			srv.TLSConfig = &tls.Config{
-				MinVersion: tls.VersionTLS12,
-				CipherSuites: []uint16{
-					tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-					tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-					tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-					tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-				},
-			}
-
-			var certFile, keyFile string
-			var err error
-
-			if cfg.TLSCert != `` && cfg.TLSKey != `` {
-				certFile, keyFile, err = tlscerts.LoadCert(cfg.TLSCert, cfg.TLSKey)
-			} else {
-				certFile, keyFile, err = tlscerts.GenerateCert()
-			}
+			srv.TLSConfig = newTLSConfig()
 
-			if err != nil {
-				logger.Fatal(`error while prepare certificates`, err)
-			}
-
-			if err = srv.ListenAndServeTLS(certFile, keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			if err := srv.ListenAndServeTLS(``, ``); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				logger.Fatal(`error while starting https server`, err)
 			}
 
@@ -78,3 +102,72 @@ func StartServer(cfg *config.Options) {
 		}
 	}()
 }
+
+// startCertificates загрузка TLS сертификатов и запуск отслеживания изменений их файлов.
+// Сертификаты общие для основного сервера и сервера API администратора.
+func startCertificates(cfg *config.Options) {
+	if err := ReloadCertificate(cfg); err != nil {
+		logger.Fatal(`error while prepare certificates`, err)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	certificates.Watch(watchCtx, tlscerts.DefaultReloadInterval)
+
+	shutdown.GetCloser().Add(func(ctx context.Context) error {
+		cancel()
+		return nil
+	})
+}
+
+// newTLSConfig TLS конфигурация сервера с выбором сертификата по имени сервера.
+func newTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		},
+		GetCertificate: certificates.GetCertificate,
+	}
+}
+
+// startAdminServer запуск http сервера API администратора.
+// При включенном HTTPS сервер использует те же сертификаты, что и основной сервер.
+func startAdminServer(cfg *config.Options) {
+
+	srv := &http.Server{
+		Addr:    cfg.AdminAddr,
+		Handler: router.GetAdminRoutes(),
+	}
+
+	shutdown.GetCloser().Add(func(ctx context.Context) error {
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			logger.Error(`error while shutting down admin server`, err)
+		}
+
+		return nil
+	})
+
+	go func() {
+		if cfg.EnableHTTPS {
+			logger.Info("Starting admin HTTPS server", zap.String("addr", cfg.AdminAddr))
+
+			srv.TLSConfig = newTLSConfig()
+
+			if err := srv.ListenAndServeTLS(``, ``); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Fatal(`error while starting admin https server`, err)
+			}
+
+			return
+		}
+
+		logger.Info("Starting admin HTTP server", zap.String("addr", cfg.AdminAddr))
+
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal(`error while starting admin http server`, err)
+		}
+	}()
+}
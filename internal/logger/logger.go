@@ -36,6 +36,9 @@ type (
 
 var logger *zap.Logger
 
+// level - уровень логгера по умолчанию, может быть изменен без перезапуска сервиса.
+var level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
 // Init Инициализация логгера.
 func Init(cfg *zap.Config) error {
 	if logger != nil {
@@ -46,6 +49,7 @@ func Init(cfg *zap.Config) error {
 
 	if cfg == nil {
 		config = zap.NewProductionConfig()
+		config.Level = level
 		config.EncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.RFC3339)
 	} else {
 		config = *cfg
@@ -62,6 +66,15 @@ func Init(cfg *zap.Config) error {
 	return nil
 }
 
+// SetLevel Изменение уровня логгера по умолчанию: debug, info, warn или error. Пустое значение - info.
+func SetLevel(name string) error {
+	if name == `` {
+		name = zap.InfoLevel.String()
+	}
+
+	return level.UnmarshalText([]byte(name))
+}
+
 // Write Реализация интерфейса Writer
 func (r *loggingResponseWriter) Write(b []byte) (int, error) {
 	size, err := r.ResponseWriter.Write(b)
@@ -109,6 +122,12 @@ func Info(msg string, fields ...zapcore.Field) {
 	defer logger.Sync()
 }
 
+// Warn warning level.
+func Warn(msg string, fields ...zapcore.Field) {
+	logger.Warn(msg, fields...)
+	defer logger.Sync()
+}
+
 // Error error level.
 func Error(msg string, err error) {
 	if err != nil {
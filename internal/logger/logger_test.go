@@ -72,6 +72,22 @@ func TestInitWithOtherCfg(t *testing.T) {
 	assert.NotNil(t, logger)
 }
 
+func TestSetLevel(t *testing.T) {
+	defer SetLevel(``)
+
+	err := SetLevel(`error`)
+	require.NoError(t, err)
+	assert.Equal(t, zap.ErrorLevel, level.Level())
+
+	err = SetLevel(`unknown`)
+	assert.Error(t, err)
+	assert.Equal(t, zap.ErrorLevel, level.Level())
+
+	err = SetLevel(``)
+	require.NoError(t, err)
+	assert.Equal(t, zap.InfoLevel, level.Level())
+}
+
 func runTests(t *testing.T, tests []test) {
 	sink := &MemorySink{new(bytes.Buffer)}
 
@@ -4,6 +4,7 @@ package models
 // APIRequest - тело запроса при добавлении URL пользователя.
 type APIRequest struct {
 	URL string `json:"url"`
+	URLMeta
 }
 
 // APIResponse - тело ответа сервиса.
@@ -17,6 +18,7 @@ type APIResponse struct {
 type APIBatchRequestEl struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	URLMeta
 }
 
 // APIBatchResponseEl - тело ответа при массовом добавлении URL пользователя.
@@ -24,3 +26,130 @@ type APIBatchResponseEl struct {
 	CorrelationID string `json:"correlation_id"`
 	ShortURL      string `json:"short_url"`
 }
+
+// Статусы элементов массового добавления URL.
+const (
+	// BatchStatusCreated - URL сокращен.
+	BatchStatusCreated = `created`
+	// BatchStatusExists - URL уже был сокращен ранее, возвращается существующий сокращенный URL.
+	BatchStatusExists = `exists`
+	// BatchStatusInvalid - элемент невалиден.
+	BatchStatusInvalid = `invalid`
+	// BatchStatusBlocked - URL отклонен политикой допустимых URL.
+	BatchStatusBlocked = `blocked`
+)
+
+// APIBatchResultEl - результат массового добавления одного URL пользователя.
+type APIBatchResultEl struct {
+	CorrelationID string `json:"correlation_id"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+}
+
+// APIUserResponse - тело ответа с информацией о пользователе.
+type APIUserResponse struct {
+	UserID   string `json:"user_id"`
+	URLCount int    `json:"url_count"`
+}
+
+// APITransferTokenResponse - тело ответа с токеном переноса URL пользователя.
+type APITransferTokenResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// APIClaimRequest - тело запроса на перенос URL пользователя.
+type APIClaimRequest struct {
+	Token string `json:"token"`
+}
+
+// APIClaimResponse - тело ответа на перенос URL пользователя.
+type APIClaimResponse struct {
+	Moved int `json:"moved"`
+}
+
+// APIAdminBlockRequest - тело запроса администратора на блокировку URL.
+type APIAdminBlockRequest struct {
+	Reason string `json:"reason"`
+}
+
+// APIAdminPurgeResponse - тело ответа администратора на удаление пользователя.
+type APIAdminPurgeResponse struct {
+	Removed int `json:"removed"`
+}
+
+// APIAdminKeyUsage - использование ключа подписи токенов пользователей.
+type APIAdminKeyUsage struct {
+	// KeyID - идентификатор ключа: первые 8 байт SHA-256 ключа в hex, для JWT совпадает с заголовком kid.
+	KeyID string `json:"kid"`
+	// Primary - ключ используется для подписи новых токенов.
+	Primary bool `json:"primary"`
+	// Validations - количество успешных проверок токенов ключом с момента запуска экземпляра сервиса.
+	Validations int64 `json:"validations"`
+}
+
+// APIAdminKeyUsageResponse - тело ответа администратора на получение использования ключей подписи.
+type APIAdminKeyUsageResponse struct {
+	// Format - формат токена пользователя: cookie или jwt.
+	Format string `json:"format"`
+	// Keys - настроенные ключи подписи в порядке конфигурации, первый ключ основной.
+	Keys []APIAdminKeyUsage `json:"keys"`
+}
+
+// APIUpdateURLRequest - тело запроса на изменение сокращенного URL. Изменяются только переданные поля.
+type APIUpdateURLRequest struct {
+	URL   string    `json:"url"`
+	Title *string   `json:"title"`
+	Notes *string   `json:"notes"`
+	Tags  *[]string `json:"tags"`
+}
+
+// HasMeta проверка наличия изменений метаданных.
+func (r *APIUpdateURLRequest) HasMeta() bool {
+	return r.Title != nil || r.Notes != nil || r.Tags != nil
+}
+
+// APIUpdateURLResponse - тело ответа на изменение сокращенного URL.
+type APIUpdateURLResponse struct {
+	ShortURL    string              `json:"short_url"`
+	OriginalURL string              `json:"original_url"`
+	History     []DestinationChange `json:"history"`
+	URLMeta
+}
+
+// Статусы строк импорта URL.
+const (
+	// ImportStatusCreated - URL сокращен.
+	ImportStatusCreated = BatchStatusCreated
+	// ImportStatusExists - URL уже был сокращен ранее.
+	ImportStatusExists = BatchStatusExists
+	// ImportStatusError - строка не импортирована.
+	ImportStatusError = `error`
+)
+
+// ImportRow - строка импорта URL пользователя.
+type ImportRow struct {
+	// Line - номер строки в импортируемом файле.
+	Line      int
+	URL       string
+	Alias     string
+	ExpiresAt string
+	Meta      URLMeta
+}
+
+// APIImportRowResult - результат импорта строки.
+type APIImportRowResult struct {
+	Line     int    `json:"line"`
+	Status   string `json:"status"`
+	ShortURL string `json:"short_url,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// APIImportResponse - тело ответа на импорт URL пользователя.
+type APIImportResponse struct {
+	Created  int                  `json:"created"`
+	Existing int                  `json:"existing"`
+	Failed   int                  `json:"failed"`
+	Rows     []APIImportRowResult `json:"rows"`
+}
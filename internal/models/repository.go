@@ -1,10 +1,20 @@
 package models
 
+import "time"
+
 // BatchEl - элемент сокращенного URL при обработке массовой вставки.
 type BatchEl struct {
 	CorrelationID string
 	OriginalURL   string
 	ShortURL      string
+	// InputURL - URL в том виде, в котором его передал пользователь, если он отличается от OriginalURL.
+	InputURL string
+	// Meta - пользовательские метаданные.
+	Meta URLMeta
+	// Exists - URL уже был сокращен пользователем, ShortURL содержит ключ существующего URL. Заполняется репозиторием.
+	Exists bool
+	// KeyInUse - ключ ShortURL занят другим URL. Заполняется репозиторием вместе с ошибкой ErrShortKeyInUse.
+	KeyInUse bool
 }
 
 // UniqueErr - тип ошибки, обозначающий, что вставляем URL уже существует.
@@ -27,4 +37,82 @@ type HistoryNotFoundErr struct {
 type HistoryEl struct {
 	OriginalURL string `json:"original_url"`
 	ShortURL    string `json:"short_url"`
+	// InputURL - URL в том виде, в котором его передал пользователь, если он отличается от OriginalURL.
+	InputURL string `json:"input_url,omitempty"`
+	URLMeta
+}
+
+// URLInfo - полная информация о сокращенном URL, используется в API администратора.
+type URLInfo struct {
+	ShortKey    string `json:"short_key"`
+	OriginalURL string `json:"original_url"`
+	InputURL    string `json:"input_url,omitempty"`
+	UserID      string `json:"user_id"`
+	IsDeleted   bool   `json:"is_deleted"`
+	IsBlocked   bool   `json:"is_blocked"`
+	BlockReason string `json:"block_reason,omitempty"`
+	// Redirect - настройки перенаправления.
+	Redirect RedirectSettings `json:"redirect"`
+	// CreatedAt - дата создания, пустая для URL, созданных до учета даты создания.
+	CreatedAt time.Time `json:"created_at"`
+	// Clicks - количество переходов по сокращенному URL.
+	Clicks int64 `json:"clicks"`
+	URLMeta
+}
+
+// URLMeta - пользовательские метаданные сокращенного URL.
+type URLMeta struct {
+	// Title - название.
+	Title string `json:"title,omitempty"`
+	// Notes - заметки.
+	Notes string `json:"notes,omitempty"`
+	// Tags - теги в нижнем регистре, отсортированные по алфавиту.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// IsEmpty проверка отсутствия метаданных.
+func (m *URLMeta) IsEmpty() bool {
+	return m.Title == `` && m.Notes == `` && len(m.Tags) == 0
+}
+
+// URLMetaErr - тип ошибки, обозначающий, что метаданные URL невалидны.
+type URLMetaErr struct {
+	Err error
+}
+
+// Error реализация интерфейса Error
+func (e *URLMetaErr) Error() string {
+	return e.Err.Error()
+}
+
+// RedirectSettings - настройки перенаправления по сокращенному URL.
+type RedirectSettings struct {
+	// StatusCode - код ответа при перенаправлении (301, 302, 307 или 308), 0 - код по умолчанию (307).
+	StatusCode int `json:"status_code"`
+	// PassQuery - передавать параметры запроса сокращенного URL в исходный URL.
+	PassQuery bool `json:"pass_query"`
+	// UTMParams - UTM параметры, добавляемые к исходному URL.
+	UTMParams map[string]string `json:"utm_params,omitempty"`
+	// Interstitial - всегда показывать промежуточную страницу с информацией об URL вместо перенаправления.
+	Interstitial bool `json:"interstitial,omitempty"`
+}
+
+// RedirectSettingsErr - тип ошибки, обозначающий, что настройки перенаправления невалидны.
+type RedirectSettingsErr struct {
+	Err error
+}
+
+// Error реализация интерфейса Error
+func (e *RedirectSettingsErr) Error() string {
+	return e.Err.Error()
+}
+
+// DestinationChange - изменение исходного URL сокращенного URL.
+type DestinationChange struct {
+	// PreviousURL - исходный URL до изменения.
+	PreviousURL string `json:"previous_url"`
+	// NewURL - исходный URL после изменения.
+	NewURL string `json:"new_url"`
+	// ChangedAt - дата изменения.
+	ChangedAt time.Time `json:"changed_at"`
 }
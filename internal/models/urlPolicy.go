@@ -0,0 +1,12 @@
+package models
+
+// URLPolicyErr - тип ошибки, обозначающий, что URL не соответствует политике допустимых URL.
+type URLPolicyErr struct {
+	URL    string
+	Reason string
+}
+
+// Error реализация интерфейса Error
+func (e *URLPolicyErr) Error() string {
+	return `url rejected: ` + e.Reason
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLPolicyErr(t *testing.T) {
+	var err error = &URLPolicyErr{URL: `javascript:alert(1)`, Reason: `scheme "javascript" is not allowed`}
+
+	assert.Equal(t, `url rejected: scheme "javascript" is not allowed`, err.Error())
+
+	var policyErr *URLPolicyErr
+	assert.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, `javascript:alert(1)`, policyErr.URL)
+}
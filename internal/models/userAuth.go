@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type contextKeyXAuthUser string
 
 // CookiesName - имя ключа cookie.
@@ -13,6 +15,17 @@ type User struct {
 	ID string
 }
 
+// TransferToken - проверенный токен переноса URL пользователя. Токен принимается один раз:
+// его идентификатор сохраняется в репозитории вместе с переносом URL.
+type TransferToken struct {
+	// ID - идентификатор токена.
+	ID string
+	// From - пользователь, выпустивший токен.
+	From *User
+	// ExpiresAt - время истечения токена.
+	ExpiresAt time.Time
+}
+
 // UserCookie - структура пользовательской cookie.
 type UserCookie struct {
 	User User
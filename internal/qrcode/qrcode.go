@@ -0,0 +1,170 @@
+// Package qrcode - сервис генерации QR кодов.
+//
+// # Описание
+//
+// Кодирует строку в QR код и возвращает изображение в формате PNG или SVG.
+// Кодирование выполняется локально, без обращения к внешним сервисам.
+package qrcode
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// Форматы изображения.
+const (
+	// FormatPNG - PNG изображение.
+	FormatPNG = `png`
+
+	// FormatSVG - SVG изображение.
+	FormatSVG = `svg`
+)
+
+// Размер изображения в пикселях.
+const (
+	// DefaultSize - размер по умолчанию.
+	DefaultSize = 256
+
+	// MinSize - минимальный размер.
+	MinSize = 64
+
+	// MaxSize - максимальный размер.
+	MaxSize = 2048
+)
+
+// DefaultECC - уровень коррекции ошибок по умолчанию.
+const DefaultECC = `M`
+
+// Параметры запроса.
+const (
+	paramFormat = `format`
+	paramSize   = `size`
+	paramECC    = `ecc`
+)
+
+// eccLevels - уровни коррекции ошибок: L - 7%, M - 15%, Q - 25%, H - 30%.
+var eccLevels = map[string]goqrcode.RecoveryLevel{
+	`L`: goqrcode.Low,
+	`M`: goqrcode.Medium,
+	`Q`: goqrcode.High,
+	`H`: goqrcode.Highest,
+}
+
+// contentTypes - заголовки Content-Type форматов.
+var contentTypes = map[string]string{
+	FormatPNG: `image/png`,
+	FormatSVG: `image/svg+xml`,
+}
+
+// Options - параметры QR кода.
+type Options struct {
+	// Format - формат изображения.
+	Format string
+	// Size - размер изображения в пикселях.
+	Size int
+	// ECC - уровень коррекции ошибок.
+	ECC string
+}
+
+// ParseOptions Получение параметров QR кода из параметров запроса format, size и ecc.
+// Для отсутствующих параметров используются значения по умолчанию.
+func ParseOptions(query url.Values) (*Options, error) {
+	opts := &Options{Format: FormatPNG, Size: DefaultSize, ECC: DefaultECC}
+
+	if format := query.Get(paramFormat); format != `` {
+		opts.Format = strings.ToLower(format)
+	}
+
+	if _, exists := contentTypes[opts.Format]; !exists {
+		return nil, errors.New(`format must be png or svg`)
+	}
+
+	if size := query.Get(paramSize); size != `` {
+		var err error
+
+		opts.Size, err = strconv.Atoi(size)
+		if err != nil || opts.Size < MinSize || opts.Size > MaxSize {
+			return nil, errors.New(`size must be between ` + strconv.Itoa(MinSize) + ` and ` + strconv.Itoa(MaxSize))
+		}
+	}
+
+	if ecc := query.Get(paramECC); ecc != `` {
+		opts.ECC = strings.ToUpper(ecc)
+	}
+
+	if _, exists := eccLevels[opts.ECC]; !exists {
+		return nil, errors.New(`ecc must be L, M, Q or H`)
+	}
+
+	return opts, nil
+}
+
+// ContentType Заголовок Content-Type изображения.
+func (o *Options) ContentType() string {
+	return contentTypes[o.Format]
+}
+
+// Key Строковое представление параметров, пригодное для построения ключа кэширования.
+func (o *Options) Key() string {
+	return o.Format + `:` + strconv.Itoa(o.Size) + `:` + o.ECC
+}
+
+// Encode Кодирование content в QR код.
+func Encode(content string, opts *Options) ([]byte, error) {
+	level, exists := eccLevels[opts.ECC]
+	if !exists {
+		return nil, errors.New(`unknown ecc level "` + opts.ECC + `"`)
+	}
+
+	code, err := goqrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Format {
+	case FormatPNG:
+		return code.PNG(opts.Size)
+	case FormatSVG:
+		return encodeSVG(code.Bitmap(), opts.Size), nil
+	default:
+		return nil, errors.New(`unknown format "` + opts.Format + `"`)
+	}
+}
+
+// encodeSVG построение SVG изображения по матрице модулей QR кода.
+// Соседние темные модули строки объединяются в один прямоугольник, чтобы уменьшить размер документа.
+func encodeSVG(bitmap [][]bool, size int) []byte {
+	modules := strconv.Itoa(len(bitmap))
+	pixels := strconv.Itoa(size)
+
+	var sb strings.Builder
+
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="` + pixels + `" height="` + pixels +
+		`" viewBox="0 0 ` + modules + ` ` + modules + `" shape-rendering="crispEdges">` + "\n")
+	sb.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/>` + "\n")
+	sb.WriteString(`<path fill="#000000" d="`)
+
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+
+			sb.WriteString(`M` + strconv.Itoa(start) + ` ` + strconv.Itoa(y) + `h` + strconv.Itoa(x-start) + `v1h-` + strconv.Itoa(x-start) + `z`)
+		}
+	}
+
+	sb.WriteString(`"/>` + "\n" + `</svg>` + "\n")
+
+	return []byte(sb.String())
+}
@@ -0,0 +1,72 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *Options
+		wantErr bool
+	}{
+		{name: `defaults`, query: ``, want: &Options{Format: FormatPNG, Size: DefaultSize, ECC: DefaultECC}},
+		{name: `svg with size and ecc`, query: `format=SVG&size=512&ecc=h`, want: &Options{Format: FormatSVG, Size: 512, ECC: `H`}},
+		{name: `unknown format`, query: `format=gif`, wantErr: true},
+		{name: `size too small`, query: `size=10`, wantErr: true},
+		{name: `size too big`, query: `size=100000`, wantErr: true},
+		{name: `size not a number`, query: `size=big`, wantErr: true},
+		{name: `unknown ecc`, query: `ecc=X`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			opts, err := ParseOptions(query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+		})
+	}
+}
+
+func TestEncodePNG(t *testing.T) {
+	data, err := Encode(`http://localhost:8080/123`, &Options{Format: FormatPNG, Size: 128, ECC: `Q`})
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 128, img.Bounds().Dx())
+	assert.Equal(t, 128, img.Bounds().Dy())
+}
+
+func TestEncodeSVG(t *testing.T) {
+	data, err := Encode(`http://localhost:8080/123`, &Options{Format: FormatSVG, Size: 300, ECC: `L`})
+	require.NoError(t, err)
+
+	svg := string(data)
+	assert.True(t, strings.HasPrefix(svg, `<?xml`))
+	assert.Contains(t, svg, `width="300" height="300"`)
+	assert.Contains(t, svg, `<path fill="#000000" d="M`)
+}
+
+func TestEncodeSVGBitmap(t *testing.T) {
+	svg := string(encodeSVG([][]bool{{true, true, false}, {false, false, true}, {false, false, false}}, 90))
+
+	assert.Contains(t, svg, `viewBox="0 0 3 3"`)
+	assert.Contains(t, svg, `d="M0 0h2v1h-2zM2 1h1v1h-1z"`)
+}
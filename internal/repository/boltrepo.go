@@ -0,0 +1,855 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/urlhasher"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ IRepository = (*BoltRepo)(nil)
+
+// Бакеты встроенного репозитория.
+var (
+	// bucketURLs - URL: короткий ключ - boltRecord в формате JSON.
+	bucketURLs = []byte(`urls`)
+
+	// bucketUsers - индексы пользователей: вложенный бакет на каждого пользователя, исходный URL - короткий ключ.
+	bucketUsers = []byte(`users`)
+
+	// bucketDeleted - удаленные пользователями URL: короткий ключ - пустое значение.
+	bucketDeleted = []byte(`deleted`)
+
+	// bucketTransfers - использованные токены переноса: идентификатор токена - время истечения в формате RFC 3339.
+	bucketTransfers = []byte(`transfers`)
+)
+
+// boltOpenTimeout - время ожидания блокировки файла БД другим процессом.
+const boltOpenTimeout = 5 * time.Second
+
+// BoltRepo - структура встроенного репозитория на основе B-дерева в файле.
+type BoltRepo struct {
+	db *bolt.DB
+	// clicks - переходы по URL, которые еще не сохранены в БД.
+	clicks *clickCounter
+}
+
+// boltRecord - URL во встроенном репозитории.
+type boltRecord struct {
+	UserID      string `json:"user_id"`
+	OriginalURL string `json:"original_url"`
+	// InputURL - URL в том виде, в котором его передал пользователь, если он отличается от OriginalURL.
+	InputURL    string                     `json:"input_url,omitempty"`
+	IsBlocked   bool                       `json:"is_blocked,omitempty"`
+	BlockReason string                     `json:"block_reason,omitempty"`
+	Redirect    models.RedirectSettings    `json:"redirect"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	Clicks      int64                      `json:"clicks,omitempty"`
+	History     []models.DestinationChange `json:"history,omitempty"`
+	Title       string                     `json:"title,omitempty"`
+	Notes       string                     `json:"notes,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+}
+
+// openBoltRepo открытие файла БД встроенного репозитория и создание бакетов.
+func openBoltRepo(ctx context.Context, path string) (*BoltRepo, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketURLs, bucketUsers, bucketDeleted, bucketTransfers} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	br := &BoltRepo{db: db}
+	br.clicks = newClickCounter(clickFlushInterval, br.flushClicks)
+
+	return br, nil
+}
+
+// Add Добавить URL.
+func (br *BoltRepo) Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error) {
+
+	select {
+	case <-ctx.Done():
+		return ``, ctx.Err()
+	default:
+	}
+
+	hash := urlhasher.GetHash(name)
+
+	err := br.db.Update(func(tx *bolt.Tx) error {
+		//Обработка существующих URL
+		if shortKey := boltUserKey(tx, user.ID, name); shortKey != `` {
+			return &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: shortKey}
+		}
+
+		// Ключ мог остаться за URL, исходный URL которого был изменен, либо быть занят другим пользователем
+		if tx.Bucket(bucketURLs).Get([]byte(hash)) != nil {
+			return ErrShortKeyInUse
+		}
+
+		return boltInsert(tx, hash, &boltRecord{UserID: user.ID, OriginalURL: name, InputURL: inputURL, CreatedAt: time.Now()})
+	})
+
+	if err != nil {
+		return ``, err
+	}
+
+	return hash, nil
+}
+
+// AddBatch Добавить несколько URL.
+// Все URL добавляются в одной транзакции. Уже сокращенные пользователем URL отмечаются в поле Exists.
+func (br *BoltRepo) AddBatch(ctx context.Context, user *models.User, list *[]models.BatchEl) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	now := time.Now()
+
+	// Поля Exists и ShortURL заполняются только при успешной транзакции
+	result := append([]models.BatchEl{}, *list...)
+
+	err := br.db.Update(func(tx *bolt.Tx) error {
+		var keyErr error
+
+		for i := range result {
+			v := &result[i]
+
+			if shortKey := boltUserKey(tx, user.ID, v.OriginalURL); shortKey != `` {
+				v.ShortURL = shortKey
+				v.Exists = true
+				continue
+			}
+
+			// Занятые ключи отмечаются во всем списке, чтобы подобрать другие ключи за одну попытку
+			if tx.Bucket(bucketURLs).Get([]byte(v.ShortURL)) != nil {
+				(*list)[i].KeyInUse = true
+				keyErr = ErrShortKeyInUse
+				continue
+			}
+
+			meta := cloneMeta(v.Meta)
+
+			err := boltInsert(tx, v.ShortURL, &boltRecord{
+				UserID:      user.ID,
+				OriginalURL: v.OriginalURL,
+				InputURL:    v.InputURL,
+				CreatedAt:   now,
+				Title:       meta.Title,
+				Notes:       meta.Notes,
+				Tags:        meta.Tags,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return keyErr
+	})
+
+	if err != nil {
+		return err
+	}
+
+	copy(*list, result)
+
+	return nil
+}
+
+// GetByShortName Получить URL по короткому имени.
+func (br *BoltRepo) GetByShortName(ctx context.Context, user *models.User, name string) (string, bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return ``, false, ctx.Err()
+	default:
+	}
+
+	var originalURL string
+	var isDeleted bool
+
+	err := br.db.View(func(tx *bolt.Tx) error {
+		rec, err := boltGet(tx, name)
+		if err != nil || rec == nil {
+			return err
+		}
+
+		if user != nil && rec.UserID != user.ID {
+			return nil
+		}
+
+		originalURL = rec.OriginalURL
+		isDeleted = tx.Bucket(bucketDeleted).Get([]byte(name)) != nil
+
+		return nil
+	})
+
+	return originalURL, isDeleted, err
+}
+
+// IsReady Готовность репозитория.
+func (br *BoltRepo) IsReady(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	default:
+	}
+
+	// Транзакция чтения не открывается после закрытия файла БД
+	return br.db.View(func(*bolt.Tx) error { return nil }) == nil
+}
+
+// RemoveByOriginalURL удалить URL.
+func (br *BoltRepo) RemoveByOriginalURL(ctx context.Context, user *models.User, url string) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return br.db.Update(func(tx *bolt.Tx) error {
+		shortKey := boltUserKey(tx, user.ID, url)
+		if shortKey == `` {
+			return nil
+		}
+
+		return boltDelete(tx, user.ID, shortKey, url)
+	})
+}
+
+// GetAll получить все URL пользователя.
+// URL читаются в короткой транзакции чтения и отправляются в канал после ее завершения,
+// поэтому медленный читатель не удерживает транзакцию и не мешает росту файла БД при записи.
+func (br *BoltRepo) GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error) {
+	select {
+	case <-ctx.Done():
+		return streamError(ctx.Err())
+	default:
+	}
+
+	var list []models.HistoryEl
+
+	err := br.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket(bucketUsers).Bucket([]byte(user.ID))
+		if index == nil {
+			return nil
+		}
+
+		urls := tx.Bucket(bucketURLs)
+
+		return index.ForEach(func(_, shortKey []byte) error {
+			rec, err := boltDecode(urls.Get(shortKey))
+			if err != nil || rec == nil {
+				return err
+			}
+
+			list = append(list, rec.historyEl(string(shortKey)))
+			return nil
+		})
+	})
+
+	if err != nil {
+		return streamError(err)
+	}
+
+	if len(list) == 0 {
+		return streamError(&models.HistoryNotFoundErr{})
+	}
+
+	return streamHistory(ctx, list)
+}
+
+// RemoveBatch массовое удаление URL. URL отмечаются удаленными и остаются в файле БД.
+func (br *BoltRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return br.db.Update(func(tx *bolt.Tx) error {
+		deleted := tx.Bucket(bucketDeleted)
+
+		for _, shortKey := range list {
+			rec, err := boltGet(tx, shortKey)
+			if err != nil {
+				return err
+			}
+
+			if rec == nil || rec.UserID != user.ID {
+				continue
+			}
+
+			if err = deleted.Put([]byte(shortKey), []byte{}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// MoveUserURLs перенос URL между пользователями.
+func (br *BoltRepo) MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	from := token.From
+	moved := 0
+
+	err := br.db.Update(func(tx *bolt.Tx) error {
+		if err := boltUseTransferToken(tx, token); err != nil {
+			return err
+		}
+
+		users := tx.Bucket(bucketUsers)
+
+		source := users.Bucket([]byte(from.ID))
+		if source == nil || from.ID == to.ID {
+			return nil
+		}
+
+		target, err := users.CreateBucketIfNotExists([]byte(to.ID))
+		if err != nil {
+			return err
+		}
+
+		// Ключи собираются заранее, так как бакет нельзя изменять во время обхода
+		var originals, keys [][]byte
+
+		err = source.ForEach(func(originalURL, shortKey []byte) error {
+			if target.Get(originalURL) != nil {
+				return nil
+			}
+
+			originals = append(originals, bytes.Clone(originalURL))
+			keys = append(keys, bytes.Clone(shortKey))
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for i, shortKey := range keys {
+			rec, err := boltGet(tx, string(shortKey))
+			if err != nil {
+				return err
+			}
+
+			rec.UserID = to.ID
+
+			if err = boltPut(tx, string(shortKey), rec); err != nil {
+				return err
+			}
+
+			if err = source.Delete(originals[i]); err != nil {
+				return err
+			}
+
+			if err = target.Put(originals[i], shortKey); err != nil {
+				return err
+			}
+
+			moved++
+		}
+
+		if boltIsEmpty(source) {
+			return users.DeleteBucket([]byte(from.ID))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, nil
+}
+
+// GetURLInfo получить URL по короткому ключу независимо от владельца.
+func (br *BoltRepo) GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var info *models.URLInfo
+
+	err := br.db.View(func(tx *bolt.Tx) error {
+		rec, err := boltGet(tx, shortKey)
+		if err != nil || rec == nil {
+			return err
+		}
+
+		el := rec.info(tx, shortKey)
+		el.Clicks += br.clicks.pending(shortKey)
+		info = &el
+
+		return nil
+	})
+
+	return info, err
+}
+
+// SetBlocked блокировка URL.
+func (br *BoltRepo) SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	if !blocked {
+		reason = ``
+	}
+
+	return br.change(shortKey, nil, func(rec *boltRecord) {
+		rec.IsBlocked = blocked
+		rec.BlockReason = reason
+	})
+}
+
+// GetUserURLs получить все URL пользователя.
+func (br *BoltRepo) GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	res := make([]models.URLInfo, 0)
+
+	err := br.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket(bucketUsers).Bucket([]byte(user.ID))
+		if index == nil {
+			return nil
+		}
+
+		return index.ForEach(func(_, shortKey []byte) error {
+			rec, err := boltGet(tx, string(shortKey))
+			if err != nil || rec == nil {
+				return err
+			}
+
+			info := rec.info(tx, string(shortKey))
+			info.Clicks += br.clicks.pending(info.ShortKey)
+			res = append(res, info)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PurgeUser удаление всех URL пользователя.
+func (br *BoltRepo) PurgeUser(ctx context.Context, user *models.User) (int, error) {
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	removed := 0
+
+	err := br.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(bucketUsers)
+
+		index := users.Bucket([]byte(user.ID))
+		if index == nil {
+			return nil
+		}
+
+		urls := tx.Bucket(bucketURLs)
+		deleted := tx.Bucket(bucketDeleted)
+
+		err := index.ForEach(func(_, shortKey []byte) error {
+			removed++
+
+			if err := urls.Delete(shortKey); err != nil {
+				return err
+			}
+
+			return deleted.Delete(shortKey)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return users.DeleteBucket([]byte(user.ID))
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// SetRedirectSettings изменение настроек перенаправления URL пользователя.
+func (br *BoltRepo) SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	return br.change(shortKey, user, func(rec *boltRecord) {
+		rec.Redirect = *settings
+	})
+}
+
+// AddClick учет перехода по URL. Переход сохраняется в БД позже вместе с другими переходами.
+func (br *BoltRepo) AddClick(ctx context.Context, shortKey string) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	br.clicks.add(shortKey)
+
+	return nil
+}
+
+// flushClicks сохранение накопленных переходов по URL в одной транзакции. Переходы по удаленным URL не сохраняются.
+func (br *BoltRepo) flushClicks(counts map[string]int64) error {
+	return br.db.Update(func(tx *bolt.Tx) error {
+		for shortKey, count := range counts {
+			rec, err := boltGet(tx, shortKey)
+			if err != nil {
+				return err
+			}
+
+			if rec == nil {
+				continue
+			}
+
+			rec.Clicks += count
+
+			if err = boltPut(tx, shortKey, rec); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UpdateURL изменение исходного URL.
+func (br *BoltRepo) UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	found := false
+
+	err := br.db.Update(func(tx *bolt.Tx) error {
+		rec, err := boltGet(tx, shortKey)
+		if err != nil || rec == nil || rec.UserID != user.ID {
+			return err
+		}
+
+		if hash := boltUserKey(tx, user.ID, name); hash != `` && hash != shortKey {
+			return &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: hash}
+		}
+
+		found = true
+
+		index := tx.Bucket(bucketUsers).Bucket([]byte(user.ID))
+		if err = index.Delete([]byte(rec.OriginalURL)); err != nil {
+			return err
+		}
+
+		if rec.OriginalURL != name {
+			rec.History = append(rec.History, models.DestinationChange{PreviousURL: rec.OriginalURL, NewURL: name, ChangedAt: time.Now()})
+		}
+
+		rec.OriginalURL = name
+		rec.InputURL = inputURL
+
+		if err = index.Put([]byte(name), []byte(shortKey)); err != nil {
+			return err
+		}
+
+		return boltPut(tx, shortKey, rec)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// GetURLHistory получение истории изменений исходного URL.
+func (br *BoltRepo) GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var history []models.DestinationChange
+
+	err := br.db.View(func(tx *bolt.Tx) error {
+		rec, err := boltGet(tx, shortKey)
+		if err != nil || rec == nil || rec.UserID != user.ID {
+			return err
+		}
+
+		history = rec.History
+
+		return nil
+	})
+
+	return history, err
+}
+
+// SetURLMeta изменение метаданных URL.
+func (br *BoltRepo) SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	return br.change(shortKey, user, func(rec *boltRecord) {
+		rec.Title = meta.Title
+		rec.Notes = meta.Notes
+		rec.Tags = cloneMeta(*meta).Tags
+	})
+}
+
+// Close завершение работы с репозиторием
+func (br *BoltRepo) Close() {
+	// Накопленные переходы сохраняются до закрытия БД
+	br.clicks.close()
+
+	err := br.db.Close()
+	if err != nil {
+		logger.Error(`error while closing bolt database`, err)
+	}
+}
+
+// change изменение URL по короткому ключу в отдельной транзакции. Если user не nil, то изменяется только URL пользователя.
+// Возвращает false, если URL не найден.
+func (br *BoltRepo) change(shortKey string, user *models.User, apply func(rec *boltRecord)) (bool, error) {
+	found := false
+
+	err := br.db.Update(func(tx *bolt.Tx) error {
+		rec, err := boltGet(tx, shortKey)
+		if err != nil || rec == nil {
+			return err
+		}
+
+		if user != nil && rec.UserID != user.ID {
+			return nil
+		}
+
+		found = true
+		apply(rec)
+
+		return boltPut(tx, shortKey, rec)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// info полная информация об URL.
+func (r *boltRecord) info(tx *bolt.Tx, shortKey string) models.URLInfo {
+	return models.URLInfo{
+		ShortKey:    shortKey,
+		OriginalURL: r.OriginalURL,
+		InputURL:    r.InputURL,
+		UserID:      r.UserID,
+		IsDeleted:   tx.Bucket(bucketDeleted).Get([]byte(shortKey)) != nil,
+		IsBlocked:   r.IsBlocked,
+		BlockReason: r.BlockReason,
+		Redirect:    r.Redirect,
+		CreatedAt:   r.CreatedAt,
+		Clicks:      r.Clicks,
+		URLMeta:     models.URLMeta{Title: r.Title, Notes: r.Notes, Tags: r.Tags},
+	}
+}
+
+// historyEl элемент списка URL пользователя.
+func (r *boltRecord) historyEl(shortKey string) models.HistoryEl {
+	return models.HistoryEl{
+		OriginalURL: r.OriginalURL,
+		ShortURL:    shortKey,
+		InputURL:    r.InputURL,
+		URLMeta:     models.URLMeta{Title: r.Title, Notes: r.Notes, Tags: r.Tags},
+	}
+}
+
+// boltUserKey короткий ключ исходного URL name пользователя userID, пустая строка, если URL не найден.
+func boltUserKey(tx *bolt.Tx, userID string, name string) string {
+	index := tx.Bucket(bucketUsers).Bucket([]byte(userID))
+	if index == nil {
+		return ``
+	}
+
+	return string(index.Get([]byte(name)))
+}
+
+// boltInsert добавление нового URL и его исходного URL в индекс пользователя.
+func boltInsert(tx *bolt.Tx, shortKey string, rec *boltRecord) error {
+	index, err := tx.Bucket(bucketUsers).CreateBucketIfNotExists([]byte(rec.UserID))
+	if err != nil {
+		return err
+	}
+
+	if err = index.Put([]byte(rec.OriginalURL), []byte(shortKey)); err != nil {
+		return err
+	}
+
+	return boltPut(tx, shortKey, rec)
+}
+
+// boltDelete удаление URL вместе с записью в индексе пользователя и отметкой удаления.
+func boltDelete(tx *bolt.Tx, userID string, shortKey string, originalURL string) error {
+	if index := tx.Bucket(bucketUsers).Bucket([]byte(userID)); index != nil {
+		if err := index.Delete([]byte(originalURL)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Bucket(bucketDeleted).Delete([]byte(shortKey)); err != nil {
+		return err
+	}
+
+	return tx.Bucket(bucketURLs).Delete([]byte(shortKey))
+}
+
+// boltIsEmpty проверка отсутствия ключей в бакете.
+func boltIsEmpty(bucket *bolt.Bucket) bool {
+	key, _ := bucket.Cursor().First()
+	return key == nil
+}
+
+// boltGet получение URL по короткому ключу, nil, если URL не найден.
+func boltGet(tx *bolt.Tx, shortKey string) (*boltRecord, error) {
+	return boltDecode(tx.Bucket(bucketURLs).Get([]byte(shortKey)))
+}
+
+// boltPut сохранение URL по короткому ключу.
+func boltPut(tx *bolt.Tx, shortKey string, rec *boltRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(bucketURLs).Put([]byte(shortKey), data)
+}
+
+// boltDecode разбор URL из значения бакета, nil для пустого значения.
+// Данные бакета действительны только внутри транзакции, поэтому разбираются в новую структуру.
+func boltDecode(data []byte) (*boltRecord, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	rec := &boltRecord{}
+	if err := json.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// boltUseTransferToken отметка токена переноса token использованным в транзакции tx.
+// Возвращает ErrTransferTokenUsed, если токен уже использован. Идентификаторы истекших токенов удаляются.
+func boltUseTransferToken(tx *bolt.Tx, token *models.TransferToken) error {
+	transfers := tx.Bucket(bucketTransfers)
+	if transfers.Get([]byte(token.ID)) != nil {
+		return ErrTransferTokenUsed
+	}
+
+	// Ключи собираются заранее, так как бакет нельзя изменять во время обхода
+	var expired [][]byte
+
+	now := time.Now()
+	err := transfers.ForEach(func(id, value []byte) error {
+		expiresAt, err := time.Parse(time.RFC3339, string(value))
+		if err != nil || !now.Before(expiresAt) {
+			expired = append(expired, bytes.Clone(id))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, id := range expired {
+		if err = transfers.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	return transfers.Put([]byte(token.ID), []byte(token.ExpiresAt.UTC().Format(time.RFC3339)))
+}
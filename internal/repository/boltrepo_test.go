@@ -0,0 +1,402 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/urlhasher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initBoltTest запуск встроенного репозитория в файле path.
+func initBoltTest(t *testing.T, ctx context.Context, path string) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.StorageMode = config.StorageModeBolt
+	cfg.BoltStoragePath = path
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+}
+
+func TestBoltRunInBoltMode(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	initBoltTest(t, ctx, filepath.Join(t.TempDir(), `short-url.db`))
+	defer GetRepository().Close()
+
+	assert.Equal(t, `BoltRepo`, reflect.TypeOf(GetRepository()).Elem().Name())
+	assert.True(t, GetRepository().IsReady(ctx))
+}
+
+func TestBoltAddURLAndGetURLSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), `short-url.db`)
+	initBoltTest(t, ctx, path)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, `HTTPS://practicum.yandex.ru`)
+	require.NoError(t, err)
+	assert.Equal(t, urlhasher.GetHash(targetURL), hash)
+
+	_, err = GetRepository().Add(ctx, user, targetURL, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, hash, uniqErr.ShortKey)
+
+	// Ключ занят URL другого пользователя
+	_, err = GetRepository().Add(ctx, &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}, targetURL, ``)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+
+	// Проверка после повторного открытия файла
+	GetRepository().Close()
+	initBoltTest(t, ctx, path)
+	defer GetRepository().Close()
+
+	res, isDeleted, err := GetRepository().GetByShortName(ctx, user, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL, res)
+	assert.False(t, isDeleted)
+
+	res, _, err = GetRepository().GetByShortName(ctx, nil, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL, res)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, `HTTPS://practicum.yandex.ru`, info.InputURL)
+	assert.Equal(t, user.ID, info.UserID)
+	assert.WithinDuration(t, time.Now(), info.CreatedAt, time.Minute)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, `any_url`)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestBoltAddBatchSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	initBoltTest(t, ctx, filepath.Join(t.TempDir(), `short-url.db`))
+	defer GetRepository().Close()
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	urlList := []models.BatchEl{
+		{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: `hash1`},
+		{CorrelationID: `2`, OriginalURL: targetURL + `2`, ShortURL: `hash2`, Meta: models.URLMeta{Title: `title`, Tags: []string{`a`}}},
+		{CorrelationID: `3`, OriginalURL: targetURL + `2`, ShortURL: `hash3`},
+	}
+
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.BatchEl{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: hash, Exists: true}, urlList[0])
+	assert.False(t, urlList[1].Exists)
+	assert.Equal(t, models.BatchEl{CorrelationID: `3`, OriginalURL: targetURL + `2`, ShortURL: `hash2`, Exists: true}, urlList[2])
+
+	info, err := GetRepository().GetURLInfo(ctx, `hash2`)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, models.URLMeta{Title: `title`, Tags: []string{`a`}}, info.URLMeta)
+
+	// Список не добавляется целиком, если ключ одного из URL занят
+	urlList = []models.BatchEl{
+		{CorrelationID: `1`, OriginalURL: targetURL + `4`, ShortURL: `hash4`},
+		{CorrelationID: `2`, OriginalURL: targetURL + `5`, ShortURL: `hash2`},
+	}
+
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+
+	info, err = GetRepository().GetURLInfo(ctx, `hash4`)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func TestBoltGetAllSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	initBoltTest(t, ctx, filepath.Join(t.TempDir(), `short-url.db`))
+	defer GetRepository().Close()
+
+	chList, chErr := GetRepository().GetAll(ctx, user)
+	for range chList {
+		t.Fatal(`unexpected url`)
+	}
+
+	var notFoundErr *models.HistoryNotFoundErr
+	assert.ErrorAs(t, <-chErr, &notFoundErr)
+
+	urlList := map[string]string{}
+	for _, name := range []string{targetURL + `1`, targetURL + `2`, targetURL + `3`} {
+		hash, err := GetRepository().Add(ctx, user, name, ``)
+		require.NoError(t, err)
+
+		urlList[hash] = name
+	}
+
+	chList, chErr = GetRepository().GetAll(ctx, user)
+
+	received := map[string]string{}
+	for el := range chList {
+		received[el.ShortURL] = el.OriginalURL
+	}
+
+	for err := range chErr {
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, urlList, received)
+
+	// Чтение прекращается при отмене контекста
+	listCtx, listCancel := context.WithCancel(ctx)
+
+	chList, chErr = GetRepository().GetAll(listCtx, user)
+	<-chList
+	listCancel()
+
+	for range chList {
+	}
+
+	assert.ErrorIs(t, <-chErr, context.Canceled)
+
+	// Непрочитанный канал не удерживает транзакцию чтения
+	chList, _ = GetRepository().GetAll(ctx, user)
+	assert.Zero(t, GetRepository().(*BoltRepo).db.Stats().OpenTxN)
+
+	for range chList {
+	}
+}
+
+func TestBoltRemoveSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	initBoltTest(t, ctx, filepath.Join(t.TempDir(), `short-url.db`))
+	defer GetRepository().Close()
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash1, err := GetRepository().Add(ctx, user, targetURL+`1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	err = GetRepository().RemoveBatch(ctx, user2, []string{hash1})
+	require.NoError(t, err)
+
+	_, isDeleted, err := GetRepository().GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.False(t, isDeleted)
+
+	err = GetRepository().RemoveBatch(ctx, user, []string{hash1, `any_url`})
+	require.NoError(t, err)
+
+	res, isDeleted, err := GetRepository().GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+	assert.True(t, isDeleted)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash1)
+	require.NoError(t, err)
+	assert.True(t, info.IsDeleted)
+
+	err = GetRepository().RemoveByOriginalURL(ctx, user, targetURL+`2`)
+	require.NoError(t, err)
+
+	info, err = GetRepository().GetURLInfo(ctx, hash2)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	// Исходный URL после удаления снова можно добавить
+	_, err = GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+}
+
+func TestBoltMoveUserURLsSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	initBoltTest(t, ctx, filepath.Join(t.TempDir(), `short-url.db`))
+	defer GetRepository().Close()
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash1, err := GetRepository().Add(ctx, user, targetURL+`1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	// У пользователя user2 исходный URL уже есть под ключом, оставшимся после изменения исходного URL
+	hash3, err := GetRepository().Add(ctx, user2, targetURL+`3`, ``)
+	require.NoError(t, err)
+
+	_, err = GetRepository().UpdateURL(ctx, user2, hash3, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	token := newTransferToken(user)
+
+	moved, err := GetRepository().MoveUserURLs(ctx, token, user2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	res, _, err := GetRepository().GetByShortName(ctx, user2, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, hash2)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`2`, res)
+
+	list, err := GetRepository().GetUserURLs(ctx, user2)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
+func TestBoltAdminSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	initBoltTest(t, ctx, filepath.Join(t.TempDir(), `short-url.db`))
+	defer GetRepository().Close()
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	found, err := GetRepository().SetBlocked(ctx, `any_url`, true, `spam`)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = GetRepository().SetBlocked(ctx, hash, true, `spam`)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.True(t, info.IsBlocked)
+	assert.Equal(t, `spam`, info.BlockReason)
+
+	found, err = GetRepository().SetBlocked(ctx, hash, false, `spam`)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	list, err := GetRepository().GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.False(t, list[0].IsBlocked)
+	assert.Empty(t, list[0].BlockReason)
+
+	removed, err := GetRepository().PurgeUser(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	info, err = GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	list, err = GetRepository().GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestBoltChangeURLSuccess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), `short-url.db`)
+	initBoltTest(t, ctx, path)
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	otherHash, err := GetRepository().Add(ctx, user, targetURL+`/other`, ``)
+	require.NoError(t, err)
+
+	settings := &models.RedirectSettings{StatusCode: 301, UTMParams: map[string]string{`utm_source`: `test`}}
+
+	found, err := GetRepository().SetRedirectSettings(ctx, user2, hash, settings)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = GetRepository().SetRedirectSettings(ctx, user, hash, settings)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	err = GetRepository().AddClick(ctx, hash)
+	require.NoError(t, err)
+
+	// Несохраненный переход учитывается сразу
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), info.Clicks)
+
+	found, err = GetRepository().SetURLMeta(ctx, user, hash, &models.URLMeta{Title: `title`, Tags: []string{`a`}})
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	_, err = GetRepository().UpdateURL(ctx, user, hash, targetURL+`/other`, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, otherHash, uniqErr.ShortKey)
+
+	found, err = GetRepository().UpdateURL(ctx, user2, hash, targetURL+`/new`, ``)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = GetRepository().UpdateURL(ctx, user, hash, targetURL+`/new`, ``)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// Проверка после повторного открытия файла
+	GetRepository().Close()
+	initBoltTest(t, ctx, path)
+	defer GetRepository().Close()
+
+	info, err = GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`/new`, info.OriginalURL)
+	assert.Equal(t, *settings, info.Redirect)
+	assert.Equal(t, int64(1), info.Clicks)
+	assert.Equal(t, models.URLMeta{Title: `title`, Tags: []string{`a`}}, info.URLMeta)
+
+	history, err := GetRepository().GetURLHistory(ctx, user, hash)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, targetURL, history[0].PreviousURL)
+	assert.Equal(t, targetURL+`/new`, history[0].NewURL)
+
+	history, err = GetRepository().GetURLHistory(ctx, user2, hash)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// Исходный URL после изменения освобождается, но ключ остается занят
+	_, err = GetRepository().Add(ctx, user, targetURL+`/new`, ``)
+	require.ErrorAs(t, err, &uniqErr)
+
+	_, err = GetRepository().Add(ctx, user, targetURL, ``)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+}
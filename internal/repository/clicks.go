@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+)
+
+// clickFlushInterval - период сохранения накопленных переходов по URL.
+const clickFlushInterval = 5 * time.Second
+
+// clickCounter - переходы по URL, накопленные в памяти до сохранения в хранилище.
+// Переходы сохраняются периодически одной операцией, чтобы запись не выполнялась при каждом перенаправлении.
+type clickCounter struct {
+	mu sync.Mutex
+	// counts - количество несохраненных переходов: короткий ключ - количество.
+	counts map[string]int64
+	// flush - сохранение переходов в хранилище.
+	flush func(counts map[string]int64) error
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// newClickCounter создание счетчика и запуск периодического сохранения переходов функцией flush.
+func newClickCounter(interval time.Duration, flush func(counts map[string]int64) error) *clickCounter {
+	c := &clickCounter{
+		counts: make(map[string]int64),
+		flush:  flush,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(c.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.save()
+			}
+		}
+	}()
+
+	return c
+}
+
+// add учет перехода по URL с коротким ключом shortKey.
+func (c *clickCounter) add(shortKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts[shortKey]++
+}
+
+// pending количество несохраненных переходов по URL с коротким ключом shortKey.
+// Переходы, которые сохраняются в данный момент, не учитываются.
+func (c *clickCounter) pending(shortKey string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counts[shortKey]
+}
+
+// save сохранение накопленных переходов. При ошибке переходы возвращаются в счетчик до следующего сохранения.
+func (c *clickCounter) save() {
+	c.mu.Lock()
+	counts := c.counts
+	c.counts = make(map[string]int64)
+	c.mu.Unlock()
+
+	if len(counts) == 0 {
+		return
+	}
+
+	if err := c.flush(counts); err != nil {
+		logger.Error(`clicks flush error`, err)
+
+		c.mu.Lock()
+		for shortKey, count := range counts {
+			c.counts[shortKey] += count
+		}
+		c.mu.Unlock()
+	}
+}
+
+// close остановка периодического сохранения и сохранение оставшихся переходов.
+func (c *clickCounter) close() {
+	select {
+	case <-c.stop:
+		return
+	default:
+	}
+
+	close(c.stop)
+	<-c.done
+
+	c.save()
+}
@@ -0,0 +1,81 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/repository"
+	"github.com/Alheor/shorturl/internal/repository/repotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabaseDsnEnv - переменная окружения с DSN локальной БД PostgreSQL для набора тестов совместимости.
+const testDatabaseDsnEnv = `TEST_DATABASE_DSN`
+
+// newConformanceRepo запуск репозитория с параметрами options для теста набора repotest.
+func newConformanceRepo(t *testing.T, options config.Options) repository.IRepository {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = repository.Init(ctx, &options, nil)
+	require.NoError(t, err)
+
+	return repository.GetRepository()
+}
+
+func TestMemoryConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IRepository {
+		return newConformanceRepo(t, config.Options{StorageMode: config.StorageModeMemory})
+	})
+}
+
+func TestFileConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IRepository {
+		return newConformanceRepo(t, config.Options{
+			StorageMode:     config.StorageModeFile,
+			FileStoragePath: filepath.Join(t.TempDir(), `short-url-db.json`),
+		})
+	})
+}
+
+func TestBoltConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IRepository {
+		return newConformanceRepo(t, config.Options{
+			StorageMode:     config.StorageModeBolt,
+			BoltStoragePath: filepath.Join(t.TempDir(), `short-url.db`),
+		})
+	})
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.IRepository {
+		return newConformanceRepo(t, config.Options{
+			DatabaseDsn: repository.SQLiteDSNPrefix + filepath.Join(t.TempDir(), `short-url.sqlite`),
+		})
+	})
+}
+
+func TestDBConformance(t *testing.T) {
+	dsn := os.Getenv(testDatabaseDsnEnv)
+	if dsn == `` {
+		t.Skip(testDatabaseDsnEnv + ` is not set`) // Для запуска с локальной БД
+	}
+
+	repotest.Run(t, func(t *testing.T) repository.IRepository {
+		repo := newConformanceRepo(t, config.Options{StorageMode: config.StorageModeDatabase, DatabaseDsn: dsn})
+
+		_, err := repository.Connection.Exec(context.Background(), `TRUNCATE short_url, short_url_history, short_url_tag`)
+		require.NoError(t, err)
+
+		return repo
+	})
+}
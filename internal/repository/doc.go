@@ -2,12 +2,32 @@
 //
 // # Описание
 //
-// В зависимости от конфигурации, репозиторий может работать либо с БД PostgreSQL, либо хранить данные в файле или памяти.
+// В зависимости от конфигурации, репозиторий может работать либо с БД PostgreSQL, либо с БД SQLite,
+// либо со встроенной БД bbolt, либо хранить данные в файле или памяти.
 // Согласно загрузившейся конфигурации, создается необходимый экземпляр репозитория. Все экземпляры имплементируют интерфейс IRepository,
 // что позволяет работать с любым из них независимо.
 //
 // При использовании базы банных, схема будет создана автоматически, если в указанной БД ее нет.
 // Для начала работы достаточно пустой БД, все остальное сервис сделает сам.
 //
-// BUG(repository): Обратите внимание, что методы RemoveBatch и RemoveByOriginalURL поддерживаются только при работе с БД.
+// Файловый репозиторий и репозиторий в памяти хранят обратный индекс исходных URL пользователя, поэтому уже сокращенный URL
+// находится без перебора всех URL пользователя. При массовом добавлении такие URL, в том числе повторяющиеся в одном запросе,
+// не добавляются повторно: в элементе возвращается ключ существующего URL и признак Exists. Репозиторий БД добавляет
+// такие списки через временную таблицу и INSERT ... ON CONFLICT DO NOTHING с тем же результатом.
+//
+// Встроенная БД хранит URL в бакете urls по короткому ключу, индексы исходных URL пользователей во вложенных бакетах
+// бакета users, а отметки удаления в бакете deleted. Каждая операция выполняется в отдельной транзакции.
+//
+// Идентификаторы использованных токенов переноса URL сохраняются в хранилище в той же транзакции, что и перенос
+// (в таблице transfer_token, бакете transfers или операции move файла), поэтому токен нельзя использовать повторно
+// после перезапуска сервиса или на другом экземпляре с тем же хранилищем. Идентификаторы истекших токенов удаляются.
+//
+// Файловый репозиторий и встроенная БД не записывают переход по URL при каждом перенаправлении: переходы накапливаются
+// в памяти и сохраняются раз в несколько секунд одной операцией, а также при закрытии репозитория.
+//
+// БД SQLite выбирается параметром DatabaseDsn вида sqlite://путь к файлу и работает через драйвер без cgo.
+// Схема совпадает со схемой PostgreSQL, журнал ведется в режиме WAL, массовое добавление выполняется в одной транзакции.
+//
+// Общее для всех типов хранилищ поведение проверяется набором тестов совместимости пакета repotest.
+// Набор запускается для каждого типа хранилища, для PostgreSQL - при заданной переменной окружения TEST_DATABASE_DSN.
 package repository
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
@@ -17,13 +18,23 @@ var _ IRepository = (*FileRepo)(nil)
 
 // FileRepo - структура файлового репозитория.
 type FileRepo struct {
-	list map[string]map[string]string
-	file *os.File
+	list map[string]map[string]*record
+	// originals - обратный индекс: пользователь - исходный URL - короткий ключ.
+	originals map[string]map[string]string
+	// owners - индекс владельцев: короткий ключ - пользователь. Короткие ключи уникальны среди всех пользователей.
+	owners map[string]string
+	// blocked - заблокированные URL: короткий ключ - причина блокировки.
+	blocked map[string]string
+	// transfers - использованные токены переноса: идентификатор токена - время истечения.
+	transfers map[string]time.Time
+	// clicks - переходы по URL, которые еще не записаны в файл.
+	clicks *clickCounter
+	file   *os.File
 	sync.RWMutex
 }
 
 // Add Добавить URL.
-func (fr *FileRepo) Add(ctx context.Context, user *models.User, name string) (string, error) {
+func (fr *FileRepo) Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error) {
 
 	select {
 	case <-ctx.Done():
@@ -34,37 +45,27 @@ func (fr *FileRepo) Add(ctx context.Context, user *models.User, name string) (st
 	fr.Lock()
 	defer fr.Unlock()
 
-	if fr.list[user.ID] == nil {
-		fr.list[user.ID] = make(map[string]string)
-	}
-
-	urls := fr.list[user.ID]
-
 	//Обработка существующих URL
-	for hash, el := range urls {
-		if el == name {
-
-			return ``, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: hash}
-		}
+	if hash, exists := fr.originals[user.ID][name]; exists {
+		return ``, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: hash}
 	}
 
 	hash := urlhasher.GetHash(name)
-	urls[hash] = name
 
-	data, err := json.Marshal(&URL{UserID: user.ID, ID: hash, URL: name})
-	if err != nil {
-		logger.Error(`marshal error`, err)
-		return ``, err
+	// Ключ мог остаться за URL, исходный URL которого был изменен, либо принадлежать URL другого пользователя
+	if _, exists := fr.owners[hash]; exists {
+		return ``, ErrShortKeyInUse
 	}
 
-	data = append(data, '\n')
+	el := &record{originalURL: name, inputURL: inputURL, createdAt: time.Now()}
 
-	_, err = fr.file.Write(data)
+	err := fr.write(&URL{UserID: user.ID, ID: hash, URL: name, InputURL: inputURL, CreatedAt: el.createdAt})
 	if err != nil {
-		logger.Error(`file write error`, err)
 		return ``, err
 	}
 
+	addRecord(fr.list, fr.originals, fr.owners, user.ID, hash, el)
+
 	return hash, nil
 }
 
@@ -80,30 +81,61 @@ func (fr *FileRepo) AddBatch(ctx context.Context, user *models.User, list *[]mod
 	fr.Lock()
 	defer fr.Unlock()
 
-	if fr.list[user.ID] == nil {
-		fr.list[user.ID] = make(map[string]string)
+	if err := checkBatch(fr.owners, fr.originals[user.ID], *list); err != nil {
+		return err
 	}
 
-	urls := fr.list[user.ID]
-
 	var data []byte
-	var err error
+	written := make(map[string]struct{}, len(*list))
 
-	for _, v := range *list {
-		v.ShortURL = urlhasher.GetHash(v.OriginalURL)
+	now := time.Now()
+
+	for i := range *list {
+		v := &(*list)[i]
+
+		// Исходный URL мог встретиться раньше в этом же списке
+		if _, exists := fr.originals[user.ID][v.OriginalURL]; exists {
+			continue
+		}
+
+		if _, exists := written[v.OriginalURL]; exists {
+			continue
+		}
+
+		el, err := json.Marshal(&URL{
+			UserID:    user.ID,
+			ID:        v.ShortURL,
+			URL:       v.OriginalURL,
+			InputURL:  v.InputURL,
+			CreatedAt: now,
+			Title:     v.Meta.Title,
+			Notes:     v.Meta.Notes,
+			Tags:      v.Meta.Tags,
+		})
 
-		el, err := json.Marshal(&URL{UserID: user.ID, ID: v.ShortURL, URL: v.OriginalURL})
 		if err != nil {
 			return err
 		}
 
-		urls[v.ShortURL] = v.OriginalURL
+		written[v.OriginalURL] = struct{}{}
 		data = append(data, append(el, '\n')...)
 	}
 
-	_, err = fr.file.Write(data)
-	if err != nil {
-		return err
+	// URL добавляются в список только после записи в файл
+	if len(data) > 0 {
+		if _, err := fr.file.Write(data); err != nil {
+			return err
+		}
+	}
+
+	for i := range *list {
+		v := &(*list)[i]
+
+		if markExisting(fr.originals[user.ID], v) {
+			continue
+		}
+
+		addRecord(fr.list, fr.originals, fr.owners, user.ID, v.ShortURL, &record{originalURL: v.OriginalURL, inputURL: v.InputURL, createdAt: now, meta: cloneMeta(v.Meta)})
 	}
 
 	return nil
@@ -121,31 +153,18 @@ func (fr *FileRepo) GetByShortName(ctx context.Context, user *models.User, name
 	fr.RLock()
 	defer fr.RUnlock()
 
-	//Костыль для прохождения тестов
+	var el *record
 	if user == nil {
-		for _, el := range fr.list {
-			//Жесть, но тесты нужно пройти
-			for short, original := range el {
-				if short == name {
-					return original, false, nil
-				}
-			}
-		}
-
-		return ``, false, nil
+		_, el = findRecord(fr.list, fr.owners, name)
+	} else {
+		el = fr.list[user.ID][name]
 	}
 
-	urls, exists := fr.list[user.ID]
-	if !exists {
+	if el == nil {
 		return ``, false, nil
 	}
 
-	el, exists := urls[name]
-	if !exists {
-		return ``, false, nil
-	}
-
-	return el, false, nil
+	return el.originalURL, el.deleted, nil
 }
 
 // IsReady Готовность репозитория.
@@ -156,62 +175,360 @@ func (fr *FileRepo) IsReady(ctx context.Context) bool {
 	default:
 	}
 
+	fr.RLock()
+	defer fr.RUnlock()
+
 	return fr.file != nil
 }
 
 // RemoveByOriginalURL удалить URL.
-// Deprecated: не поддерживается эти типом репозитория.
 func (fr *FileRepo) RemoveByOriginalURL(ctx context.Context, user *models.User, url string) error {
-	return errors.New(`method "Remove" from file repository not supported`)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if _, exists := fr.originals[user.ID][url]; !exists {
+		return nil
+	}
+
+	err := fr.write(&URL{Op: opRemove, UserID: user.ID, URL: url})
+	if err != nil {
+		return err
+	}
+
+	removeRecord(fr.list, fr.originals, fr.owners, fr.blocked, user.ID, url)
+
+	return nil
 }
 
 // GetAll получить все URL пользователя.
+// URL копируются под блокировкой и отправляются в канал после ее снятия.
 func (fr *FileRepo) GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error) {
-	out := make(chan models.HistoryEl)
-	errCh := make(chan error, 1)
 
-	defer close(errCh)
+	select {
+	case <-ctx.Done():
+		return streamError(ctx.Err())
+	default:
+	}
+
+	fr.RLock()
+	list := historyList(fr.list[user.ID])
+	fr.RUnlock()
+
+	if len(list) == 0 {
+		return streamError(&models.HistoryNotFoundErr{})
+	}
+
+	return streamHistory(ctx, list)
+}
+
+// RemoveBatch массовое удаление URL.
+func (fr *FileRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	// В файл записываются только ключи URL пользователя
+	keys := userKeys(fr.list[user.ID], list)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := fr.write(&URL{Op: opDelete, UserID: user.ID, IDs: keys})
+	if err != nil {
+		return err
+	}
+
+	markDeleted(fr.list[user.ID], keys)
+
+	return nil
+}
+
+// MoveUserURLs перенос URL между пользователями.
+func (fr *FileRepo) MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if _, used := fr.transfers[token.ID]; used {
+		return 0, ErrTransferTokenUsed
+	}
+
+	// Сначала операция сохраняется в файл, при загрузке она будет применена повторно.
+	// Операция записывается и без URL для переноса, чтобы токен остался использованным после перезапуска
+	err := fr.write(&URL{Op: opMove, ID: token.ID, ExpiresAt: token.ExpiresAt, UserID: token.From.ID, TargetUserID: to.ID})
+	if err != nil {
+		return 0, err
+	}
+
+	useTransferToken(fr.transfers, token)
+
+	return moveUserURLs(fr.list, fr.originals, fr.owners, token.From.ID, to.ID), nil
+}
+
+// GetURLInfo получить URL по короткому ключу независимо от владельца.
+func (fr *FileRepo) GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return findURLInfo(fr.list, fr.owners, fr.blocked, shortKey), nil
+}
+
+// SetBlocked блокировка URL.
+func (fr *FileRepo) SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if findURLInfo(fr.list, fr.owners, fr.blocked, shortKey) == nil {
+		return false, nil
+	}
+
+	el := &URL{Op: opUnblock, ID: shortKey}
+	if blocked {
+		el = &URL{Op: opBlock, ID: shortKey, Reason: reason}
+	}
+
+	err := fr.write(el)
+	if err != nil {
+		return false, err
+	}
+
+	if fr.blocked == nil {
+		fr.blocked = make(map[string]string)
+	}
+
+	setBlocked(fr.blocked, shortKey, blocked, reason)
+
+	return true, nil
+}
+
+// GetUserURLs получить все URL пользователя.
+func (fr *FileRepo) GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error) {
 
 	select {
 	case <-ctx.Done():
-		close(out)
-		errCh <- ctx.Err()
+		return nil, ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return userURLInfo(fr.list, fr.blocked, user.ID), nil
+}
+
+// PurgeUser удаление всех URL пользователя.
+func (fr *FileRepo) PurgeUser(ctx context.Context, user *models.User) (int, error) {
 
-		return out, errCh
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
 	default:
 	}
 
-	list, exists := fr.list[user.ID]
+	fr.Lock()
+	defer fr.Unlock()
+
+	removed := len(fr.list[user.ID])
+	if removed == 0 {
+		return 0, nil
+	}
+
+	err := fr.write(&URL{Op: opPurge, UserID: user.ID})
+	if err != nil {
+		return 0, err
+	}
+
+	deleteUser(fr.list, fr.originals, fr.owners, fr.blocked, user.ID)
+
+	return removed, nil
+}
+
+// SetRedirectSettings изменение настроек перенаправления URL пользователя.
+func (fr *FileRepo) SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	el, exists := fr.list[user.ID][shortKey]
 	if !exists {
-		close(out)
-		errCh <- &models.HistoryNotFoundErr{}
+		return false, nil
+	}
 
-		return out, errCh
+	err := fr.write(&URL{Op: opRedirect, UserID: user.ID, ID: shortKey, Redirect: settings})
+	if err != nil {
+		return false, err
 	}
 
-	go func() {
-		defer close(out)
+	el.redirect = *settings
 
-		for shortURL, originalURL := range list {
-			out <- models.HistoryEl{OriginalURL: originalURL, ShortURL: shortURL}
-		}
-	}()
+	return true, nil
+}
+
+// AddClick учет перехода по URL. Переход записывается в файл позже вместе с другими переходами.
+func (fr *FileRepo) AddClick(ctx context.Context, shortKey string) error {
 
-	return out, errCh
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	_, el := findRecord(fr.list, fr.owners, shortKey)
+	if el == nil {
+		return nil
+	}
+
+	el.clicks.Add(1)
+	fr.clicks.add(shortKey)
+
+	return nil
 }
 
-// RemoveBatch массовое удаление URL.
-// Deprecated: не поддерживается эти типом репозитория.
-func (fr *FileRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
-	return errors.New(`method "RemoveBatch" from file repository not supported`)
+// flushClicks запись накопленных переходов по URL в файл одной операцией.
+func (fr *FileRepo) flushClicks(counts map[string]int64) error {
+	fr.Lock()
+	defer fr.Unlock()
+
+	if fr.file == nil {
+		return errors.New(`file repository is closed`)
+	}
+
+	return fr.write(&URL{Op: opClick, Clicks: counts})
+}
+
+// UpdateURL изменение исходного URL.
+func (fr *FileRepo) UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	el, err := findUpdated(fr.list[user.ID], fr.originals[user.ID], shortKey, name)
+	if err != nil || el == nil {
+		return false, err
+	}
+
+	changedAt := time.Now()
+
+	err = fr.write(&URL{Op: opUpdate, UserID: user.ID, ID: shortKey, URL: name, InputURL: inputURL, CreatedAt: changedAt})
+	if err != nil {
+		return false, err
+	}
+
+	updateRecord(fr.originals[user.ID], shortKey, el, name, inputURL, changedAt)
+
+	return true, nil
+}
+
+// GetURLHistory получение истории изменений исходного URL.
+func (fr *FileRepo) GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return historyOf(fr.list[user.ID], shortKey), nil
+}
+
+// SetURLMeta изменение метаданных URL.
+func (fr *FileRepo) SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	el, exists := fr.list[user.ID][shortKey]
+	if !exists {
+		return false, nil
+	}
+
+	err := fr.write(&URL{Op: opMeta, UserID: user.ID, ID: shortKey, Title: meta.Title, Notes: meta.Notes, Tags: meta.Tags})
+	if err != nil {
+		return false, err
+	}
+
+	el.meta = cloneMeta(*meta)
+
+	return true, nil
 }
 
 // Close завершение работы с репозиторием
 func (fr *FileRepo) Close() {
+	// Накопленные переходы записываются до закрытия файла
+	if fr.clicks != nil {
+		fr.clicks.close()
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if fr.file == nil {
+		return
+	}
+
 	err := fr.file.Close()
 	if err != nil {
 		logger.Error(`error while closing file`, err)
 	}
+
+	fr.file = nil
 }
 
 // Load - загрузка данных из файла.
@@ -244,11 +561,75 @@ func (fr *FileRepo) load(ctx context.Context, path string) error {
 			continue
 		}
 
-		if fr.list[el.UserID] == nil {
-			fr.list[el.UserID] = make(map[string]string)
+		switch el.Op {
+		case opMove:
+			// В записях прежнего формата идентификатора токена нет
+			if el.ID != `` {
+				useTransferToken(fr.transfers, &models.TransferToken{ID: el.ID, ExpiresAt: el.ExpiresAt})
+			}
+
+			moveUserURLs(fr.list, fr.originals, fr.owners, el.UserID, el.TargetUserID)
+
+		case opBlock, opUnblock:
+			setBlocked(fr.blocked, el.ID, el.Op == opBlock, el.Reason)
+
+		case opPurge:
+			deleteUser(fr.list, fr.originals, fr.owners, fr.blocked, el.UserID)
+
+		case opClick:
+			if el.Clicks == nil {
+				el.Clicks = map[string]int64{el.ID: 1}
+			}
+
+			for shortKey, count := range el.Clicks {
+				if _, rec := findRecord(fr.list, fr.owners, shortKey); rec != nil {
+					rec.clicks.Add(count)
+				}
+			}
+
+		case opUpdate:
+			if rec, exists := fr.list[el.UserID][el.ID]; exists {
+				updateRecord(fr.originals[el.UserID], el.ID, rec, el.URL, el.InputURL, el.CreatedAt)
+			}
+
+		case opMeta:
+			if rec, exists := fr.list[el.UserID][el.ID]; exists {
+				rec.meta = el.meta()
+			}
+
+		case opDelete:
+			markDeleted(fr.list[el.UserID], el.IDs)
+
+		case opRemove:
+			removeRecord(fr.list, fr.originals, fr.owners, fr.blocked, el.UserID, el.URL)
+
+		case opRedirect:
+			if rec, exists := fr.list[el.UserID][el.ID]; exists && el.Redirect != nil {
+				rec.redirect = *el.Redirect
+			}
+
+		default:
+			addRecord(fr.list, fr.originals, fr.owners, el.UserID, el.ID, &record{originalURL: el.URL, inputURL: el.InputURL, createdAt: el.CreatedAt, meta: el.meta()})
 		}
+	}
+
+	return nil
+}
+
+// write запись операции в файл.
+func (fr *FileRepo) write(el *URL) error {
+	data, err := json.Marshal(el)
+	if err != nil {
+		logger.Error(`marshal error`, err)
+		return err
+	}
+
+	data = append(data, '\n')
 
-		fr.list[el.UserID][el.ID] = el.URL
+	_, err = fr.file.Write(data)
+	if err != nil {
+		logger.Error(`file write error`, err)
+		return err
 	}
 
 	return nil
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -63,7 +64,7 @@ func TestFileAddURLAndGetURLSuccess(t *testing.T) {
 	shortsList := make(map[string]string)
 
 	for _, val := range urlList {
-		hash, err := GetRepository().Add(ctx, user, val)
+		hash, err := GetRepository().Add(ctx, user, val, ``)
 		require.NoError(t, err)
 
 		shortsList[hash] = val
@@ -98,7 +99,7 @@ func TestFileAddURLAndGetAllURLSuccess(t *testing.T) {
 	shortsList := make(map[string]string)
 
 	for _, val := range urlList {
-		hash, err := GetRepository().Add(ctx, user, val)
+		hash, err := GetRepository().Add(ctx, user, val, ``)
 		require.NoError(t, err)
 
 		shortsList[hash] = val
@@ -140,14 +141,23 @@ func TestFileAddExistsURLFileSuccess(t *testing.T) {
 	err = Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
-	hash, err := GetRepository().Add(ctx, user, targetURL)
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
 	require.NoError(t, err)
 
-	_, err = GetRepository().Add(ctx, user, targetURL)
+	_, err = GetRepository().Add(ctx, user, targetURL, ``)
 	var uniqError *models.UniqueErr
 	require.ErrorAs(t, err, &uniqError)
 	require.Equal(t, hash, uniqError.ShortKey)
 
+	// Ключ занят URL другого пользователя
+	_, err = GetRepository().Add(ctx, &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}, targetURL, ``)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, user.ID, info.UserID)
+
 	err = os.Remove(cfg.FileStoragePath)
 	require.NoError(t, err)
 }
@@ -167,7 +177,7 @@ func TestFileCreatedFileSuccess(t *testing.T) {
 	err = Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
-	_, err = GetRepository().Add(ctx, user, targetURL)
+	_, err = GetRepository().Add(ctx, user, targetURL, ``)
 	require.NoError(t, err)
 
 	assert.FileExists(t, cfg.FileStoragePath)
@@ -191,7 +201,7 @@ func TestFileLoadFromFileSuccess(t *testing.T) {
 	err = Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
-	hash, err := GetRepository().Add(ctx, user, targetURL)
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
 	require.NoError(t, err)
 
 	err = Init(ctx, &cfg, nil)
@@ -239,6 +249,56 @@ func TestFileAddBatchSuccess(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestFileAddBatchDuplicates(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	hash2 := urlhasher.GetHash(targetURL + `2`)
+
+	urlList := []models.BatchEl{
+		{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: hash},
+		{CorrelationID: `2`, OriginalURL: targetURL + `2`, ShortURL: hash2},
+		{CorrelationID: `3`, OriginalURL: targetURL + `2`, ShortURL: hash2},
+	}
+
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.BatchEl{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: hash, Exists: true}, urlList[0])
+	assert.Equal(t, models.BatchEl{CorrelationID: `2`, OriginalURL: targetURL + `2`, ShortURL: hash2}, urlList[1])
+	assert.Equal(t, models.BatchEl{CorrelationID: `3`, OriginalURL: targetURL + `2`, ShortURL: hash2, Exists: true}, urlList[2])
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	list, err := GetRepository().GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	_, err = GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, hash2, uniqErr.ShortKey)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
 func TestFileIsReadyFileSuccess(t *testing.T) {
 	shutdown.Init()
 	cfg := config.Load()
@@ -274,3 +334,400 @@ func TestFileIsReadyFileFalse(t *testing.T) {
 
 	assert.False(t, GetRepository().IsReady(ctx))
 }
+
+func TestFileMoveUserURLsSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash1, err := GetRepository().Add(ctx, user, targetURL+`1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	// У пользователя user2 исходный URL уже есть под ключом, оставшимся после изменения исходного URL
+	hash3, err := GetRepository().Add(ctx, user2, targetURL+`3`, ``)
+	require.NoError(t, err)
+
+	_, err = GetRepository().UpdateURL(ctx, user2, hash3, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	token := newTransferToken(user)
+
+	moved, err := GetRepository().MoveUserURLs(ctx, token, user2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	res, _, err := GetRepository().GetByShortName(ctx, user2, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, hash2)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`2`, res)
+
+	// Токен остается использованным после повторной загрузки
+	_, err = GetRepository().MoveUserURLs(ctx, token, user2)
+	assert.ErrorIs(t, err, ErrTransferTokenUsed)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileRemoveSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash1, err := GetRepository().Add(ctx, user, targetURL+`1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	err = GetRepository().RemoveBatch(ctx, user, []string{hash1, `any_url`})
+	require.NoError(t, err)
+
+	err = GetRepository().RemoveByOriginalURL(ctx, user, targetURL+`2`)
+	require.NoError(t, err)
+
+	// Проверка после повторной загрузки из файла
+	GetRepository().Close()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	res, isDeleted, err := GetRepository().GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+	assert.True(t, isDeleted)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash2)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	GetRepository().Close()
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileAdminSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	_, err = GetRepository().Add(ctx, user2, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	found, err := GetRepository().SetBlocked(ctx, hash, true, `spam`)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	removed, err := GetRepository().PurgeUser(ctx, user2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.WithinDuration(t, time.Now(), info.CreatedAt, time.Minute)
+	assert.Equal(t, &models.URLInfo{ShortKey: hash, OriginalURL: targetURL, UserID: user.ID, IsBlocked: true, BlockReason: `spam`, CreatedAt: info.CreatedAt}, info)
+
+	list, err := GetRepository().GetUserURLs(ctx, user2)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	found, err = GetRepository().SetBlocked(ctx, hash, false, ``)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err = GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.False(t, info.IsBlocked)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileAddInputURLSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL+`/?a=1&b=2`, `HTTPS://`+targetURL[8:]+`/?b=2&a=1`)
+	require.NoError(t, err)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`/?a=1&b=2`, info.OriginalURL)
+	assert.Equal(t, `HTTPS://`+targetURL[8:]+`/?b=2&a=1`, info.InputURL)
+
+	chList, _ := GetRepository().GetAll(ctx, user)
+	for el := range chList {
+		assert.Equal(t, info.InputURL, el.InputURL)
+	}
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileSetRedirectSettingsSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+	settings := &models.RedirectSettings{StatusCode: 301, PassQuery: true, UTMParams: map[string]string{`utm_source`: `short`}}
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	found, err := GetRepository().SetRedirectSettings(ctx, user2, hash, settings)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = GetRepository().SetRedirectSettings(ctx, user, hash, settings)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, *settings, info.Redirect)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileAddClickSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	err = GetRepository().AddClick(ctx, `unknown`)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = GetRepository().AddClick(ctx, hash)
+		require.NoError(t, err)
+	}
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Clicks)
+
+	// Переходы записываются в файл при закрытии репозитория одной операцией
+	GetRepository().Close()
+
+	data, err := os.ReadFile(cfg.FileStoragePath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), `"op":"click"`))
+
+	// Запись перехода прежнего формата учитывается как один переход
+	data = append(data, []byte(`{"user_id":"","id":"`+hash+`","url":"","op":"click"}`+"\n")...)
+	err = os.WriteFile(cfg.FileStoragePath, data, 0666)
+	require.NoError(t, err)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err = GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), info.Clicks)
+	assert.WithinDuration(t, time.Now(), info.CreatedAt, time.Minute)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileUpdateURLSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	otherHash, err := GetRepository().Add(ctx, user, targetURL+`/other`, ``)
+	require.NoError(t, err)
+
+	_, err = GetRepository().UpdateURL(ctx, user, hash, targetURL+`/other`, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, otherHash, uniqErr.ShortKey)
+
+	found, err := GetRepository().UpdateURL(ctx, user, hash, targetURL+`/new`, ``)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = GetRepository().UpdateURL(ctx, user, hash, targetURL+`/newest`, ``)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`/newest`, info.OriginalURL)
+
+	history, err := GetRepository().GetURLHistory(ctx, user, hash)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, targetURL, history[0].PreviousURL)
+	assert.Equal(t, targetURL+`/new`, history[1].PreviousURL)
+	assert.Equal(t, targetURL+`/newest`, history[1].NewURL)
+	assert.WithinDuration(t, time.Now(), history[1].ChangedAt, time.Minute)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
+
+func TestFileSetURLMetaSuccess(t *testing.T) {
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_ = os.Remove(cfg.FileStoragePath)
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	found, err := GetRepository().SetURLMeta(ctx, user, hash, &models.URLMeta{Title: `title`, Tags: []string{`a`}})
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	list := []models.BatchEl{{OriginalURL: targetURL + `/batch`, ShortURL: urlhasher.GetHash(targetURL + `/batch`), Meta: models.URLMeta{Notes: `notes`, Tags: []string{`b`}}}}
+	err = GetRepository().AddBatch(ctx, user, &list)
+	require.NoError(t, err)
+
+	// Проверка после повторной загрузки из файла
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, models.URLMeta{Title: `title`, Tags: []string{`a`}}, info.URLMeta)
+
+	info, err = GetRepository().GetURLInfo(ctx, urlhasher.GetHash(targetURL+`/batch`))
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, models.URLMeta{Notes: `notes`, Tags: []string{`b`}}, info.URLMeta)
+
+	err = os.Remove(cfg.FileStoragePath)
+	require.NoError(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/Alheor/shorturl/internal/models"
 	"github.com/Alheor/shorturl/internal/urlhasher"
@@ -13,12 +14,22 @@ var _ IRepository = (*MemoryRepo)(nil)
 
 // MemoryRepo - структура репозитория в памяти.
 type MemoryRepo struct {
-	list map[string]map[string]string
+	list map[string]map[string]*record
+	// originals - обратный индекс: пользователь - исходный URL - короткий ключ.
+	originals map[string]map[string]string
+	// owners - индекс владельцев: короткий ключ - пользователь. Короткие ключи уникальны среди всех пользователей.
+	owners map[string]string
+	// blocked - заблокированные URL: короткий ключ - причина блокировки.
+	blocked map[string]string
+	// transfers - использованные токены переноса: идентификатор токена - время истечения.
+	transfers map[string]time.Time
+	// closed - репозиторий завершил работу.
+	closed bool
 	sync.RWMutex
 }
 
 // Add Добавить URL.
-func (fr *MemoryRepo) Add(ctx context.Context, user *models.User, name string) (string, error) {
+func (fr *MemoryRepo) Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error) {
 
 	select {
 	case <-ctx.Done():
@@ -29,21 +40,19 @@ func (fr *MemoryRepo) Add(ctx context.Context, user *models.User, name string) (
 	fr.Lock()
 	defer fr.Unlock()
 
-	if fr.list[user.ID] == nil {
-		fr.list[user.ID] = make(map[string]string)
+	//Обработка существующих URL
+	if hash, exists := fr.originals[user.ID][name]; exists {
+		return ``, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: hash}
 	}
 
-	urls := fr.list[user.ID]
+	hash := urlhasher.GetHash(name)
 
-	//Обработка существующих URL
-	for hash, el := range urls {
-		if el == name {
-			return ``, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: hash}
-		}
+	// Ключ мог остаться за URL, исходный URL которого был изменен, либо принадлежать URL другого пользователя
+	if _, exists := fr.owners[hash]; exists {
+		return ``, ErrShortKeyInUse
 	}
 
-	hash := urlhasher.GetHash(name)
-	urls[hash] = name
+	addRecord(fr.list, fr.originals, fr.owners, user.ID, hash, &record{originalURL: name, inputURL: inputURL, createdAt: time.Now()})
 
 	return hash, nil
 }
@@ -60,14 +69,20 @@ func (fr *MemoryRepo) AddBatch(ctx context.Context, user *models.User, list *[]m
 	fr.Lock()
 	defer fr.Unlock()
 
-	if fr.list[user.ID] == nil {
-		fr.list[user.ID] = make(map[string]string)
+	if err := checkBatch(fr.owners, fr.originals[user.ID], *list); err != nil {
+		return err
 	}
 
-	urls := fr.list[user.ID]
+	now := time.Now()
 
-	for _, v := range *list {
-		urls[v.ShortURL] = v.OriginalURL
+	for i := range *list {
+		v := &(*list)[i]
+
+		if markExisting(fr.originals[user.ID], v) {
+			continue
+		}
+
+		addRecord(fr.list, fr.originals, fr.owners, user.ID, v.ShortURL, &record{originalURL: v.OriginalURL, inputURL: v.InputURL, createdAt: now, meta: cloneMeta(v.Meta)})
 	}
 
 	return nil
@@ -85,31 +100,18 @@ func (fr *MemoryRepo) GetByShortName(ctx context.Context, user *models.User, nam
 	fr.RLock()
 	defer fr.RUnlock()
 
-	//Костыль для прохождения тестов
+	var el *record
 	if user == nil {
-		for _, el := range fr.list {
-			//Жесть, но тесты нужно пройти
-			for short, original := range el {
-				if short == name {
-					return original, false, nil
-				}
-			}
-		}
-
-		return ``, false, nil
-	}
-
-	urls, exists := fr.list[user.ID]
-	if !exists {
-		return ``, false, nil
+		_, el = findRecord(fr.list, fr.owners, name)
+	} else {
+		el = fr.list[user.ID][name]
 	}
 
-	el, exists := urls[name]
-	if !exists {
+	if el == nil {
 		return ``, false, nil
 	}
 
-	return el, false, nil
+	return el.originalURL, el.deleted, nil
 }
 
 // IsReady Готовность репозитория.
@@ -120,55 +122,467 @@ func (fr *MemoryRepo) IsReady(ctx context.Context) bool {
 	default:
 	}
 
-	return fr.list != nil
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return fr.list != nil && !fr.closed
 }
 
 // RemoveByOriginalURL удалить URL.
-// Deprecated: не поддерживается эти типом репозитория.
 func (fr *MemoryRepo) RemoveByOriginalURL(ctx context.Context, user *models.User, url string) error {
-	return errors.New(`method "Remove" from memory repository not supported`)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	removeRecord(fr.list, fr.originals, fr.owners, fr.blocked, user.ID, url)
+
+	return nil
 }
 
 // GetAll получить все URL пользователя.
+// URL копируются под блокировкой и отправляются в канал после ее снятия.
 func (fr *MemoryRepo) GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error) {
-	out := make(chan models.HistoryEl)
-	errCh := make(chan error, 1)
 
-	defer close(errCh)
+	select {
+	case <-ctx.Done():
+		return streamError(ctx.Err())
+	default:
+	}
+
+	fr.RLock()
+	list := historyList(fr.list[user.ID])
+	fr.RUnlock()
+
+	if len(list) == 0 {
+		return streamError(&models.HistoryNotFoundErr{})
+	}
+
+	return streamHistory(ctx, list)
+}
+
+// RemoveBatch массовое удаление URL.
+func (fr *MemoryRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	markDeleted(fr.list[user.ID], list)
+
+	return nil
+}
+
+// MoveUserURLs перенос URL между пользователями.
+func (fr *MemoryRepo) MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if !useTransferToken(fr.transfers, token) {
+		return 0, ErrTransferTokenUsed
+	}
+
+	return moveUserURLs(fr.list, fr.originals, fr.owners, token.From.ID, to.ID), nil
+}
+
+// GetURLInfo получить URL по короткому ключу независимо от владельца.
+func (fr *MemoryRepo) GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return findURLInfo(fr.list, fr.owners, fr.blocked, shortKey), nil
+}
+
+// SetBlocked блокировка URL.
+func (fr *MemoryRepo) SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	if findURLInfo(fr.list, fr.owners, fr.blocked, shortKey) == nil {
+		return false, nil
+	}
+
+	if fr.blocked == nil {
+		fr.blocked = make(map[string]string)
+	}
+
+	setBlocked(fr.blocked, shortKey, blocked, reason)
+
+	return true, nil
+}
+
+// GetUserURLs получить все URL пользователя.
+func (fr *MemoryRepo) GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return userURLInfo(fr.list, fr.blocked, user.ID), nil
+}
+
+// PurgeUser удаление всех URL пользователя.
+func (fr *MemoryRepo) PurgeUser(ctx context.Context, user *models.User) (int, error) {
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	removed := len(fr.list[user.ID])
+	deleteUser(fr.list, fr.originals, fr.owners, fr.blocked, user.ID)
+
+	return removed, nil
+}
+
+// SetRedirectSettings изменение настроек перенаправления URL пользователя.
+func (fr *MemoryRepo) SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	el, exists := fr.list[user.ID][shortKey]
+	if !exists {
+		return false, nil
+	}
+
+	el.redirect = *settings
+
+	return true, nil
+}
+
+// AddClick учет перехода по URL.
+func (fr *MemoryRepo) AddClick(ctx context.Context, shortKey string) error {
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	fr.RLock()
+	defer fr.RUnlock()
+
+	if _, el := findRecord(fr.list, fr.owners, shortKey); el != nil {
+		el.clicks.Add(1)
+	}
+
+	return nil
+}
+
+// UpdateURL изменение исходного URL.
+func (fr *MemoryRepo) UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error) {
 
 	select {
 	case <-ctx.Done():
-		close(out)
-		errCh <- ctx.Err()
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	el, err := findUpdated(fr.list[user.ID], fr.originals[user.ID], shortKey, name)
+	if err != nil || el == nil {
+		return false, err
+	}
+
+	updateRecord(fr.originals[user.ID], shortKey, el, name, inputURL, time.Now())
 
-		return out, errCh
+	return true, nil
+}
+
+// GetURLHistory получение истории изменений исходного URL.
+func (fr *MemoryRepo) GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	default:
 	}
 
-	list, exists := fr.list[user.ID]
+	fr.RLock()
+	defer fr.RUnlock()
+
+	return historyOf(fr.list[user.ID], shortKey), nil
+}
+
+// SetURLMeta изменение метаданных URL.
+func (fr *MemoryRepo) SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	fr.Lock()
+	defer fr.Unlock()
+
+	el, exists := fr.list[user.ID][shortKey]
 	if !exists {
-		close(out)
-		errCh <- &models.HistoryNotFoundErr{}
+		return false, nil
+	}
+
+	el.meta = cloneMeta(*meta)
 
-		return out, errCh
+	return true, nil
+}
+
+// Close завершение работы с репозиторием
+func (fr *MemoryRepo) Close() {
+	fr.Lock()
+	defer fr.Unlock()
+
+	fr.closed = true
+}
+
+// moveUserURLs перенос URL между пользователями в списке list, обратном индексе originals и индексе владельцев owners.
+// URL, которые у пользователя to уже есть, остаются у пользователя from.
+func moveUserURLs(list map[string]map[string]*record, originals map[string]map[string]string, owners map[string]string, from, to string) int {
+	source := list[from]
+	if len(source) == 0 || from == to {
+		return 0
+	}
+
+	moved := 0
+	for shortURL, el := range source {
+		if _, exists := originals[to][el.originalURL]; exists {
+			continue
+		}
+
+		delete(source, shortURL)
+		delete(originals[from], el.originalURL)
+		addRecord(list, originals, owners, to, shortURL, el)
+		moved++
 	}
 
-	go func() {
-		defer close(out)
+	if len(source) == 0 {
+		delete(list, from)
+		delete(originals, from)
+	}
+
+	return moved
+}
+
+// addRecord добавление URL пользователя userID в список list, обратный индекс originals и индекс владельцев owners.
+func addRecord(list map[string]map[string]*record, originals map[string]map[string]string, owners map[string]string, userID, shortKey string, el *record) {
+	if list[userID] == nil {
+		list[userID] = make(map[string]*record)
+	}
+
+	if originals[userID] == nil {
+		originals[userID] = make(map[string]string)
+	}
 
-		for shortURL, originalURL := range list {
-			out <- models.HistoryEl{OriginalURL: originalURL, ShortURL: shortURL}
+	if prev, exists := list[userID][shortKey]; exists {
+		delete(originals[userID], prev.originalURL)
+	}
+
+	list[userID][shortKey] = el
+	originals[userID][el.originalURL] = shortKey
+	owners[shortKey] = userID
+}
+
+// updateRecord изменение исходного URL el с коротким ключом shortKey и обновлением обратного индекса originals пользователя.
+func updateRecord(originals map[string]string, shortKey string, el *record, name string, inputURL string, changedAt time.Time) {
+	delete(originals, el.originalURL)
+	el.update(name, inputURL, changedAt)
+	originals[el.originalURL] = shortKey
+}
+
+// deleteUser удаление всех URL пользователя userID из списка list, обратного индекса originals, индекса владельцев owners
+// и заблокированных URL blocked, чтобы URL, добавленный позже с тем же ключом, не оказался заблокированным.
+func deleteUser(list map[string]map[string]*record, originals map[string]map[string]string, owners map[string]string, blocked map[string]string, userID string) {
+	for shortKey := range list[userID] {
+		deleteOwner(owners, shortKey, userID)
+		delete(blocked, shortKey)
+	}
+
+	delete(list, userID)
+	delete(originals, userID)
+}
+
+// historyList копия списка URL пользователя urls.
+func historyList(urls map[string]*record) []models.HistoryEl {
+	res := make([]models.HistoryEl, 0, len(urls))
+
+	for shortKey, el := range urls {
+		res = append(res, el.historyEl(shortKey))
+	}
+
+	return res
+}
+
+// userKeys короткие ключи из списка keys, которые есть среди URL пользователя urls.
+func userKeys(urls map[string]*record, keys []string) []string {
+	var res []string
+
+	for _, key := range keys {
+		if _, exists := urls[key]; exists {
+			res = append(res, key)
 		}
-	}()
+	}
 
-	return out, errCh
+	return res
 }
 
-// RemoveBatch массовое удаление URL.
-// Deprecated: не поддерживается эти типом репозитория.
-func (fr *MemoryRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
-	return errors.New(`method "RemoveBatch" from memory repository not supported`)
+// markDeleted отметка URL пользователя urls с короткими ключами keys удаленными.
+func markDeleted(urls map[string]*record, keys []string) {
+	for _, key := range keys {
+		if el, exists := urls[key]; exists {
+			el.deleted = true
+		}
+	}
 }
 
-// Close завершение работы с репозиторием
-func (fr *MemoryRepo) Close() {}
+// removeRecord удаление URL пользователя userID с исходным URL name из списка list, обратного индекса originals,
+// индекса владельцев owners и заблокированных URL blocked. Возвращает false, если URL не найден.
+func removeRecord(list map[string]map[string]*record, originals map[string]map[string]string, owners map[string]string, blocked map[string]string, userID, name string) bool {
+	shortKey, exists := originals[userID][name]
+	if !exists {
+		return false
+	}
+
+	delete(list[userID], shortKey)
+	delete(originals[userID], name)
+	deleteOwner(owners, shortKey, userID)
+	delete(blocked, shortKey)
+
+	return true
+}
+
+// deleteOwner удаление короткого ключа shortKey пользователя userID из индекса владельцев owners.
+// Ключ, который занят другим пользователем, не удаляется.
+func deleteOwner(owners map[string]string, shortKey, userID string) {
+	if owners[shortKey] == userID {
+		delete(owners, shortKey)
+	}
+}
+
+// checkBatch проверка, что новые URL списка batch можно добавить к URL пользователя с обратным индексом originals.
+// Возвращает ошибку, если короткий ключ нового URL по индексу владельцев owners занят другим URL:
+// URL, исходный URL которого был изменен, либо URL другого пользователя. Такие URL отмечаются в поле KeyInUse.
+func checkBatch(owners map[string]string, originals map[string]string, batch []models.BatchEl) error {
+	var err error
+
+	for i := range batch {
+		if _, exists := originals[batch[i].OriginalURL]; exists {
+			continue
+		}
+
+		if _, exists := owners[batch[i].ShortURL]; exists {
+			batch[i].KeyInUse = true
+			err = ErrShortKeyInUse
+		}
+	}
+
+	return err
+}
+
+// markExisting отметка URL el, который уже есть у пользователя с обратным индексом originals, существующим.
+// Короткий ключ el заменяется ключом существующего URL.
+func markExisting(originals map[string]string, el *models.BatchEl) bool {
+	hash, exists := originals[el.OriginalURL]
+	if !exists {
+		return false
+	}
+
+	el.ShortURL = hash
+	el.Exists = true
+
+	return true
+}
+
+// findURLInfo поиск URL по короткому ключу среди всех пользователей списка list по индексу владельцев owners.
+func findURLInfo(list map[string]map[string]*record, owners map[string]string, blocked map[string]string, shortKey string) *models.URLInfo {
+	userID, el := findRecord(list, owners, shortKey)
+	if el == nil {
+		return nil
+	}
+
+	info := el.info(userID, shortKey, blocked)
+	return &info
+}
+
+// findRecord поиск URL и его владельца по короткому ключу среди всех пользователей списка list по индексу владельцев owners.
+func findRecord(list map[string]map[string]*record, owners map[string]string, shortKey string) (string, *record) {
+	userID, exists := owners[shortKey]
+	if !exists {
+		return ``, nil
+	}
+
+	el, exists := list[userID][shortKey]
+	if !exists {
+		return ``, nil
+	}
+
+	return userID, el
+}
+
+// userURLInfo все URL пользователя userID из списка list.
+func userURLInfo(list map[string]map[string]*record, blocked map[string]string, userID string) []models.URLInfo {
+	res := make([]models.URLInfo, 0, len(list[userID]))
+
+	for shortKey, el := range list[userID] {
+		res = append(res, el.info(userID, shortKey, blocked))
+	}
+
+	return res
+}
+
+// setBlocked установка или снятие блокировки URL в списке blocked.
+func setBlocked(blocked map[string]string, shortKey string, isBlocked bool, reason string) {
+	if isBlocked {
+		blocked[shortKey] = reason
+		return
+	}
+
+	delete(blocked, shortKey)
+}
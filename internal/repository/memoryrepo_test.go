@@ -50,7 +50,7 @@ func TestMemoryAddURLAndGetURLSuccess(t *testing.T) {
 	shortsList := make(map[string]string)
 
 	for _, val := range urlList {
-		hash, err := GetRepository().Add(ctx, user, val)
+		hash, err := GetRepository().Add(ctx, user, val, ``)
 		require.NoError(t, err)
 
 		shortsList[hash] = val
@@ -79,7 +79,7 @@ func TestMemoryAddURLAndGetAllURLSuccess(t *testing.T) {
 	shortsList := make(map[string]string)
 
 	for _, val := range urlList {
-		hash, err := GetRepository().Add(ctx, user, val)
+		hash, err := GetRepository().Add(ctx, user, val, ``)
 		require.NoError(t, err)
 
 		shortsList[hash] = val
@@ -118,10 +118,10 @@ func TestMemoryAddExistsURLSuccess(t *testing.T) {
 	err = Init(ctx, &cfg, nil)
 	require.NoError(t, err)
 
-	hash, err := GetRepository().Add(ctx, user, targetURL)
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
 	require.NoError(t, err)
 
-	_, err = GetRepository().Add(ctx, user, targetURL)
+	_, err = GetRepository().Add(ctx, user, targetURL, ``)
 	var uniqError *models.UniqueErr
 	require.ErrorAs(t, err, &uniqError)
 	require.Equal(t, hash, uniqError.ShortKey)
@@ -156,6 +156,70 @@ func TestMemoryAddBatchSuccess(t *testing.T) {
 	}
 }
 
+func TestMemoryAddBatchDuplicates(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	urlList := []models.BatchEl{
+		{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: `hash1`},
+		{CorrelationID: `2`, OriginalURL: targetURL + `2`, ShortURL: `hash2`},
+		{CorrelationID: `3`, OriginalURL: targetURL + `2`, ShortURL: `hash3`},
+	}
+
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.BatchEl{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: hash, Exists: true}, urlList[0])
+	assert.Equal(t, models.BatchEl{CorrelationID: `2`, OriginalURL: targetURL + `2`, ShortURL: `hash2`}, urlList[1])
+	assert.Equal(t, models.BatchEl{CorrelationID: `3`, OriginalURL: targetURL + `2`, ShortURL: `hash2`, Exists: true}, urlList[2])
+
+	res, _, err := GetRepository().GetByShortName(ctx, user, `hash3`)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	_, err = GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, `hash2`, uniqErr.ShortKey)
+
+	// Исходный URL после изменения снова можно добавить
+	found, err := GetRepository().UpdateURL(ctx, user, `hash2`, targetURL+`/new`, ``)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	urlList = []models.BatchEl{{CorrelationID: `1`, OriginalURL: targetURL + `2`, ShortURL: `hash3`}}
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	require.NoError(t, err)
+	assert.False(t, urlList[0].Exists)
+
+	// Ключ занят URL, исходный URL которого был изменен
+	urlList = []models.BatchEl{{CorrelationID: `1`, OriginalURL: targetURL + `3`, ShortURL: `hash2`}}
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+
+	// Ключ занят URL другого пользователя
+	urlList = []models.BatchEl{{CorrelationID: `1`, OriginalURL: targetURL + `2`, ShortURL: `hash3`}}
+	err = GetRepository().AddBatch(ctx, &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}, &urlList)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+
+	info, err := GetRepository().GetURLInfo(ctx, `hash3`)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, user.ID, info.UserID)
+}
+
 func TestMemoryIsReadySuccess(t *testing.T) {
 	cfg := config.Load()
 	cfg.FileStoragePath = ``
@@ -192,3 +256,266 @@ func TestMemoryIsReadyFileFalse(t *testing.T) {
 
 	assert.False(t, GetRepository().IsReady(ctx))
 }
+
+func TestMemoryMoveUserURLsSuccess(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash1, err := GetRepository().Add(ctx, user, targetURL+`1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := GetRepository().Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	// У пользователя user2 исходный URL уже есть под ключом, оставшимся после изменения исходного URL
+	hash3, err := GetRepository().Add(ctx, user2, targetURL+`3`, ``)
+	require.NoError(t, err)
+
+	_, err = GetRepository().UpdateURL(ctx, user2, hash3, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	token := newTransferToken(user)
+
+	moved, err := GetRepository().MoveUserURLs(ctx, token, user2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	res, _, err := GetRepository().GetByShortName(ctx, user2, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, _, err = GetRepository().GetByShortName(ctx, user, hash2)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`2`, res)
+}
+
+func TestMemoryAdminSuccess(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, `unknown`)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	found, err := GetRepository().SetBlocked(ctx, `unknown`, true, `spam`)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = GetRepository().SetBlocked(ctx, hash, true, `spam`)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	info, err = GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.WithinDuration(t, time.Now(), info.CreatedAt, time.Minute)
+	assert.Equal(t, &models.URLInfo{ShortKey: hash, OriginalURL: targetURL, UserID: user.ID, IsBlocked: true, BlockReason: `spam`, CreatedAt: info.CreatedAt}, info)
+
+	list, err := GetRepository().GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, []models.URLInfo{*info}, list)
+
+	found, err = GetRepository().SetBlocked(ctx, hash, false, ``)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	info, err = GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.False(t, info.IsBlocked)
+
+	removed, err := GetRepository().PurgeUser(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	list, err = GetRepository().GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestMemorySetRedirectSettingsSuccess(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	settings := &models.RedirectSettings{StatusCode: 308, UTMParams: map[string]string{`utm_medium`: `link`}}
+
+	found, err := GetRepository().SetRedirectSettings(ctx, user, `unknown`, settings)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	found, err = GetRepository().SetRedirectSettings(ctx, user, hash, settings)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, *settings, info.Redirect)
+}
+
+func TestMemoryAddClickSuccess(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	err = GetRepository().AddClick(ctx, `unknown`)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	err = GetRepository().AddClick(ctx, hash)
+	require.NoError(t, err)
+
+	err = GetRepository().AddClick(ctx, hash)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Clicks)
+}
+
+func TestMemoryUpdateURLSuccess(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	user2 := &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	otherHash, err := GetRepository().Add(ctx, user, targetURL+`/other`, ``)
+	require.NoError(t, err)
+
+	found, err := GetRepository().UpdateURL(ctx, user2, hash, targetURL+`/new`, ``)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = GetRepository().UpdateURL(ctx, user, hash, targetURL+`/other`, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, otherHash, uniqErr.ShortKey)
+
+	found, err = GetRepository().UpdateURL(ctx, user, hash, targetURL+`/new`, `HTTPS://practicum.yandex.ru/new`)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`/new`, info.OriginalURL)
+	assert.Equal(t, `HTTPS://practicum.yandex.ru/new`, info.InputURL)
+
+	history, err := GetRepository().GetURLHistory(ctx, user, hash)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, targetURL, history[0].PreviousURL)
+	assert.Equal(t, targetURL+`/new`, history[0].NewURL)
+
+	// Ключ исходного URL занят измененным URL
+	_, err = GetRepository().Add(ctx, user, targetURL, ``)
+	assert.ErrorIs(t, err, ErrShortKeyInUse)
+}
+
+func TestMemorySetURLMetaSuccess(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	meta := &models.URLMeta{Title: `title`, Notes: `notes`, Tags: []string{`a`, `b`}}
+
+	found, err := GetRepository().SetURLMeta(ctx, user, `unknown`, meta)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	found, err = GetRepository().SetURLMeta(ctx, user, hash, meta)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// Изменение переданного списка не должно влиять на сохраненные теги
+	meta.Tags[0] = `changed`
+
+	list := []models.BatchEl{{OriginalURL: targetURL + `/batch`, ShortURL: `batch`, Meta: models.URLMeta{Tags: []string{`c`}}}}
+	err = GetRepository().AddBatch(ctx, user, &list)
+	require.NoError(t, err)
+
+	info, err := GetRepository().GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, models.URLMeta{Title: `title`, Notes: `notes`, Tags: []string{`a`, `b`}}, info.URLMeta)
+
+	all := make(map[string]models.URLMeta)
+
+	chList, _ := GetRepository().GetAll(ctx, user)
+	for el := range chList {
+		all[el.ShortURL] = el.URLMeta
+	}
+
+	assert.Equal(t, map[string]models.URLMeta{
+		hash:    {Title: `title`, Notes: `notes`, Tags: []string{`a`, `b`}},
+		`batch`: {Tags: []string{`c`}},
+	}, all)
+}
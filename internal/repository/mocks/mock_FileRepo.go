@@ -15,9 +15,9 @@ type MockFileRepo struct {
 }
 
 // Add Добавить URL.
-func (m *MockFileRepo) Add(ctx context.Context, user *models.User, name string) (string, error) {
+func (m *MockFileRepo) Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error) {
 
-	args := m.Called(ctx, user, name)
+	args := m.Called(ctx, user, name, inputURL)
 	return args.String(0), args.Error(0)
 }
 
@@ -61,5 +61,71 @@ func (m *MockFileRepo) RemoveBatch(ctx context.Context, user *models.User, list
 	return args.Error(0)
 }
 
+// MoveUserURLs перенос URL между пользователями.
+func (m *MockFileRepo) MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+	args := m.Called(ctx, token, to)
+	return args.Int(0), args.Error(1)
+}
+
+// GetURLInfo получить URL по короткому ключу независимо от владельца.
+func (m *MockFileRepo) GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+	args := m.Called(ctx, shortKey)
+
+	info, _ := args.Get(0).(*models.URLInfo)
+	return info, args.Error(1)
+}
+
+// SetBlocked блокировка URL.
+func (m *MockFileRepo) SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error) {
+	args := m.Called(ctx, shortKey, blocked, reason)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetUserURLs получить все URL пользователя.
+func (m *MockFileRepo) GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error) {
+	args := m.Called(ctx, user)
+
+	list, _ := args.Get(0).([]models.URLInfo)
+	return list, args.Error(1)
+}
+
+// PurgeUser удаление пользователя.
+func (m *MockFileRepo) PurgeUser(ctx context.Context, user *models.User) (int, error) {
+	args := m.Called(ctx, user)
+	return args.Int(0), args.Error(1)
+}
+
+// SetRedirectSettings изменение настроек перенаправления URL.
+func (m *MockFileRepo) SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+	args := m.Called(ctx, user, shortKey, settings)
+	return args.Bool(0), args.Error(1)
+}
+
+// AddClick учет перехода по URL.
+func (m *MockFileRepo) AddClick(ctx context.Context, shortKey string) error {
+	args := m.Called(ctx, shortKey)
+	return args.Error(0)
+}
+
+// UpdateURL изменение исходного URL.
+func (m *MockFileRepo) UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error) {
+	args := m.Called(ctx, user, shortKey, name, inputURL)
+	return args.Bool(0), args.Error(1)
+}
+
+// GetURLHistory получение истории изменений исходного URL.
+func (m *MockFileRepo) GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+	args := m.Called(ctx, user, shortKey)
+
+	list, _ := args.Get(0).([]models.DestinationChange)
+	return list, args.Error(1)
+}
+
+// SetURLMeta изменение метаданных URL.
+func (m *MockFileRepo) SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+	args := m.Called(ctx, user, shortKey, meta)
+	return args.Bool(0), args.Error(1)
+}
+
 // Close завершение работы с репозиторием
 func (m *MockFileRepo) Close() {}
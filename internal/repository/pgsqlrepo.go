@@ -18,19 +18,26 @@ import (
 
 var _ IRepository = (*PostgresRepo)(nil)
 
+// tagsColumn - теги URL из таблицы short_url_tag в виде массива.
+const tagsColumn = `ARRAY(SELECT t.tag FROM short_url_tag t WHERE t.short_key = short_url.short_key ORDER BY t.tag)`
+
+// urlInfoColumns - колонки models.URLInfo в порядке сканирования.
+const urlInfoColumns = `short_key, original_url, input_url, user_id, is_deleted, is_blocked, block_reason, ` +
+	`redirect_status, pass_query, utm_params, interstitial, created_at, clicks, title, notes, ` + tagsColumn
+
 // PostgresRepo - структура БД репозитория.
 type PostgresRepo struct {
 	Conn *pgxpool.Pool
 }
 
 // Add Добавить URL.
-func (pg *PostgresRepo) Add(ctx context.Context, user *models.User, name string) (string, error) {
+func (pg *PostgresRepo) Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error) {
 
 	hash := urlhasher.GetHash(name)
 
 	_, err := pg.Conn.Exec(ctx,
-		"INSERT INTO short_url (user_id, short_key, original_url) VALUES (@userId, @shortKey, @originalURL)",
-		pgx.NamedArgs{"userId": user.ID, "shortKey": hash, "originalURL": name},
+		"INSERT INTO short_url (user_id, short_key, original_url, input_url) VALUES (@userId, @shortKey, @originalURL, @inputURL)",
+		pgx.NamedArgs{"userId": user.ID, "shortKey": hash, "originalURL": name, "inputURL": inputURL},
 	)
 
 	if err != nil {
@@ -44,6 +51,11 @@ func (pg *PostgresRepo) Add(ctx context.Context, user *models.User, name string)
 			var shortKey string
 			err = row.Scan(&shortKey)
 			if err != nil {
+				// Ключ занят URL, исходный URL которого был изменен
+				if errors.Is(err, pgx.ErrNoRows) {
+					return ``, ErrShortKeyInUse
+				}
+
 				return ``, err
 			}
 
@@ -57,33 +69,215 @@ func (pg *PostgresRepo) Add(ctx context.Context, user *models.User, name string)
 }
 
 // AddBatch Добавить несколько URL.
+// URL копируются в таблицу одной операцией COPY. Если в списке есть повторяющиеся URL, либо копирование прервано
+// нарушением уникальности, URL добавляются через временную таблицу, и уже сокращенные URL отмечаются в поле Exists.
 func (pg *PostgresRepo) AddBatch(ctx context.Context, user *models.User, list *[]models.BatchEl) error {
 
+	if hasDuplicates(*list) {
+		return pg.addBatchUpsert(ctx, user, list)
+	}
+
+	err := pg.addBatchCopy(ctx, user, *list)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		return pg.addBatchUpsert(ctx, user, list)
+	}
+
+	return err
+}
+
+// addBatchCopy добавление URL операцией COPY в одной транзакции.
+func (pg *PostgresRepo) addBatchCopy(ctx context.Context, user *models.User, list []models.BatchEl) error {
+
 	tx, err := pg.Conn.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	var entries [][]any
-	for _, v := range *list {
-		entries = append(entries, []any{user.ID, v.ShortURL, v.OriginalURL})
+	defer tx.Rollback(ctx)
+
+	entries := make([][]any, 0, len(list))
+	for _, v := range list {
+		entries = append(entries, []any{user.ID, v.ShortURL, v.OriginalURL, v.InputURL, v.Meta.Title, v.Meta.Notes})
 	}
 
-	_, err = pg.Conn.CopyFrom(
+	_, err = tx.CopyFrom(
 		ctx,
 		pgx.Identifier{`short_url`},
-		[]string{"user_id", "short_key", "original_url"},
+		[]string{"user_id", "short_key", "original_url", "input_url", "title", "notes"},
 		pgx.CopyFromRows(entries),
 	)
 
 	if err != nil {
-		tx.Rollback(ctx)
+		return err
+	}
+
+	if err = copyBatchTags(ctx, tx, list); err != nil {
 		return err
 	}
 
 	return tx.Commit(ctx)
 }
 
+// addBatchUpsert добавление URL через временную таблицу в одной транзакции.
+// Добавляется первое вхождение каждого исходного URL, для остальных и для уже сокращенных пользователем URL
+// возвращается ключ существующего URL. Если ключ занят другим URL, возвращается ошибка, ни один URL не добавляется,
+// а URL с занятыми ключами отмечаются в поле KeyInUse.
+func (pg *PostgresRepo) addBatchUpsert(ctx context.Context, user *models.User, list *[]models.BatchEl) error {
+
+	tx, err := pg.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		CREATE TEMP TABLE short_url_batch (
+		    ord integer NOT NULL,
+		    short_key varchar(`+strconv.Itoa(urlhasher.HashLength)+`) NOT NULL,
+		    original_url text NOT NULL,
+		    input_url text NOT NULL,
+		    title text NOT NULL,
+		    notes text NOT NULL
+		) ON COMMIT DROP
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	entries := make([][]any, 0, len(*list))
+	for i, v := range *list {
+		entries = append(entries, []any{i, v.ShortURL, v.OriginalURL, v.InputURL, v.Meta.Title, v.Meta.Notes})
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{`short_url_batch`},
+		[]string{"ord", "short_key", "original_url", "input_url", "title", "notes"},
+		pgx.CopyFromRows(entries),
+	)
+
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		INSERT INTO short_url (user_id, short_key, original_url, input_url, title, notes)
+		SELECT DISTINCT ON (original_url) @userId, short_key, original_url, input_url, title, notes
+		FROM short_url_batch ORDER BY original_url, ord
+		ON CONFLICT DO NOTHING
+		RETURNING original_url`,
+		pgx.NamedArgs{"userId": user.ID},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	inserted, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT DISTINCT s.original_url, s.short_key FROM short_url_batch b
+		JOIN short_url s ON s.user_id = @userId AND s.original_url = b.original_url`,
+		pgx.NamedArgs{"userId": user.ID},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]string, len(*list))
+
+	var originalURL, shortKey string
+	_, err = pgx.ForEachRow(rows, []any{&originalURL, &shortKey}, func() error {
+		keys[originalURL] = shortKey
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	created := make(map[string]bool, len(inserted))
+	for _, name := range inserted {
+		created[name] = true
+	}
+
+	added := make([]models.BatchEl, 0, len(inserted))
+
+	var keyErr error
+
+	for i := range *list {
+		v := &(*list)[i]
+
+		key, exists := keys[v.OriginalURL]
+		if !exists {
+			// Ключ занят URL, исходный URL которого был изменен, либо URL другого пользователя.
+			// Занятые ключи отмечаются во всем списке, чтобы подобрать другие ключи за одну попытку
+			v.KeyInUse = true
+			keyErr = ErrShortKeyInUse
+			continue
+		}
+
+		if created[v.OriginalURL] {
+			delete(created, v.OriginalURL)
+			added = append(added, *v)
+			continue
+		}
+
+		v.ShortURL = key
+		v.Exists = true
+	}
+
+	if keyErr != nil {
+		return keyErr
+	}
+
+	if err = copyBatchTags(ctx, tx, added); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// copyBatchTags добавление тегов URL списка list в транзакции tx.
+func copyBatchTags(ctx context.Context, tx pgx.Tx, list []models.BatchEl) error {
+	var tags [][]any
+	for _, v := range list {
+		for _, tag := range v.Meta.Tags {
+			tags = append(tags, []any{v.ShortURL, tag})
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{`short_url_tag`}, []string{"short_key", "tag"}, pgx.CopyFromRows(tags))
+
+	return err
+}
+
+// hasDuplicates проверка наличия повторяющихся исходных URL в списке list.
+func hasDuplicates(list []models.BatchEl) bool {
+	seen := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		if _, exists := seen[v.OriginalURL]; exists {
+			return true
+		}
+
+		seen[v.OriginalURL] = struct{}{}
+	}
+
+	return false
+}
+
 // GetByShortName Получить URL по короткому имени.
 func (pg *PostgresRepo) GetByShortName(ctx context.Context, user *models.User, name string) (string, bool, error) {
 
@@ -134,40 +328,41 @@ func (pg *PostgresRepo) RemoveByOriginalURL(ctx context.Context, user *models.Us
 }
 
 // GetAll получить все URL пользователя.
+// Строки читаются по мере отправки в канал, подключение возвращается в пул после чтения всех строк или отмены ctx.
 func (pg *PostgresRepo) GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error) {
-	out := make(chan models.HistoryEl)
-	errCh := make(chan error, 1)
-
 	rows, err := pg.Conn.Query(ctx,
-		"SELECT short_key, original_url FROM short_url WHERE user_id = @userId",
+		"SELECT short_key, original_url, input_url, title, notes, "+tagsColumn+" FROM short_url WHERE user_id = @userId",
 		pgx.NamedArgs{"userId": user.ID},
 	)
 
 	if err != nil {
-		close(out)
-		errCh <- err
-		close(errCh)
-		return out, errCh
+		return streamError(err)
 	}
 
+	out := make(chan models.HistoryEl)
+	errCh := make(chan error, 1)
+
 	go func() {
-		defer rows.Close()
-		defer close(out)
 		defer close(errCh)
+		defer close(out)
+		defer rows.Close()
 
 		for rows.Next() {
-			var shortURL, originalURL string
-			if err = rows.Scan(&shortURL, &originalURL); err == nil {
-				out <- models.HistoryEl{OriginalURL: originalURL, ShortURL: shortURL}
-
-			} else {
+			var el models.HistoryEl
+			if err := rows.Scan(&el.ShortURL, &el.OriginalURL, &el.InputURL, &el.Title, &el.Notes, &el.Tags); err != nil {
 				errCh <- err
 				return
 			}
+
+			select {
+			case out <- el:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
 		}
 
-		err = rows.Err()
-		if err != nil {
+		if err := rows.Err(); err != nil {
 			errCh <- err
 		}
 	}()
@@ -177,6 +372,10 @@ func (pg *PostgresRepo) GetAll(ctx context.Context, user *models.User) (<-chan m
 
 // RemoveBatch - массовое удаление URL.
 func (pg *PostgresRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
+	if len(list) == 0 {
+		return nil
+	}
+
 	regex := regexp.MustCompile(`\D`)
 	stringIds := ``
 
@@ -196,6 +395,325 @@ func (pg *PostgresRepo) RemoveBatch(ctx context.Context, user *models.User, list
 	return nil
 }
 
+// MoveUserURLs перенос URL между пользователями.
+func (pg *PostgresRepo) MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+
+	tx, err := pg.Conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM transfer_token WHERE expires_at < now()`)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO transfer_token (id, expires_at) VALUES (@id, @expiresAt) ON CONFLICT (id) DO NOTHING`,
+		pgx.NamedArgs{"id": token.ID, "expiresAt": token.ExpiresAt},
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return 0, ErrTransferTokenUsed
+	}
+
+	moved := int64(0)
+
+	if token.From.ID != to.ID {
+		tag, err = tx.Exec(ctx, `
+			UPDATE short_url SET user_id = @toUserId
+			WHERE user_id = @fromUserId AND NOT EXISTS (
+				SELECT 1 FROM short_url t WHERE t.user_id = @toUserId AND t.original_url = short_url.original_url
+			)`,
+			pgx.NamedArgs{"fromUserId": token.From.ID, "toUserId": to.ID},
+		)
+
+		if err != nil {
+			return 0, err
+		}
+
+		moved = tag.RowsAffected()
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return int(moved), nil
+}
+
+// GetURLInfo получить URL по короткому ключу независимо от владельца.
+func (pg *PostgresRepo) GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+	row := pg.Conn.QueryRow(ctx,
+		"SELECT "+urlInfoColumns+" FROM short_url WHERE short_key=@shortKey",
+		pgx.NamedArgs{"shortKey": shortKey},
+	)
+
+	var info models.URLInfo
+
+	err := row.Scan(&info.ShortKey, &info.OriginalURL, &info.InputURL, &info.UserID, &info.IsDeleted, &info.IsBlocked, &info.BlockReason,
+		&info.Redirect.StatusCode, &info.Redirect.PassQuery, &info.Redirect.UTMParams, &info.Redirect.Interstitial, &info.CreatedAt, &info.Clicks, &info.Title, &info.Notes, &info.Tags)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	return &info, nil
+}
+
+// SetBlocked блокировка URL.
+func (pg *PostgresRepo) SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error) {
+	if !blocked {
+		reason = ``
+	}
+
+	tag, err := pg.Conn.Exec(ctx,
+		"UPDATE short_url SET is_blocked = @isBlocked, block_reason = @reason WHERE short_key = @shortKey",
+		pgx.NamedArgs{"isBlocked": blocked, "reason": reason, "shortKey": shortKey},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetUserURLs получить все URL пользователя.
+func (pg *PostgresRepo) GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error) {
+	rows, err := pg.Conn.Query(ctx,
+		"SELECT "+urlInfoColumns+" FROM short_url WHERE user_id = @userId",
+		pgx.NamedArgs{"userId": user.ID},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res := make([]models.URLInfo, 0)
+
+	for rows.Next() {
+		var info models.URLInfo
+
+		err = rows.Scan(&info.ShortKey, &info.OriginalURL, &info.InputURL, &info.UserID, &info.IsDeleted, &info.IsBlocked, &info.BlockReason,
+			&info.Redirect.StatusCode, &info.Redirect.PassQuery, &info.Redirect.UTMParams, &info.Redirect.Interstitial, &info.CreatedAt, &info.Clicks, &info.Title, &info.Notes, &info.Tags)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, info)
+	}
+
+	return res, rows.Err()
+}
+
+// PurgeUser удаление всех URL пользователя.
+func (pg *PostgresRepo) PurgeUser(ctx context.Context, user *models.User) (int, error) {
+	// Одним запросом вместе с историей изменений исходных URL
+	row := pg.Conn.QueryRow(ctx, `
+		WITH removed AS (
+			DELETE FROM short_url WHERE user_id = @userId RETURNING short_key
+		), history AS (
+			DELETE FROM short_url_history WHERE short_key IN (SELECT short_key FROM removed)
+		), tags AS (
+			DELETE FROM short_url_tag WHERE short_key IN (SELECT short_key FROM removed)
+		)
+		SELECT count(*) FROM removed`,
+		pgx.NamedArgs{"userId": user.ID},
+	)
+
+	var removed int
+	if err := row.Scan(&removed); err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// SetRedirectSettings изменение настроек перенаправления URL пользователя.
+func (pg *PostgresRepo) SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+	utmParams := settings.UTMParams
+	if utmParams == nil {
+		utmParams = map[string]string{}
+	}
+
+	tag, err := pg.Conn.Exec(ctx, `
+		UPDATE short_url SET redirect_status = @status, pass_query = @passQuery, utm_params = @utmParams,
+			interstitial = @interstitial
+		WHERE user_id = @userId AND short_key = @shortKey`,
+		pgx.NamedArgs{
+			"status":       settings.StatusCode,
+			"passQuery":    settings.PassQuery,
+			"utmParams":    utmParams,
+			"interstitial": settings.Interstitial,
+			"userId":       user.ID,
+			"shortKey":     shortKey,
+		},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// AddClick учет перехода по URL.
+func (pg *PostgresRepo) AddClick(ctx context.Context, shortKey string) error {
+	_, err := pg.Conn.Exec(ctx,
+		"UPDATE short_url SET clicks = clicks + 1 WHERE short_key = @shortKey",
+		pgx.NamedArgs{"shortKey": shortKey},
+	)
+
+	return err
+}
+
+// UpdateURL изменение исходного URL.
+func (pg *PostgresRepo) UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error) {
+
+	tx, err := pg.Conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx,
+		"SELECT original_url FROM short_url WHERE user_id = @userId AND short_key = @shortKey FOR UPDATE",
+		pgx.NamedArgs{"userId": user.ID, "shortKey": shortKey},
+	)
+
+	var previousURL string
+	if err = row.Scan(&previousURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx,
+		"UPDATE short_url SET original_url = @originalURL, input_url = @inputURL WHERE user_id = @userId AND short_key = @shortKey",
+		pgx.NamedArgs{"originalURL": name, "inputURL": inputURL, "userId": user.ID, "shortKey": shortKey},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != pgerrcode.UniqueViolation {
+			return false, err
+		}
+
+		tx.Rollback(ctx)
+
+		row = pg.Conn.QueryRow(ctx,
+			"SELECT short_key FROM short_url WHERE user_id=@userId AND original_url=@originalUrl",
+			pgx.NamedArgs{"userId": user.ID, "originalUrl": name},
+		)
+
+		var existsKey string
+		if err = row.Scan(&existsKey); err != nil {
+			return false, err
+		}
+
+		return false, &models.UniqueErr{Err: pgErr, ShortKey: existsKey}
+	}
+
+	if previousURL != name {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO short_url_history (short_key, previous_url, new_url) VALUES (@shortKey, @previousURL, @newURL)",
+			pgx.NamedArgs{"shortKey": shortKey, "previousURL": previousURL, "newURL": name},
+		)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetURLHistory получение истории изменений исходного URL.
+func (pg *PostgresRepo) GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+	rows, err := pg.Conn.Query(ctx, `
+		SELECT h.previous_url, h.new_url, h.changed_at FROM short_url_history h
+		JOIN short_url s ON s.short_key = h.short_key
+		WHERE s.user_id = @userId AND h.short_key = @shortKey ORDER BY h.id`,
+		pgx.NamedArgs{"userId": user.ID, "shortKey": shortKey},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var res []models.DestinationChange
+
+	for rows.Next() {
+		var el models.DestinationChange
+		if err = rows.Scan(&el.PreviousURL, &el.NewURL, &el.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		res = append(res, el)
+	}
+
+	return res, rows.Err()
+}
+
+// SetURLMeta изменение метаданных URL.
+func (pg *PostgresRepo) SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+
+	tx, err := pg.Conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE short_url SET title = @title, notes = @notes WHERE user_id = @userId AND short_key = @shortKey",
+		pgx.NamedArgs{"title": meta.Title, "notes": meta.Notes, "userId": user.ID, "shortKey": shortKey},
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM short_url_tag WHERE short_key = @shortKey", pgx.NamedArgs{"shortKey": shortKey})
+	if err != nil {
+		return false, err
+	}
+
+	if len(meta.Tags) > 0 {
+		_, err = tx.Exec(ctx,
+			"INSERT INTO short_url_tag (short_key, tag) SELECT @shortKey, unnest(@tags::text[])",
+			pgx.NamedArgs{"shortKey": shortKey, "tags": meta.Tags},
+		)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
 // Close завершение работы с репозиторием
 func (pg *PostgresRepo) Close() {
 	Connection.Close()
@@ -213,8 +731,43 @@ func createDBSchema(ctx context.Context, conn *pgxpool.Pool) error {
 			is_deleted boolean NOT NULL DEFAULT false
 		);
 
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS is_blocked boolean NOT NULL DEFAULT false;
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS block_reason text NOT NULL DEFAULT '';
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS input_url text NOT NULL DEFAULT '';
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS redirect_status smallint NOT NULL DEFAULT 0;
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS pass_query boolean NOT NULL DEFAULT false;
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS utm_params jsonb NOT NULL DEFAULT '{}';
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS interstitial boolean NOT NULL DEFAULT false;
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS clicks bigint NOT NULL DEFAULT 0;
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS title text NOT NULL DEFAULT '';
+		ALTER TABLE short_url ADD COLUMN IF NOT EXISTS notes text NOT NULL DEFAULT '';
+
 		CREATE UNIQUE INDEX IF NOT EXISTS short_url_user_id_original_url_unique_idx ON short_url (user_id, original_url);
 		CREATE INDEX IF NOT EXISTS short_url_user_id_idx ON short_url (user_id);
+
+		CREATE TABLE IF NOT EXISTS short_url_history (
+		    id SERIAL NOT NULL PRIMARY KEY,
+		    short_key varchar(`+strconv.Itoa(urlhasher.HashLength)+`) NOT NULL,
+		    previous_url text NOT NULL,
+		    new_url text NOT NULL,
+		    changed_at timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE INDEX IF NOT EXISTS short_url_history_short_key_idx ON short_url_history (short_key);
+
+		CREATE TABLE IF NOT EXISTS short_url_tag (
+		    short_key varchar(`+strconv.Itoa(urlhasher.HashLength)+`) NOT NULL,
+		    tag text NOT NULL,
+		    PRIMARY KEY (short_key, tag)
+		);
+
+		CREATE INDEX IF NOT EXISTS short_url_tag_tag_idx ON short_url_tag (tag);
+
+		CREATE TABLE IF NOT EXISTS transfer_token (
+		    id varchar(36) NOT NULL PRIMARY KEY,
+		    expires_at timestamptz NOT NULL
+		);
 	`)
 
 	if err != nil {
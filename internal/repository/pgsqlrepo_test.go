@@ -65,7 +65,7 @@ func TestDBAddURLAndGetURLSuccess(t *testing.T) {
 	shortsList := make(map[string]string)
 
 	for _, val := range urlList {
-		hash, err := GetRepository().Add(ctx, user, val)
+		hash, err := GetRepository().Add(ctx, user, val, ``)
 		require.NoError(t, err)
 
 		shortsList[hash] = val
@@ -116,6 +116,59 @@ func TestDBAddBatchSuccess(t *testing.T) {
 	}
 }
 
+func TestDBAddBatchDuplicates(t *testing.T) {
+
+	t.Skip(`Run with database only`) // Для ручного запуска с локальной БД
+
+	shutdown.Init()
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Load()
+	cfg.DatabaseDsn = `user=app password=pass host=localhost port=5432 dbname=app pool_max_conns=10`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err = Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	_, err = Connection.Exec(ctx, `TRUNCATE short_url, short_url_tag`)
+	require.NoError(t, err)
+
+	hash, err := GetRepository().Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	// Копирование прерывается нарушением уникальности, URL добавляются через временную таблицу
+	urlList := []models.BatchEl{
+		{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: `hash1`},
+		{CorrelationID: `2`, OriginalURL: targetURL + `2`, ShortURL: `hash2`, Meta: models.URLMeta{Tags: []string{`a`}}},
+	}
+
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.BatchEl{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: hash, Exists: true}, urlList[0])
+	assert.False(t, urlList[1].Exists)
+
+	info, err := GetRepository().GetURLInfo(ctx, `hash2`)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, []string{`a`}, info.Tags)
+
+	// Повторяющиеся в списке URL
+	urlList = []models.BatchEl{
+		{CorrelationID: `1`, OriginalURL: targetURL + `3`, ShortURL: `hash3`},
+		{CorrelationID: `2`, OriginalURL: targetURL + `3`, ShortURL: `hash3`},
+	}
+
+	err = GetRepository().AddBatch(ctx, user, &urlList)
+	require.NoError(t, err)
+
+	assert.False(t, urlList[0].Exists)
+	assert.Equal(t, models.BatchEl{CorrelationID: `2`, OriginalURL: targetURL + `3`, ShortURL: `hash3`, Exists: true}, urlList[1])
+}
+
 func TestDBIsReadySuccess(t *testing.T) {
 
 	t.Skip(`Run with database only`) // Для ручного запуска с локальной БД
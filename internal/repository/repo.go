@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Alheor/shorturl/internal/config"
@@ -17,12 +20,21 @@ var repo IRepository
 // Connection - активное подключение к БД(если используется БД).
 var Connection *pgxpool.Pool
 
+// ErrShortKeyInUse - короткий ключ нового URL занят другим URL: URL, исходный URL которого был изменен,
+// либо URL другого пользователя. Сервис в этом случае подбирает другой ключ.
+var ErrShortKeyInUse = errors.New(`short key already in use`)
+
+// ErrTransferTokenUsed - токен переноса URL уже использован.
+var ErrTransferTokenUsed = errors.New(`transfer token already used`)
+
 // IRepository - интерфейс репозитория.
 type IRepository interface {
-	// Add - добавить URL.
-	Add(ctx context.Context, user *models.User, name string) (string, error)
+	// Add - добавить URL. inputURL - URL в том виде, в котором его передал пользователь,
+	// если он отличается от нормализованного name, иначе пустая строка.
+	Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error)
 
-	// AddBatch - добавить несколько URL.
+	// AddBatch - добавить несколько URL. Если ключи части URL заняты, возвращает ErrShortKeyInUse,
+	// не добавляет ни одного URL и отмечает такие URL в поле KeyInUse.
 	AddBatch(ctx context.Context, user *models.User, list *[]models.BatchEl) error
 
 	// GetByShortName - получить 1 URL.
@@ -35,11 +47,46 @@ type IRepository interface {
 	RemoveByOriginalURL(ctx context.Context, user *models.User, url string) error
 
 	// GetAll - получить все URL.
+	// URL отправляются в канал, пока его читают. При отмене ctx отправка прекращается, ошибка ctx.Err() передается
+	// в канал ошибок, и оба канала закрываются. Вызывающий код должен читать канал URL до закрытия, либо отменить ctx.
 	GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error)
 
 	// RemoveBatch - удалить несколько URL.
 	RemoveBatch(ctx context.Context, user *models.User, list []string) error
 
+	// MoveUserURLs - атомарно перенести все URL пользователя, выпустившего токен переноса token, пользователю to
+	// и отметить токен использованным. Если токен уже использован, то URL не переносятся и возвращается ErrTransferTokenUsed.
+	// URL, которые у пользователя to уже есть, остаются у прежнего владельца. Возвращает количество перенесенных URL.
+	MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error)
+
+	// GetURLInfo - получить URL по короткому ключу независимо от владельца. Если URL не найден, возвращается nil.
+	GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error)
+
+	// SetBlocked - заблокировать или разблокировать URL по короткому ключу. Возвращает false, если URL не найден.
+	SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error)
+
+	// GetUserURLs - получить все URL пользователя с признаками удаления и блокировки.
+	GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error)
+
+	// PurgeUser - удалить все URL пользователя. Возвращает количество удаленных URL.
+	PurgeUser(ctx context.Context, user *models.User) (int, error)
+
+	// SetRedirectSettings - изменить настройки перенаправления URL пользователя. Возвращает false, если URL не найден.
+	SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error)
+
+	// AddClick - учесть переход по сокращенному URL независимо от владельца.
+	AddClick(ctx context.Context, shortKey string) error
+
+	// UpdateURL - изменить исходный URL сокращенного URL пользователя с сохранением истории изменений.
+	// Возвращает false, если URL не найден, и models.UniqueErr, если у пользователя уже есть другой сокращенный URL с исходным URL name.
+	UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error)
+
+	// GetURLHistory - получить историю изменений исходного URL сокращенного URL пользователя от старых к новым.
+	GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error)
+
+	// SetURLMeta - заменить метаданные сокращенного URL пользователя. Возвращает false, если URL не найден.
+	SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error)
+
 	Close()
 }
 
@@ -48,22 +95,243 @@ type URL struct {
 	UserID string `json:"user_id"`
 	ID     string `json:"id"`
 	URL    string `json:"url"`
+	// Op - операция над данными, пустое значение означает добавление URL.
+	Op string `json:"op,omitempty"`
+	// TargetUserID - пользователь, которому переносятся URL (для операции перенос).
+	TargetUserID string `json:"target_user_id,omitempty"`
+	// ExpiresAt - время истечения токена переноса с идентификатором ID (для операции перенос).
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// Reason - причина блокировки URL (для операции блокировка).
+	Reason string `json:"reason,omitempty"`
+	// InputURL - URL в том виде, в котором его передал пользователь, если он отличается от URL.
+	InputURL string `json:"input_url,omitempty"`
+	// Redirect - настройки перенаправления (для операции изменения настроек перенаправления).
+	Redirect *models.RedirectSettings `json:"redirect,omitempty"`
+	// CreatedAt - дата создания URL (для операции изменения исходного URL - дата изменения).
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// Title - название URL (для операций добавление и изменение метаданных).
+	Title string `json:"title,omitempty"`
+	// Notes - заметки к URL (для операций добавление и изменение метаданных).
+	Notes string `json:"notes,omitempty"`
+	// Tags - теги URL (для операций добавление и изменение метаданных).
+	Tags []string `json:"tags,omitempty"`
+	// IDs - короткие ключи удаляемых URL (для операции удаление).
+	IDs []string `json:"ids,omitempty"`
+	// Clicks - количество переходов по коротким ключам URL (для операции переход).
+	// В записях прежнего формата поле пустое, а переход по URL с ключом ID учитывается один раз.
+	Clicks map[string]int64 `json:"clicks,omitempty"`
+}
+
+// meta метаданные из записи файла.
+func (u *URL) meta() models.URLMeta {
+	return models.URLMeta{Title: u.Title, Notes: u.Notes, Tags: u.Tags}
 }
 
+// record - URL пользователя в репозиториях в памяти и в файле.
+type record struct {
+	// originalURL - сокращенный URL.
+	originalURL string
+	// inputURL - URL в том виде, в котором его передал пользователь, если он отличается от originalURL.
+	inputURL string
+	// redirect - настройки перенаправления.
+	redirect models.RedirectSettings
+	// createdAt - дата создания.
+	createdAt time.Time
+	// clicks - количество переходов. Изменяется без монопольной блокировки репозитория.
+	clicks atomic.Int64
+	// history - история изменений исходного URL.
+	history []models.DestinationChange
+	// meta - пользовательские метаданные.
+	meta models.URLMeta
+	// deleted - URL удален пользователем.
+	deleted bool
+}
+
+// info полная информация об URL.
+func (r *record) info(userID string, shortKey string, blocked map[string]string) models.URLInfo {
+	reason, isBlocked := blocked[shortKey]
+
+	return models.URLInfo{
+		ShortKey:    shortKey,
+		OriginalURL: r.originalURL,
+		InputURL:    r.inputURL,
+		UserID:      userID,
+		IsDeleted:   r.deleted,
+		IsBlocked:   isBlocked,
+		BlockReason: reason,
+		Redirect:    r.redirect,
+		CreatedAt:   r.createdAt,
+		Clicks:      r.clicks.Load(),
+		URLMeta:     cloneMeta(r.meta),
+	}
+}
+
+// historyEl элемент списка URL пользователя.
+func (r *record) historyEl(shortKey string) models.HistoryEl {
+	return models.HistoryEl{OriginalURL: r.originalURL, ShortURL: shortKey, InputURL: r.inputURL, URLMeta: cloneMeta(r.meta)}
+}
+
+// cloneMeta копия метаданных, чтобы список тегов не разделялся между записью и вызывающим кодом.
+func cloneMeta(meta models.URLMeta) models.URLMeta {
+	if meta.Tags != nil {
+		meta.Tags = append([]string{}, meta.Tags...)
+	}
+
+	return meta
+}
+
+// update изменение исходного URL с сохранением предыдущего в истории.
+func (r *record) update(name string, inputURL string, changedAt time.Time) {
+	if r.originalURL == name {
+		r.inputURL = inputURL
+		return
+	}
+
+	r.history = append(r.history, models.DestinationChange{PreviousURL: r.originalURL, NewURL: name, ChangedAt: changedAt})
+	r.originalURL = name
+	r.inputURL = inputURL
+}
+
+// findUpdated поиск URL пользователя для изменения исходного URL на name по URL пользователя urls и их обратному индексу originals.
+// Возвращает nil, если URL не найден, и models.UniqueErr, если исходный URL name уже есть у другого URL пользователя.
+func findUpdated(urls map[string]*record, originals map[string]string, shortKey string, name string) (*record, error) {
+	el, exists := urls[shortKey]
+	if !exists {
+		return nil, nil
+	}
+
+	if hash, exists := originals[name]; exists && hash != shortKey {
+		return nil, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: hash}
+	}
+
+	return el, nil
+}
+
+// historyOf копия истории изменений исходного URL пользователя.
+func historyOf(urls map[string]*record, shortKey string) []models.DestinationChange {
+	el, exists := urls[shortKey]
+	if !exists {
+		return nil
+	}
+
+	return append([]models.DestinationChange{}, el.history...)
+}
+
+// useTransferToken отметка токена переноса token использованным в transfers: идентификатор токена - время истечения.
+// Возвращает false, если токен уже использован. Идентификаторы истекших токенов удаляются,
+// так как такие токены не проходят проверку срока действия.
+func useTransferToken(transfers map[string]time.Time, token *models.TransferToken) bool {
+	if _, exists := transfers[token.ID]; exists {
+		return false
+	}
+
+	now := time.Now()
+	for id, expiresAt := range transfers {
+		if !now.Before(expiresAt) {
+			delete(transfers, id)
+		}
+	}
+
+	transfers[token.ID] = token.ExpiresAt
+
+	return true
+}
+
+// streamHistory отправка списка URL пользователя list в канал до отмены ctx.
+func streamHistory(ctx context.Context, list []models.HistoryEl) (<-chan models.HistoryEl, <-chan error) {
+	out := make(chan models.HistoryEl)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+		defer close(out)
+
+		for _, el := range list {
+			select {
+			case out <- el:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return out, errCh
+}
+
+// streamError закрытый канал URL и канал с единственной ошибкой err.
+func streamError(err error) (<-chan models.HistoryEl, <-chan error) {
+	out := make(chan models.HistoryEl)
+	errCh := make(chan error, 1)
+
+	close(out)
+	errCh <- err
+	close(errCh)
+
+	return out, errCh
+}
+
+// Операции файлового репозитория.
+const (
+	// opMove - перенос URL между пользователями.
+	opMove = `move`
+
+	// opBlock - блокировка URL.
+	opBlock = `block`
+
+	// opUnblock - снятие блокировки URL.
+	opUnblock = `unblock`
+
+	// opPurge - удаление всех URL пользователя.
+	opPurge = `purge`
+
+	// opRedirect - изменение настроек перенаправления URL.
+	opRedirect = `redirect`
+
+	// opClick - переход по URL.
+	opClick = `click`
+
+	// opUpdate - изменение исходного URL.
+	opUpdate = `update`
+
+	// opMeta - изменение метаданных URL.
+	opMeta = `meta`
+
+	// opDelete - удаление URL пользователя с сохранением записи (мягкое удаление).
+	opDelete = `delete`
+
+	// opRemove - удаление URL пользователя по исходному URL.
+	opRemove = `remove`
+)
+
 // Init - инициализация репозитория, определение типа.
-func Init(ctx context.Context, config *config.Options, repository IRepository) error {
+func Init(ctx context.Context, options *config.Options, repository IRepository) error {
 
 	if repository != nil {
 		repo = repository
 		return nil
 	}
 
-	if config.DatabaseDsn != `` {
+	switch storageMode(options) {
+	case config.StorageModeDatabase:
+		if strings.HasPrefix(options.DatabaseDsn, SQLiteDSNPrefix) {
+			logger.Info(`IRepository starting in sqlite mode`)
+
+			sRepo, err := openSQLiteRepo(ctx, options.DatabaseDsn)
+			if err != nil {
+				return err
+			}
+
+			repo = sRepo
+
+			break
+		}
+
 		logger.Info(`IRepository starting in database mode`)
 
 		var err error
 
-		if Connection, err = pgxpool.New(ctx, config.DatabaseDsn); err != nil {
+		if Connection, err = pgxpool.New(ctx, options.DatabaseDsn); err != nil {
 			return err
 		}
 
@@ -79,22 +347,49 @@ func Init(ctx context.Context, config *config.Options, repository IRepository) e
 			return err
 		}
 
-	} else if config.FileStoragePath != `` {
+	case config.StorageModeBolt:
+		logger.Info(`IRepository starting in embedded database mode`)
+
+		bRepo, err := openBoltRepo(ctx, options.BoltStoragePath)
+		if err != nil {
+			return err
+		}
+
+		repo = bRepo
+
+	case config.StorageModeFile:
 		logger.Info(`IRepository starting in file mode`)
 
-		fRepo := &FileRepo{list: make(map[string]map[string]string)}
+		fRepo := &FileRepo{
+			list:      make(map[string]map[string]*record),
+			originals: make(map[string]map[string]string),
+			owners:    make(map[string]string),
+			blocked:   make(map[string]string),
+			transfers: make(map[string]time.Time),
+		}
 
-		err := fRepo.load(ctx, config.FileStoragePath)
+		err := fRepo.load(ctx, options.FileStoragePath)
 		if err != nil {
 			return err
 		}
 
+		fRepo.clicks = newClickCounter(clickFlushInterval, fRepo.flushClicks)
+
 		repo = fRepo
 
-	} else {
+	case config.StorageModeMemory:
 		logger.Info(`IRepository starting in memory mode`)
 
-		repo = &MemoryRepo{list: make(map[string]map[string]string)}
+		repo = &MemoryRepo{
+			list:      make(map[string]map[string]*record),
+			originals: make(map[string]map[string]string),
+			owners:    make(map[string]string),
+			blocked:   make(map[string]string),
+			transfers: make(map[string]time.Time),
+		}
+
+	default:
+		return errors.New(`unknown storage mode "` + options.StorageMode + `"`)
 	}
 
 	logger.Info(`done`)
@@ -102,6 +397,20 @@ func Init(ctx context.Context, config *config.Options, repository IRepository) e
 	return nil
 }
 
+// storageMode режим хранения данных. Если режим не задан, он определяется по параметрам DatabaseDsn и FileStoragePath.
+func storageMode(options *config.Options) string {
+	switch {
+	case options.StorageMode != ``:
+		return options.StorageMode
+	case options.DatabaseDsn != ``:
+		return config.StorageModeDatabase
+	case options.FileStoragePath != ``:
+		return config.StorageModeFile
+	default:
+		return config.StorageModeMemory
+	}
+}
+
 // GetRepository - метод получения текущего экземпляра репозитория.
 func GetRepository() IRepository {
 	return repo
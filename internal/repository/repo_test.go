@@ -8,8 +8,10 @@ import (
 
 	"github.com/Alheor/shorturl/internal/config"
 	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
 	"github.com/Alheor/shorturl/internal/shutdown"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -63,3 +65,24 @@ func TestRepoRunInDBMode(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, `PostgresRepo`, reflect.TypeOf(GetRepository()).Elem().Name())
 }
+
+func TestUseTransferToken(t *testing.T) {
+	transfers := make(map[string]time.Time)
+
+	expired := &models.TransferToken{ID: `expired`, ExpiresAt: time.Now().Add(-time.Second)}
+	assert.True(t, useTransferToken(transfers, expired))
+	assert.False(t, useTransferToken(transfers, expired))
+
+	token := newTransferToken(user)
+	assert.True(t, useTransferToken(transfers, token))
+	assert.False(t, useTransferToken(transfers, token))
+
+	// Истекшие токены удаляются при отметке нового токена
+	assert.NotContains(t, transfers, expired.ID)
+	assert.Contains(t, transfers, token.ID)
+}
+
+// newTransferToken токен переноса URL пользователя from.
+func newTransferToken(from *models.User) *models.TransferToken {
+	return &models.TransferToken{ID: uuid.New().String(), From: from, ExpiresAt: time.Now().Add(time.Minute)}
+}
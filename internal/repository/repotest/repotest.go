@@ -0,0 +1,484 @@
+// Package repotest - набор тестов совместимости для реализаций repository.IRepository.
+//
+// # Описание
+//
+// Набор проверяет одинаково для всех типов хранилищ поведение, на которое рассчитывают сервис и обработчики:
+// повторное добавление URL, массовое добавление с отметкой занятых ключей, мягкое удаление (на удаленные URL обработчики отвечают кодом 410),
+// удаление пользователя со снятием блокировки его URL, чтение всех URL пользователя и его отмену без утечки горутин,
+// однократное использование токена переноса URL, параллельную запись и чтение и завершение работы репозитория.
+//
+// Тест типа хранилища передает в Run функцию, создающую пустой репозиторий:
+//
+//	func TestMemoryConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repository.IRepository {
+//			...
+//			return repository.GetRepository()
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+	"github.com/Alheor/shorturl/internal/urlhasher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+const targetURL = `https://practicum.yandex.ru/`
+
+var (
+	user  = &models.User{ID: `6a30af51-b6ac-63ba-9e1c-5da06e1b610e`}
+	user2 = &models.User{ID: `0b32aa55-b2af-63ba-9e1c-5da06e1b610e`}
+	user3 = &models.User{ID: `9d41bb66-c3b0-74cb-8f2d-6eb17f2c721f`}
+
+	// unknownKey - короткий ключ, которого нет в репозитории.
+	unknownKey = urlhasher.GetHash(`unknown`)
+)
+
+// Factory - функция создания пустого репозитория для одного теста набора. Репозиторий закрывается набором.
+type Factory func(t *testing.T) repository.IRepository
+
+// Run запуск набора тестов для репозиториев, создаваемых newRepo.
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.IRepository)
+	}{
+		{name: `AddDedup`, run: testAddDedup},
+		{name: `AddBatch`, run: testAddBatch},
+		{name: `SoftDelete`, run: testSoftDelete},
+		{name: `PurgeReAdd`, run: testPurgeReAdd},
+		{name: `GetAll`, run: testGetAll},
+		{name: `TransferTokenOnce`, run: testTransferTokenOnce},
+		{name: `ConcurrentWriters`, run: testConcurrentWriters},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newRepo(t)
+			defer repo.Close()
+
+			test.run(t, repo)
+		})
+	}
+
+	t.Run(`Close`, func(t *testing.T) {
+		testClose(t, newRepo(t))
+	})
+}
+
+// testContext контекст одного теста набора.
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+// batchEl элемент массового добавления URL с ключом, который формирует сервис.
+func batchEl(correlationID string, originalURL string, meta models.URLMeta) models.BatchEl {
+	return models.BatchEl{CorrelationID: correlationID, OriginalURL: originalURL, ShortURL: urlhasher.GetHash(originalURL), Meta: meta}
+}
+
+// collect чтение всех URL и ошибок из каналов GetAll: короткий ключ - исходный URL.
+func collect(chList <-chan models.HistoryEl, chErr <-chan error) (map[string]string, []error) {
+	list := make(map[string]string)
+	for el := range chList {
+		list[el.ShortURL] = el.OriginalURL
+	}
+
+	var errs []error
+	for err := range chErr {
+		errs = append(errs, err)
+	}
+
+	return list, errs
+}
+
+func testAddDedup(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	hash, err := repo.Add(ctx, user, targetURL, `HTTPS://practicum.yandex.ru/`)
+	require.NoError(t, err)
+	assert.Equal(t, urlhasher.GetHash(targetURL), hash)
+
+	// Повторное добавление возвращает ключ существующего URL
+	_, err = repo.Add(ctx, user, targetURL, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, hash, uniqErr.ShortKey)
+
+	res, isDeleted, err := repo.GetByShortName(ctx, user, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL, res)
+	assert.False(t, isDeleted)
+
+	res, _, err = repo.GetByShortName(ctx, nil, hash)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL, res)
+
+	res, _, err = repo.GetByShortName(ctx, user2, hash)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, isDeleted, err = repo.GetByShortName(ctx, user, unknownKey)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+	assert.False(t, isDeleted)
+
+	info, err := repo.GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, user.ID, info.UserID)
+	assert.Equal(t, `HTTPS://practicum.yandex.ru/`, info.InputURL)
+
+	info, err = repo.GetURLInfo(ctx, unknownKey)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func testAddBatch(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	hash, err := repo.Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	// Уже сокращенные URL, в том числе повторяющиеся в списке, отмечаются существующими
+	list := []models.BatchEl{
+		batchEl(`1`, targetURL, models.URLMeta{}),
+		batchEl(`2`, targetURL+`1`, models.URLMeta{Title: `title`, Tags: []string{`a`, `b`}}),
+		batchEl(`3`, targetURL+`1`, models.URLMeta{}),
+	}
+
+	err = repo.AddBatch(ctx, user, &list)
+	require.NoError(t, err)
+
+	hash1 := urlhasher.GetHash(targetURL + `1`)
+
+	assert.Equal(t, models.BatchEl{CorrelationID: `1`, OriginalURL: targetURL, ShortURL: hash, Exists: true}, list[0])
+	assert.False(t, list[1].Exists)
+	assert.Equal(t, hash1, list[1].ShortURL)
+	assert.Equal(t, models.BatchEl{CorrelationID: `3`, OriginalURL: targetURL + `1`, ShortURL: hash1, Exists: true}, list[2])
+
+	info, err := repo.GetURLInfo(ctx, hash1)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, models.URLMeta{Title: `title`, Tags: []string{`a`, `b`}}, info.URLMeta)
+
+	// Ключи заняты URL, исходный URL которого был изменен, и URL другого пользователя:
+	// список не добавляется целиком, все URL с занятыми ключами отмечаются
+	hash2, err := repo.Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	found, err := repo.UpdateURL(ctx, user, hash2, targetURL+`3`, ``)
+	require.NoError(t, err)
+	require.True(t, found)
+
+	_, err = repo.Add(ctx, user2, targetURL+`5`, ``)
+	require.NoError(t, err)
+
+	list = []models.BatchEl{
+		batchEl(`1`, targetURL+`4`, models.URLMeta{}),
+		batchEl(`2`, targetURL+`2`, models.URLMeta{}),
+		batchEl(`3`, targetURL+`5`, models.URLMeta{}),
+	}
+
+	err = repo.AddBatch(ctx, user, &list)
+	require.ErrorIs(t, err, repository.ErrShortKeyInUse)
+
+	assert.False(t, list[0].KeyInUse)
+	assert.True(t, list[1].KeyInUse)
+	assert.True(t, list[2].KeyInUse)
+
+	info, err = repo.GetURLInfo(ctx, urlhasher.GetHash(targetURL+`4`))
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func testSoftDelete(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	hash1, err := repo.Add(ctx, user, targetURL+`1`, ``)
+	require.NoError(t, err)
+
+	hash2, err := repo.Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+
+	// URL другого пользователя не удаляются
+	err = repo.RemoveBatch(ctx, user2, []string{hash1})
+	require.NoError(t, err)
+
+	_, isDeleted, err := repo.GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.False(t, isDeleted)
+
+	err = repo.RemoveBatch(ctx, user, nil)
+	require.NoError(t, err)
+
+	err = repo.RemoveBatch(ctx, user, []string{hash1, unknownKey})
+	require.NoError(t, err)
+
+	// Удаленный URL остается в репозитории с отметкой удаления
+	res, isDeleted, err := repo.GetByShortName(ctx, user, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+	assert.True(t, isDeleted)
+
+	res, isDeleted, err = repo.GetByShortName(ctx, nil, hash1)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`1`, res)
+	assert.True(t, isDeleted)
+
+	info, err := repo.GetURLInfo(ctx, hash1)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.True(t, info.IsDeleted)
+
+	_, isDeleted, err = repo.GetByShortName(ctx, user, hash2)
+	require.NoError(t, err)
+	assert.False(t, isDeleted)
+
+	_, err = repo.Add(ctx, user, targetURL+`1`, ``)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, hash1, uniqErr.ShortKey)
+
+	// Удаление по исходному URL удаляет запись, после чего URL можно добавить снова
+	err = repo.RemoveByOriginalURL(ctx, user2, targetURL+`2`)
+	require.NoError(t, err)
+
+	res, _, err = repo.GetByShortName(ctx, user, hash2)
+	require.NoError(t, err)
+	assert.Equal(t, targetURL+`2`, res)
+
+	err = repo.RemoveByOriginalURL(ctx, user, targetURL+`2`)
+	require.NoError(t, err)
+
+	err = repo.RemoveByOriginalURL(ctx, user, targetURL+`unknown`)
+	require.NoError(t, err)
+
+	res, _, err = repo.GetByShortName(ctx, user, hash2)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	info, err = repo.GetURLInfo(ctx, hash2)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	hash, err := repo.Add(ctx, user, targetURL+`2`, ``)
+	require.NoError(t, err)
+	assert.Equal(t, hash2, hash)
+}
+
+func testPurgeReAdd(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	hash, err := repo.Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	found, err := repo.SetBlocked(ctx, hash, true, `phishing`)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	removed, err := repo.PurgeUser(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	info, err := repo.GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	// Тот же URL другого пользователя получает тот же ключ и не наследует блокировку удаленного URL
+	reAdded, err := repo.Add(ctx, user2, targetURL, ``)
+	require.NoError(t, err)
+	assert.Equal(t, hash, reAdded)
+
+	info, err = repo.GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, user2.ID, info.UserID)
+	assert.False(t, info.IsBlocked)
+	assert.Empty(t, info.BlockReason)
+}
+
+func testGetAll(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	// У пользователя без URL поток пуст, либо завершается ошибкой models.HistoryNotFoundErr
+	list, errs := collect(repo.GetAll(ctx, user))
+	assert.Empty(t, list)
+
+	for _, err := range errs {
+		var notFoundErr *models.HistoryNotFoundErr
+		assert.ErrorAs(t, err, &notFoundErr)
+	}
+
+	want := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		name := targetURL + strconv.Itoa(i)
+
+		hash, err := repo.Add(ctx, user, name, ``)
+		require.NoError(t, err)
+
+		want[hash] = name
+	}
+
+	_, err := repo.Add(ctx, user2, targetURL+`other`, ``)
+	require.NoError(t, err)
+
+	list, errs = collect(repo.GetAll(ctx, user))
+	assert.Empty(t, errs)
+	assert.Equal(t, want, list)
+
+	// Канал URL не дочитывается: после отмены контекста отправка прекращается без утечки горутин
+	ignore := goleak.IgnoreCurrent()
+	listCtx, listCancel := context.WithCancel(ctx)
+
+	chList, chErr := repo.GetAll(listCtx, user)
+	<-chList
+	listCancel()
+
+	goleak.VerifyNone(t, ignore)
+
+	for range chList {
+		t.Fatal(`url sent after cancel`)
+	}
+
+	assert.ErrorIs(t, <-chErr, context.Canceled)
+}
+
+func testTransferTokenOnce(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	hash, err := repo.Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	token := &models.TransferToken{ID: `b1f3c2d4-0a1e-4c5b-9d8e-7f6a5b4c3d2e`, From: user, ExpiresAt: time.Now().Add(time.Minute)}
+
+	moved, err := repo.MoveUserURLs(ctx, token, user2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	// Повторное использование токена не переносит URL, в том числе добавленные после первого переноса
+	_, err = repo.Add(ctx, user, targetURL+`other`, ``)
+	require.NoError(t, err)
+
+	_, err = repo.MoveUserURLs(ctx, token, user2)
+	require.ErrorIs(t, err, repository.ErrTransferTokenUsed)
+
+	left, err := repo.GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	assert.Len(t, left, 1)
+
+	info, err := repo.GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, user2.ID, info.UserID)
+
+	// Токен пользователя без URL тоже отмечается использованным
+	empty := &models.TransferToken{ID: `c2e4d3f5-1b2f-4d6c-8e9f-8a7b6c5d4e3f`, From: user3, ExpiresAt: time.Now().Add(time.Minute)}
+
+	moved, err = repo.MoveUserURLs(ctx, empty, user2)
+	require.NoError(t, err)
+	assert.Zero(t, moved)
+
+	_, err = repo.MoveUserURLs(ctx, empty, user2)
+	assert.ErrorIs(t, err, repository.ErrTransferTokenUsed)
+}
+
+func testConcurrentWriters(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	const writers = 8
+	const urlsPerWriter = 10
+
+	hash, err := repo.Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var created atomic.Int32
+
+	errCh := make(chan error, writers*(urlsPerWriter+2)+urlsPerWriter)
+
+	// Чтение всех URL пользователя одновременно с записью
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < urlsPerWriter; i++ {
+			_, errs := collect(repo.GetAll(ctx, user))
+			for _, err := range errs {
+				errCh <- err
+			}
+		}
+	}()
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < urlsPerWriter; i++ {
+				_, err := repo.Add(ctx, user, targetURL+strconv.Itoa(w)+`/`+strconv.Itoa(i), ``)
+				errCh <- err
+			}
+
+			// Один и тот же URL добавляется только одним из писателей
+			_, err := repo.Add(ctx, user, targetURL+`shared`, ``)
+
+			var uniqErr *models.UniqueErr
+			switch {
+			case err == nil:
+				created.Add(1)
+			case !errors.As(err, &uniqErr):
+				errCh <- err
+			}
+
+			errCh <- repo.AddClick(ctx, hash)
+		}(w)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), created.Load())
+
+	urls, err := repo.GetUserURLs(ctx, user)
+	require.NoError(t, err)
+	assert.Len(t, urls, writers*urlsPerWriter+2)
+
+	info, err := repo.GetURLInfo(ctx, hash)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, int64(writers), info.Clicks)
+}
+
+func testClose(t *testing.T, repo repository.IRepository) {
+	ctx := testContext(t)
+
+	_, err := repo.Add(ctx, user, targetURL, ``)
+	require.NoError(t, err)
+
+	assert.True(t, repo.IsReady(ctx))
+
+	repo.Close()
+
+	assert.False(t, repo.IsReady(ctx))
+}
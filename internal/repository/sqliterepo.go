@@ -0,0 +1,716 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/urlhasher"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var _ IRepository = (*SQLiteRepo)(nil)
+
+// SQLiteDSNPrefix - префикс DatabaseDsn, при котором данные хранятся в файле SQLite, например sqlite:///var/lib/shorturl/urls.db.
+const SQLiteDSNPrefix = `sqlite://`
+
+// sqliteTagsColumn - теги URL из таблицы short_url_tag в виде JSON массива.
+const sqliteTagsColumn = `(SELECT json_group_array(tag) FROM (SELECT t.tag FROM short_url_tag t WHERE t.short_key = short_url.short_key ORDER BY t.tag))`
+
+// sqliteURLInfoColumns - колонки models.URLInfo в порядке сканирования.
+const sqliteURLInfoColumns = `short_key, original_url, input_url, user_id, is_deleted, is_blocked, block_reason, ` +
+	`redirect_status, pass_query, utm_params, interstitial, created_at, clicks, title, notes, ` + sqliteTagsColumn
+
+// SQLiteRepo - структура репозитория в файле SQLite.
+type SQLiteRepo struct {
+	db *sql.DB
+}
+
+// openSQLiteRepo открытие файла SQLite по DSN вида sqlite://путь и создание схемы.
+// Журнал ведется в режиме WAL, транзакции сразу захватывают блокировку записи.
+func openSQLiteRepo(ctx context.Context, dsn string) (*SQLiteRepo, error) {
+	path, params, _ := strings.Cut(strings.TrimPrefix(dsn, SQLiteDSNPrefix), `?`)
+
+	query, err := url.ParseQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Add(`_pragma`, `journal_mode(WAL)`)
+	query.Add(`_pragma`, `busy_timeout(5000)`)
+	query.Set(`_time_format`, `sqlite`)
+	query.Set(`_txlock`, `immediate`)
+
+	db, err := sql.Open(`sqlite`, `file:`+path+`?`+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = createSQLiteSchema(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepo{db: db}, nil
+}
+
+// Add Добавить URL.
+func (sr *SQLiteRepo) Add(ctx context.Context, user *models.User, name string, inputURL string) (string, error) {
+
+	hash := urlhasher.GetHash(name)
+
+	_, err := sr.db.ExecContext(ctx,
+		"INSERT INTO short_url (user_id, short_key, original_url, input_url, created_at) VALUES (?, ?, ?, ?, ?)",
+		user.ID, hash, name, inputURL, time.Now(),
+	)
+
+	if err != nil {
+		if !isSQLiteUniqueErr(err) {
+			return ``, err
+		}
+
+		shortKey, err := sqliteUserKey(ctx, sr.db, user.ID, name)
+		if err != nil {
+			return ``, err
+		}
+
+		// Ключ занят URL, исходный URL которого был изменен
+		if shortKey == `` {
+			return ``, ErrShortKeyInUse
+		}
+
+		return ``, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: shortKey}
+	}
+
+	return hash, nil
+}
+
+// AddBatch Добавить несколько URL.
+// Все URL добавляются в одной транзакции. Уже сокращенные пользователем URL, в том числе повторяющиеся в списке,
+// не добавляются и отмечаются в поле Exists. Если ключ занят другим URL, ни один URL не добавляется.
+func (sr *SQLiteRepo) AddBatch(ctx context.Context, user *models.User, list *[]models.BatchEl) error {
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	insertURL, err := tx.PrepareContext(ctx, `
+		INSERT INTO short_url (user_id, short_key, original_url, input_url, title, notes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	defer insertURL.Close()
+
+	insertTag, err := tx.PrepareContext(ctx, "INSERT INTO short_url_tag (short_key, tag) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+
+	defer insertTag.Close()
+
+	now := time.Now()
+
+	// Поля Exists и ShortURL заполняются только при успешной транзакции
+	result := append([]models.BatchEl{}, *list...)
+
+	var keyErr error
+
+	for i := range result {
+		v := &result[i]
+
+		res, err := insertURL.ExecContext(ctx, user.ID, v.ShortURL, v.OriginalURL, v.InputURL, v.Meta.Title, v.Meta.Notes, now)
+		if err != nil {
+			return err
+		}
+
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if inserted == 0 {
+			shortKey, err := sqliteUserKey(ctx, tx, user.ID, v.OriginalURL)
+			if err != nil {
+				return err
+			}
+
+			// Занятые ключи отмечаются во всем списке, чтобы подобрать другие ключи за одну попытку
+			if shortKey == `` {
+				(*list)[i].KeyInUse = true
+				keyErr = ErrShortKeyInUse
+				continue
+			}
+
+			v.ShortURL = shortKey
+			v.Exists = true
+
+			continue
+		}
+
+		for _, tag := range v.Meta.Tags {
+			if _, err = insertTag.ExecContext(ctx, v.ShortURL, tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	if keyErr != nil {
+		return keyErr
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	copy(*list, result)
+
+	return nil
+}
+
+// GetByShortName Получить URL по короткому имени.
+func (sr *SQLiteRepo) GetByShortName(ctx context.Context, user *models.User, name string) (string, bool, error) {
+
+	var originalURL string
+	var isDeletedURL bool
+	var row *sql.Row
+
+	if user == nil {
+		row = sr.db.QueryRowContext(ctx, "SELECT original_url, is_deleted FROM short_url WHERE short_key = ?", name)
+	} else {
+		row = sr.db.QueryRowContext(ctx, "SELECT original_url, is_deleted FROM short_url WHERE user_id = ? AND short_key = ?", user.ID, name)
+	}
+
+	err := row.Scan(&originalURL, &isDeletedURL)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return ``, false, err
+		}
+
+		return ``, false, nil
+	}
+
+	return originalURL, isDeletedURL, nil
+}
+
+// IsReady Готовность репозитория.
+func (sr *SQLiteRepo) IsReady(ctx context.Context) bool {
+	err := sr.db.PingContext(ctx)
+	return err == nil
+}
+
+// RemoveByOriginalURL - удалить URL.
+func (sr *SQLiteRepo) RemoveByOriginalURL(ctx context.Context, user *models.User, originalURL string) error {
+	_, err := sr.db.ExecContext(ctx, "DELETE FROM short_url WHERE user_id = ? AND original_url = ?", user.ID, originalURL)
+
+	return err
+}
+
+// GetAll получить все URL пользователя.
+func (sr *SQLiteRepo) GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error) {
+	rows, err := sr.db.QueryContext(ctx,
+		"SELECT short_key, original_url, input_url, title, notes, "+sqliteTagsColumn+" FROM short_url WHERE user_id = ?",
+		user.ID,
+	)
+
+	if err != nil {
+		return streamError(err)
+	}
+
+	out := make(chan models.HistoryEl)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(errCh)
+		defer close(out)
+		defer rows.Close()
+
+		for rows.Next() {
+			var el models.HistoryEl
+			var tags string
+
+			if err := rows.Scan(&el.ShortURL, &el.OriginalURL, &el.InputURL, &el.Title, &el.Notes, &tags); err != nil {
+				errCh <- err
+				return
+			}
+
+			if el.Tags, err = decodeSQLiteTags(tags); err != nil {
+				errCh <- err
+				return
+			}
+
+			select {
+			case out <- el:
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			errCh <- err
+		}
+	}()
+
+	return out, errCh
+}
+
+// RemoveBatch - массовое удаление URL.
+func (sr *SQLiteRepo) RemoveBatch(ctx context.Context, user *models.User, list []string) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(list)+1)
+	args = append(args, user.ID)
+
+	for _, id := range list {
+		args = append(args, id)
+	}
+
+	_, err := sr.db.ExecContext(ctx,
+		"UPDATE short_url SET is_deleted = true WHERE user_id = ? AND short_key IN (?"+strings.Repeat(`, ?`, len(list)-1)+")",
+		args...,
+	)
+
+	return err
+}
+
+// MoveUserURLs перенос URL между пользователями.
+func (sr *SQLiteRepo) MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM transfer_token WHERE expires_at < @now`, sql.Named("now", time.Now().Unix()))
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO transfer_token (id, expires_at) VALUES (@id, @expiresAt) ON CONFLICT (id) DO NOTHING`,
+		sql.Named("id", token.ID), sql.Named("expiresAt", token.ExpiresAt.Unix()),
+	)
+
+	if err != nil {
+		return 0, err
+	}
+
+	inserted, err := isSQLiteAffected(res)
+	if err != nil {
+		return 0, err
+	}
+
+	if !inserted {
+		return 0, ErrTransferTokenUsed
+	}
+
+	moved := int64(0)
+
+	if token.From.ID != to.ID {
+		res, err = tx.ExecContext(ctx, `
+			UPDATE short_url SET user_id = @toUserId
+			WHERE user_id = @fromUserId AND NOT EXISTS (
+				SELECT 1 FROM short_url t WHERE t.user_id = @toUserId AND t.original_url = short_url.original_url
+			)`,
+			sql.Named("fromUserId", token.From.ID), sql.Named("toUserId", to.ID),
+		)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if moved, err = res.RowsAffected(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(moved), nil
+}
+
+// GetURLInfo получить URL по короткому ключу независимо от владельца.
+func (sr *SQLiteRepo) GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+	row := sr.db.QueryRowContext(ctx, "SELECT "+sqliteURLInfoColumns+" FROM short_url WHERE short_key = ?", shortKey)
+
+	info, err := scanSQLiteURLInfo(row)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	return info, nil
+}
+
+// SetBlocked блокировка URL.
+func (sr *SQLiteRepo) SetBlocked(ctx context.Context, shortKey string, blocked bool, reason string) (bool, error) {
+	if !blocked {
+		reason = ``
+	}
+
+	res, err := sr.db.ExecContext(ctx, "UPDATE short_url SET is_blocked = ?, block_reason = ? WHERE short_key = ?", blocked, reason, shortKey)
+	if err != nil {
+		return false, err
+	}
+
+	return isSQLiteAffected(res)
+}
+
+// GetUserURLs получить все URL пользователя.
+func (sr *SQLiteRepo) GetUserURLs(ctx context.Context, user *models.User) ([]models.URLInfo, error) {
+	rows, err := sr.db.QueryContext(ctx, "SELECT "+sqliteURLInfoColumns+" FROM short_url WHERE user_id = ?", user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	res := make([]models.URLInfo, 0)
+
+	for rows.Next() {
+		info, err := scanSQLiteURLInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, *info)
+	}
+
+	return res, rows.Err()
+}
+
+// PurgeUser удаление всех URL пользователя.
+func (sr *SQLiteRepo) PurgeUser(ctx context.Context, user *models.User) (int, error) {
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	// Вместе с историей изменений исходных URL и тегами
+	for _, table := range []string{`short_url_history`, `short_url_tag`} {
+		_, err = tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE short_key IN (SELECT short_key FROM short_url WHERE user_id = ?)", user.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM short_url WHERE user_id = ?", user.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(removed), tx.Commit()
+}
+
+// SetRedirectSettings изменение настроек перенаправления URL пользователя.
+func (sr *SQLiteRepo) SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+	utmParams := settings.UTMParams
+	if utmParams == nil {
+		utmParams = map[string]string{}
+	}
+
+	rawUTM, err := json.Marshal(utmParams)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := sr.db.ExecContext(ctx, `
+		UPDATE short_url SET redirect_status = ?, pass_query = ?, utm_params = ?, interstitial = ?
+		WHERE user_id = ? AND short_key = ?`,
+		settings.StatusCode, settings.PassQuery, string(rawUTM), settings.Interstitial, user.ID, shortKey,
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	return isSQLiteAffected(res)
+}
+
+// AddClick учет перехода по URL.
+func (sr *SQLiteRepo) AddClick(ctx context.Context, shortKey string) error {
+	_, err := sr.db.ExecContext(ctx, "UPDATE short_url SET clicks = clicks + 1 WHERE short_key = ?", shortKey)
+
+	return err
+}
+
+// UpdateURL изменение исходного URL.
+func (sr *SQLiteRepo) UpdateURL(ctx context.Context, user *models.User, shortKey string, name string, inputURL string) (bool, error) {
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, "SELECT original_url FROM short_url WHERE user_id = ? AND short_key = ?", user.ID, shortKey)
+
+	var previousURL string
+	if err = row.Scan(&previousURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE short_url SET original_url = ?, input_url = ? WHERE user_id = ? AND short_key = ?",
+		name, inputURL, user.ID, shortKey,
+	)
+
+	if err != nil {
+		if !isSQLiteUniqueErr(err) {
+			return false, err
+		}
+
+		existsKey, err := sqliteUserKey(ctx, tx, user.ID, name)
+		if err != nil {
+			return false, err
+		}
+
+		return false, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: existsKey}
+	}
+
+	if previousURL != name {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO short_url_history (short_key, previous_url, new_url, changed_at) VALUES (?, ?, ?, ?)",
+			shortKey, previousURL, name, time.Now(),
+		)
+
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// GetURLHistory получение истории изменений исходного URL.
+func (sr *SQLiteRepo) GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+	rows, err := sr.db.QueryContext(ctx, `
+		SELECT h.previous_url, h.new_url, h.changed_at FROM short_url_history h
+		JOIN short_url s ON s.short_key = h.short_key
+		WHERE s.user_id = ? AND h.short_key = ? ORDER BY h.id`,
+		user.ID, shortKey,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var res []models.DestinationChange
+
+	for rows.Next() {
+		var el models.DestinationChange
+		if err = rows.Scan(&el.PreviousURL, &el.NewURL, &el.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		res = append(res, el)
+	}
+
+	return res, rows.Err()
+}
+
+// SetURLMeta изменение метаданных URL.
+func (sr *SQLiteRepo) SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE short_url SET title = ?, notes = ? WHERE user_id = ? AND short_key = ?",
+		meta.Title, meta.Notes, user.ID, shortKey,
+	)
+
+	if err != nil {
+		return false, err
+	}
+
+	if found, err := isSQLiteAffected(res); err != nil || !found {
+		return false, err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM short_url_tag WHERE short_key = ?", shortKey); err != nil {
+		return false, err
+	}
+
+	for _, tag := range meta.Tags {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO short_url_tag (short_key, tag) VALUES (?, ?)", shortKey, tag); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// Close завершение работы с репозиторием
+func (sr *SQLiteRepo) Close() {
+	err := sr.db.Close()
+	if err != nil {
+		logger.Error(`error while closing sqlite database`, err)
+	}
+}
+
+// sqliteQuerier - подключение или транзакция SQLite.
+type sqliteQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteUserKey короткий ключ исходного URL name пользователя userID, пустая строка, если URL не найден.
+func sqliteUserKey(ctx context.Context, q sqliteQuerier, userID string, name string) (string, error) {
+	var shortKey string
+
+	err := q.QueryRowContext(ctx, "SELECT short_key FROM short_url WHERE user_id = ? AND original_url = ?", userID, name).Scan(&shortKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ``, nil
+	}
+
+	return shortKey, err
+}
+
+// scanSQLiteURLInfo чтение строки с колонками sqliteURLInfoColumns.
+func scanSQLiteURLInfo(row interface{ Scan(dest ...any) error }) (*models.URLInfo, error) {
+	var info models.URLInfo
+	var utmParams, tags string
+
+	err := row.Scan(&info.ShortKey, &info.OriginalURL, &info.InputURL, &info.UserID, &info.IsDeleted, &info.IsBlocked, &info.BlockReason,
+		&info.Redirect.StatusCode, &info.Redirect.PassQuery, &utmParams, &info.Redirect.Interstitial, &info.CreatedAt, &info.Clicks, &info.Title, &info.Notes, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal([]byte(utmParams), &info.Redirect.UTMParams); err != nil {
+		return nil, err
+	}
+
+	if info.Tags, err = decodeSQLiteTags(tags); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// decodeSQLiteTags разбор JSON массива тегов, nil для пустого массива.
+func decodeSQLiteTags(data string) ([]string, error) {
+	var tags []string
+	if err := json.Unmarshal([]byte(data), &tags); err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	return tags, nil
+}
+
+// isSQLiteAffected проверка, что запрос изменил хотя бы одну строку.
+func isSQLiteAffected(res sql.Result) (bool, error) {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// isSQLiteUniqueErr проверка нарушения уникальности.
+func isSQLiteUniqueErr(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// createSQLiteSchema создание схемы SQLite, совпадающей со схемой PostgreSQL.
+func createSQLiteSchema(ctx context.Context, db *sql.DB) error {
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS short_url (
+		    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		    user_id varchar(36) NOT NULL,
+		    short_key varchar(`+strconv.Itoa(urlhasher.HashLength)+`) UNIQUE NOT NULL,
+		    original_url text NOT NULL,
+		    is_deleted boolean NOT NULL DEFAULT false,
+		    is_blocked boolean NOT NULL DEFAULT false,
+		    block_reason text NOT NULL DEFAULT '',
+		    input_url text NOT NULL DEFAULT '',
+		    redirect_status smallint NOT NULL DEFAULT 0,
+		    pass_query boolean NOT NULL DEFAULT false,
+		    utm_params text NOT NULL DEFAULT '{}',
+		    interstitial boolean NOT NULL DEFAULT false,
+		    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		    clicks bigint NOT NULL DEFAULT 0,
+		    title text NOT NULL DEFAULT '',
+		    notes text NOT NULL DEFAULT ''
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS short_url_user_id_original_url_unique_idx ON short_url (user_id, original_url);
+		CREATE INDEX IF NOT EXISTS short_url_user_id_idx ON short_url (user_id);
+
+		CREATE TABLE IF NOT EXISTS short_url_history (
+		    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		    short_key varchar(`+strconv.Itoa(urlhasher.HashLength)+`) NOT NULL,
+		    previous_url text NOT NULL,
+		    new_url text NOT NULL,
+		    changed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS short_url_history_short_key_idx ON short_url_history (short_key);
+
+		CREATE TABLE IF NOT EXISTS short_url_tag (
+		    short_key varchar(`+strconv.Itoa(urlhasher.HashLength)+`) NOT NULL,
+		    tag text NOT NULL,
+		    PRIMARY KEY (short_key, tag)
+		);
+
+		CREATE INDEX IF NOT EXISTS short_url_tag_tag_idx ON short_url_tag (tag);
+
+		CREATE TABLE IF NOT EXISTS transfer_token (
+		    id varchar(36) NOT NULL PRIMARY KEY,
+		    expires_at bigint NOT NULL
+		);
+	`)
+
+	return err
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+	"github.com/Alheor/shorturl/internal/userauth"
+
+	"go.uber.org/zap"
+)
+
+// Действия администратора для журнала аудита.
+const (
+	auditActionGetURL    = `get_url`
+	auditActionBlock     = `block_url`
+	auditActionUnblock   = `unblock_url`
+	auditActionUserURLs  = `get_user_urls`
+	auditActionPurgeUser = `purge_user`
+	auditActionKeyUsage  = `get_key_usage`
+)
+
+// GetURLInfo Получение информации об URL по короткому ключу независимо от владельца.
+func GetURLInfo(ctx context.Context, shortKey string) (*models.URLInfo, error) {
+	info, err := repository.GetRepository().GetURLInfo(ctx, shortKey)
+	if err != nil {
+		logger.Error(`get url info error: `, err)
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// GetUserURLInfo Получение информации об URL пользователя. Если URL не найден или принадлежит другому пользователю, возвращается nil.
+func GetUserURLInfo(ctx context.Context, user *models.User, shortKey string) (*models.URLInfo, error) {
+	info, err := GetURLInfo(ctx, shortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if info == nil || info.UserID != user.ID {
+		return nil, nil
+	}
+
+	return info, nil
+}
+
+// AdminGetURL Получение информации об URL администратором.
+func AdminGetURL(ctx context.Context, actor string, shortKey string) (*models.URLInfo, error) {
+	info, err := GetURLInfo(ctx, shortKey)
+	audit(actor, auditActionGetURL, err, zap.String("short_key", shortKey), zap.Bool("found", info != nil))
+
+	return info, err
+}
+
+// BlockURL Блокировка URL администратором. Возвращает false, если URL не найден.
+func BlockURL(ctx context.Context, actor string, shortKey string, reason string) (bool, error) {
+	found, err := repository.GetRepository().SetBlocked(ctx, shortKey, true, reason)
+	audit(actor, auditActionBlock, err, zap.String("short_key", shortKey), zap.String("reason", reason), zap.Bool("found", found))
+
+	return found, err
+}
+
+// UnblockURL Снятие блокировки URL администратором. Возвращает false, если URL не найден.
+func UnblockURL(ctx context.Context, actor string, shortKey string) (bool, error) {
+	found, err := repository.GetRepository().SetBlocked(ctx, shortKey, false, ``)
+	audit(actor, auditActionUnblock, err, zap.String("short_key", shortKey), zap.Bool("found", found))
+
+	return found, err
+}
+
+// AdminGetUserURLs Получение всех URL пользователя администратором.
+func AdminGetUserURLs(ctx context.Context, actor string, user *models.User) ([]models.URLInfo, error) {
+	list, err := repository.GetRepository().GetUserURLs(ctx, user)
+	audit(actor, auditActionUserURLs, err, zap.String("user_id", user.ID), zap.Int("count", len(list)))
+
+	return list, err
+}
+
+// PurgeUser Удаление всех URL пользователя администратором.
+func PurgeUser(ctx context.Context, actor string, user *models.User) (int, error) {
+	removed, err := repository.GetRepository().PurgeUser(ctx, user)
+	audit(actor, auditActionPurgeUser, err, zap.String("user_id", user.ID), zap.Int("removed", removed))
+
+	return removed, err
+}
+
+// AdminGetKeyUsage Получение администратором количества проверок токенов пользователей по ключам подписи.
+// Позволяет понять, когда предыдущий ключ больше не используется и его можно удалить из конфигурации.
+func AdminGetKeyUsage(actor string) models.APIAdminKeyUsageResponse {
+	res := userauth.GetConfiguredKeyUsage()
+	audit(actor, auditActionKeyUsage, nil)
+
+	return res
+}
+
+// audit запись действия администратора в журнал аудита.
+func audit(actor string, action string, err error, fields ...zap.Field) {
+	fields = append([]zap.Field{zap.String("actor", actor), zap.String("action", action)}, fields...)
+
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+
+	logger.Info(`admin audit`, fields...)
+}
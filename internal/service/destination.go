@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+)
+
+// UpdateURL Изменение исходного URL сокращенного URL пользователя. Возвращает false, если URL не найден,
+// и models.UniqueErr, если у пользователя уже есть другой сокращенный URL с таким исходным URL.
+func UpdateURL(ctx context.Context, user *models.User, shortKey string, URL string) (bool, error) {
+	name, inputURL := canonical(URL)
+
+	found, err := repository.GetRepository().UpdateURL(ctx, user, shortKey, name, inputURL)
+	if err != nil {
+		var uniqErr *models.UniqueErr
+		if !errors.As(err, &uniqErr) {
+			logger.Error(`update url error: `, err)
+		}
+
+		return false, err
+	}
+
+	return found, nil
+}
+
+// GetURLHistory Получение истории изменений исходного URL сокращенного URL пользователя.
+func GetURLHistory(ctx context.Context, user *models.User, shortKey string) ([]models.DestinationChange, error) {
+	history, err := repository.GetRepository().GetURLHistory(ctx, user, shortKey)
+	if err != nil {
+		logger.Error(`get url history error: `, err)
+		return nil, err
+	}
+
+	if history == nil {
+		history = []models.DestinationChange{}
+	}
+
+	return history, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAfterUpdateURL(t *testing.T) {
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	Init(&cfg)
+
+	user := &models.User{ID: `6a30af51-b6ac-63ba-9e1c-5da06e1b610e`}
+	targetURL := `https://example.com/old`
+
+	shortKey, err := Add(ctx, user, targetURL)
+	require.NoError(t, err)
+
+	found, err := UpdateURL(ctx, user, shortKey, `https://example.com/new`)
+	require.NoError(t, err)
+	require.True(t, found)
+
+	// Ключ исходного URL занят измененным URL, выдается другой ключ
+	newKey, err := Add(ctx, user, targetURL)
+	require.NoError(t, err)
+	assert.NotEqual(t, shortKey, newKey)
+
+	URL, _ := Get(ctx, user, newKey)
+	assert.Equal(t, targetURL, URL)
+
+	URL, _ = Get(ctx, user, shortKey)
+	assert.Equal(t, `https://example.com/new`, URL)
+
+	_, err = Add(ctx, user, targetURL)
+	var uniqErr *models.UniqueErr
+	require.ErrorAs(t, err, &uniqErr)
+	assert.Equal(t, newKey, uniqErr.ShortKey)
+
+	// Пакетное добавление
+	batchKey, err := Add(ctx, user, targetURL+`/batch`)
+	require.NoError(t, err)
+
+	_, err = UpdateURL(ctx, user, batchKey, `https://example.com/batch`)
+	require.NoError(t, err)
+
+	list, err := AddBatch(ctx, user, []models.APIBatchRequestEl{
+		{CorrelationID: `1`, OriginalURL: targetURL + `/batch`},
+		{CorrelationID: `2`, OriginalURL: targetURL},
+	})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	assert.NotEqual(t, cfg.BaseHost+`/`+batchKey, list[0].ShortURL)
+	assert.Equal(t, cfg.BaseHost+`/`+newKey, list[1].ShortURL)
+}
+
+func TestAddKeyTakenByOtherUser(t *testing.T) {
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	Init(&cfg)
+
+	owner := &models.User{ID: `6a30af51-b6ac-63ba-9e1c-5da06e1b610e`}
+	other := &models.User{ID: `7b41bf62-c7bd-74cb-af2d-6eb17f2c721f`}
+
+	shortKey, err := Add(ctx, owner, `https://example.com/old`)
+	require.NoError(t, err)
+
+	_, err = UpdateURL(ctx, owner, shortKey, `https://example.com/new`)
+	require.NoError(t, err)
+
+	otherKey, err := Add(ctx, other, `https://example.com/old`)
+	require.NoError(t, err)
+	assert.NotEmpty(t, otherKey)
+
+	URL, _ := Get(ctx, other, otherKey)
+	assert.Equal(t, `https://example.com/old`, URL)
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+)
+
+// ImportChunkSize - количество строк импорта, добавляемых за одно обращение к репозиторию.
+const ImportChunkSize = 100
+
+// ImportURLs Импорт части строк URL пользователя. Строки добавляются через AddBatchEach, результат возвращается для каждой строки.
+// Ошибка репозитория возвращается для всей части.
+//
+// Сокращенные ключи формируются из URL, а срок действия URL не поддерживается, поэтому строки с колонкой expires_at
+// и строки с alias, отличным от ключа URL, не импортируются. Alias, совпадающий с ключом URL (например, в файле,
+// полученном экспортом), принимается, если под ним сохранен тот же URL пользователя или ключ свободен.
+func ImportURLs(ctx context.Context, user *models.User, rows []models.ImportRow) ([]models.APIImportRowResult, error) {
+
+	results := make([]models.APIImportRowResult, len(rows))
+	batch := make([]models.APIBatchRequestEl, 0, len(rows))
+
+	for i := range rows {
+		row := &rows[i]
+		results[i].Line = row.Line
+
+		err := validateImportRow(row)
+		if err == nil {
+			err = checkImportAlias(ctx, user, row)
+		}
+
+		if err != nil {
+			results[i].Status = models.ImportStatusError
+			results[i].Error = err.Error()
+			continue
+		}
+
+		batch = append(batch, models.APIBatchRequestEl{CorrelationID: strconv.Itoa(i), OriginalURL: row.URL, URLMeta: row.Meta})
+	}
+
+	if len(batch) == 0 {
+		return results, nil
+	}
+
+	added, err := AddBatchEach(ctx, user, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, el := range added {
+		i, _ := strconv.Atoi(el.CorrelationID)
+
+		results[i].ShortURL = el.ShortURL
+		results[i].Error = el.Error
+
+		switch el.Status {
+		case models.BatchStatusCreated:
+			results[i].Status = models.ImportStatusCreated
+		case models.BatchStatusExists:
+			results[i].Status = models.ImportStatusExists
+		default:
+			results[i].Status = models.ImportStatusError
+			continue
+		}
+
+		// URL пользователя уже сокращен с другим ключом
+		if shortKey := strings.TrimPrefix(el.ShortURL, settings.Load().baseHost+`/`); rows[i].Alias != `` && rows[i].Alias != shortKey {
+			results[i].Status = models.ImportStatusError
+			results[i].ShortURL = ``
+			results[i].Error = `url is already shortened with short key "` + shortKey + `"`
+		}
+	}
+
+	return results, nil
+}
+
+// checkImportAlias проверка колонок строки импорта, которые нельзя применить.
+func checkImportAlias(ctx context.Context, user *models.User, row *models.ImportRow) error {
+	if row.ExpiresAt != `` {
+		return errors.New(`expires_at is not supported, url expiration is not implemented`)
+	}
+
+	if row.Alias == `` {
+		return nil
+	}
+
+	name, _ := canonical(row.URL)
+
+	info, err := repository.GetRepository().GetURLInfo(ctx, row.Alias)
+	if err != nil {
+		logger.Error(`get url info error: `, err)
+		return errors.New(`internal error`)
+	}
+
+	// URL пользователя уже сохранен под этим ключом
+	if info != nil && info.UserID == user.ID && info.OriginalURL == name {
+		return nil
+	}
+
+	if row.Alias != shortKeyFor(name, 0) {
+		return errors.New(`alias "` + row.Alias + `" is not supported, short key is generated from the url`)
+	}
+
+	if info != nil {
+		return errors.New(`alias "` + row.Alias + `" is in use`)
+	}
+
+	return nil
+}
+
+// validateImportRow проверка строки импорта и нормализация ее метаданных.
+func validateImportRow(row *models.ImportRow) error {
+	if row.URL == `` {
+		return errors.New(`url required`)
+	}
+
+	return normalizeMeta(&row.Meta)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+	"github.com/Alheor/shorturl/internal/urlhasher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportURLs(t *testing.T) {
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	Init(&cfg)
+
+	user := &models.User{ID: `7b41bf62-c7bd-74cb-af2d-6eb17f2c721f`}
+
+	rows := []models.ImportRow{
+		{Line: 2, URL: `https://example.com/import1`, Meta: models.URLMeta{Tags: []string{`Docs`}}},
+		{Line: 3, URL: `https://example.com/import2`, Alias: urlhasher.GetHash(`https://example.com/import2`)},
+		{Line: 4, URL: `https://example.com/import3`, Alias: `custom`},
+		{Line: 5, URL: `https://example.com/import4`, ExpiresAt: `2030-01-01`},
+		{Line: 6, URL: `https://example.com/import5`, Meta: models.URLMeta{Tags: []string{`bad tag`}}},
+		{Line: 7},
+	}
+
+	results, err := ImportURLs(ctx, user, rows)
+	require.NoError(t, err)
+	require.Len(t, results, len(rows))
+
+	for i, status := range []string{
+		models.ImportStatusCreated,
+		models.ImportStatusCreated,
+		models.ImportStatusError,
+		models.ImportStatusError,
+		models.ImportStatusError,
+		models.ImportStatusError,
+	} {
+		assert.Equal(t, rows[i].Line, results[i].Line)
+		assert.Equal(t, status, results[i].Status, results[i].Error)
+	}
+
+	assert.Equal(t, cfg.BaseHost+`/`+urlhasher.GetHash(`https://example.com/import1`), results[0].ShortURL)
+	assert.Equal(t, `url required`, results[5].Error)
+
+	// Строки с alias, отличным от ключа URL, и с expires_at не импортируются
+	assert.Equal(t, cfg.BaseHost+`/`+urlhasher.GetHash(`https://example.com/import2`), results[1].ShortURL)
+	assert.Empty(t, results[2].ShortURL)
+	assert.Contains(t, results[2].Error, `alias "custom" is not supported`)
+	assert.Contains(t, results[3].Error, `expires_at is not supported`)
+
+	// Alias, занятый другим пользователем
+	other := &models.User{ID: `8c52cf73-d8ce-85dc-bf3f-7fc28a3d832a`}
+	results, err = ImportURLs(ctx, other, []models.ImportRow{
+		{Line: 2, URL: `https://example.com/import1`, Alias: urlhasher.GetHash(`https://example.com/import1`)},
+		{Line: 3, URL: `https://example.com/import2`, Alias: urlhasher.GetHash(`https://example.com/import2`)},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, models.ImportStatusError, results[0].Status)
+	assert.Contains(t, results[0].Error, `is in use`)
+
+	// Тот же URL пользователя под тем же ключом
+	results, err = ImportURLs(ctx, user, rows[1:2])
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, models.ImportStatusExists, results[0].Status)
+
+	chList, chErr := GetAll(ctx, user)
+
+	var list []models.HistoryEl
+	for el := range chList {
+		list = append(list, el)
+	}
+
+	for err := range chErr {
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, list, 2)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+)
+
+// Ограничения метаданных URL.
+const (
+	// maxTitleLength - максимальная длина названия в символах.
+	maxTitleLength = 200
+
+	// maxNotesLength - максимальная длина заметок в символах.
+	maxNotesLength = 2000
+
+	// maxTags - максимальное количество тегов.
+	maxTags = 20
+
+	// maxTagLength - максимальная длина тега в символах.
+	maxTagLength = 50
+)
+
+// NormalizeTag Приведение тега к виду, в котором он хранится: без пробелов по краям и в нижнем регистре.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// MergeURLMeta Применение изменений метаданных из запроса к текущим метаданным с проверкой результата.
+func MergeURLMeta(current models.URLMeta, req *models.APIUpdateURLRequest) (*models.URLMeta, error) {
+	meta := current
+
+	if req.Title != nil {
+		meta.Title = *req.Title
+	}
+
+	if req.Notes != nil {
+		meta.Notes = *req.Notes
+	}
+
+	if req.Tags != nil {
+		meta.Tags = *req.Tags
+	}
+
+	if err := normalizeMeta(&meta); err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
+
+// SetURLMeta Изменение метаданных URL пользователя. Возвращает false, если URL не найден.
+func SetURLMeta(ctx context.Context, user *models.User, shortKey string, meta *models.URLMeta) (bool, error) {
+	if err := normalizeMeta(meta); err != nil {
+		return false, err
+	}
+
+	found, err := repository.GetRepository().SetURLMeta(ctx, user, shortKey, meta)
+	if err != nil {
+		logger.Error(`set url meta error: `, err)
+		return false, err
+	}
+
+	return found, nil
+}
+
+// normalizeMeta проверка метаданных и приведение тегов к единому виду: без повторов и по алфавиту.
+func normalizeMeta(meta *models.URLMeta) error {
+	meta.Title = strings.TrimSpace(meta.Title)
+	meta.Notes = strings.TrimSpace(meta.Notes)
+
+	if utf8.RuneCountInString(meta.Title) > maxTitleLength {
+		return &models.URLMetaErr{Err: errors.New(`title is longer than ` + strconv.Itoa(maxTitleLength) + ` characters`)}
+	}
+
+	if utf8.RuneCountInString(meta.Notes) > maxNotesLength {
+		return &models.URLMetaErr{Err: errors.New(`notes are longer than ` + strconv.Itoa(maxNotesLength) + ` characters`)}
+	}
+
+	if len(meta.Tags) == 0 {
+		meta.Tags = nil
+		return nil
+	}
+
+	unique := make(map[string]struct{}, len(meta.Tags))
+	tags := make([]string, 0, len(meta.Tags))
+
+	for _, tag := range meta.Tags {
+		tag = NormalizeTag(tag)
+
+		if err := validateTag(tag); err != nil {
+			return err
+		}
+
+		if _, exists := unique[tag]; exists {
+			continue
+		}
+
+		unique[tag] = struct{}{}
+		tags = append(tags, tag)
+	}
+
+	if len(tags) > maxTags {
+		return &models.URLMetaErr{Err: errors.New(`too many tags, max ` + strconv.Itoa(maxTags))}
+	}
+
+	sort.Strings(tags)
+	meta.Tags = tags
+
+	return nil
+}
+
+// validateTag проверка тега: буквы, цифры, дефис и подчеркивание.
+func validateTag(tag string) error {
+	if tag == `` || utf8.RuneCountInString(tag) > maxTagLength {
+		return &models.URLMetaErr{Err: errors.New(`tag length must be between 1 and ` + strconv.Itoa(maxTagLength) + ` characters`)}
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return &models.URLMetaErr{Err: errors.New(`tag "` + tag + `" contains invalid characters`)}
+		}
+	}
+
+	return nil
+}
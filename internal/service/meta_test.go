@@ -0,0 +1,90 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeMeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		meta    models.URLMeta
+		want    models.URLMeta
+		wantErr bool
+	}{
+		{
+			name: `empty meta`,
+			meta: models.URLMeta{Tags: []string{}},
+			want: models.URLMeta{},
+		},
+		{
+			name: `trim, lowercase, deduplicate and sort tags`,
+			meta: models.URLMeta{Title: ` Campaign `, Notes: "print\n", Tags: []string{`Spring`, ` print `, `spring`, `qr_code`}},
+			want: models.URLMeta{Title: `Campaign`, Notes: `print`, Tags: []string{`print`, `qr_code`, `spring`}},
+		},
+		{
+			name:    `title too long`,
+			meta:    models.URLMeta{Title: strings.Repeat(`a`, maxTitleLength+1)},
+			wantErr: true,
+		},
+		{
+			name:    `notes too long`,
+			meta:    models.URLMeta{Notes: strings.Repeat(`a`, maxNotesLength+1)},
+			wantErr: true,
+		},
+		{
+			name:    `empty tag`,
+			meta:    models.URLMeta{Tags: []string{` `}},
+			wantErr: true,
+		},
+		{
+			name:    `tag with invalid characters`,
+			meta:    models.URLMeta{Tags: []string{`a,b`}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeMeta(&tt.meta)
+			if tt.wantErr {
+				var metaErr *models.URLMetaErr
+				assert.ErrorAs(t, err, &metaErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tt.meta)
+		})
+	}
+}
+
+func TestNormalizeMetaTooManyTags(t *testing.T) {
+	meta := models.URLMeta{}
+	for i := 0; i <= maxTags; i++ {
+		meta.Tags = append(meta.Tags, strings.Repeat(`t`, i+1))
+	}
+
+	var metaErr *models.URLMetaErr
+	assert.ErrorAs(t, normalizeMeta(&meta), &metaErr)
+}
+
+func TestMergeURLMeta(t *testing.T) {
+	current := models.URLMeta{Title: `old`, Notes: `notes`, Tags: []string{`a`}}
+	title := `New`
+	tags := []string{`B`}
+
+	meta, err := MergeURLMeta(current, &models.APIUpdateURLRequest{Title: &title, Tags: &tags})
+	require.NoError(t, err)
+	assert.Equal(t, &models.URLMeta{Title: `New`, Notes: `notes`, Tags: []string{`b`}}, meta)
+
+	empty := []string{}
+	meta, err = MergeURLMeta(current, &models.APIUpdateURLRequest{Tags: &empty})
+	require.NoError(t, err)
+	assert.Equal(t, &models.URLMeta{Title: `old`, Notes: `notes`}, meta)
+}
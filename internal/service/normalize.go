@@ -0,0 +1,97 @@
+package service
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// defaultTrackingParams - параметры отслеживания, удаляемые из URL по умолчанию.
+var defaultTrackingParams = []string{`utm_*`, `fbclid`}
+
+// defaultPorts - порты по умолчанию для схем URL.
+var defaultPorts = map[string]string{
+	`http`:  `80`,
+	`https`: `443`,
+}
+
+// normalizeURL приведение URL к каноническому виду: схема и хост в нижнем регистре, хост в punycode,
+// без порта по умолчанию, фрагмента и параметров отслеживания, параметры запроса отсортированы.
+// Если нормализация выключена или URL не удалось разобрать, то возвращается исходный URL.
+func normalizeURL(rawURL string) string {
+	if !settings.Load().normalize {
+		return rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == `` {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := u.Hostname()
+	if asciiHost, err := idna.Lookup.ToASCII(host); err == nil {
+		host = asciiHost
+	}
+
+	host = strings.ToLower(host)
+	port := u.Port()
+
+	if port == defaultPorts[u.Scheme] {
+		port = ``
+	}
+
+	if strings.Contains(host, `:`) {
+		host = `[` + host + `]`
+	}
+
+	if port != `` {
+		host = net.JoinHostPort(strings.Trim(host, `[]`), port)
+	}
+
+	u.Host = host
+	u.Fragment = ``
+	u.RawFragment = ``
+
+	if u.RawQuery != `` {
+		query, err := url.ParseQuery(u.RawQuery)
+		if err == nil {
+			for name := range query {
+				if isTrackingParam(name) {
+					query.Del(name)
+				}
+			}
+
+			// Encode сортирует параметры по имени
+			u.RawQuery = query.Encode()
+		}
+	}
+
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// isTrackingParam является ли параметр запроса параметром отслеживания.
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, pattern := range settings.Load().trackingParams {
+		if prefix, isWildcard := strings.CutSuffix(pattern, `*`); isWildcard {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+
+			continue
+		}
+
+		if name == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+	"github.com/Alheor/shorturl/internal/repository"
+)
+
+// DefaultRedirectStatus - код ответа при перенаправлении по умолчанию.
+const DefaultRedirectStatus = http.StatusTemporaryRedirect
+
+// utmParamPrefix - префикс UTM параметров.
+const utmParamPrefix = `utm_`
+
+// allowedRedirectStatuses - допустимые коды ответа при перенаправлении.
+var allowedRedirectStatuses = map[int]struct{}{
+	http.StatusMovedPermanently:  {},
+	http.StatusFound:             {},
+	http.StatusTemporaryRedirect: {},
+	http.StatusPermanentRedirect: {},
+}
+
+// GetRedirectSettings Получение настроек перенаправления URL пользователя. Если URL не найден, возвращается nil.
+func GetRedirectSettings(ctx context.Context, user *models.User, shortKey string) (*models.RedirectSettings, error) {
+	info, err := GetUserURLInfo(ctx, user, shortKey)
+	if err != nil || info == nil {
+		return nil, err
+	}
+
+	return &info.Redirect, nil
+}
+
+// SetRedirectSettings Изменение настроек перенаправления URL пользователя. Возвращает false, если URL не найден.
+func SetRedirectSettings(ctx context.Context, user *models.User, shortKey string, settings *models.RedirectSettings) (bool, error) {
+	if err := validateRedirectSettings(settings); err != nil {
+		return false, err
+	}
+
+	found, err := repository.GetRepository().SetRedirectSettings(ctx, user, shortKey, settings)
+	if err != nil {
+		logger.Error(`set redirect settings error: `, err)
+		return false, err
+	}
+
+	return found, nil
+}
+
+// AddClick Учет перехода по сокращенному URL. Ошибка учета не должна мешать перенаправлению, поэтому только логируется.
+func AddClick(ctx context.Context, shortKey string) {
+	err := repository.GetRepository().AddClick(ctx, shortKey)
+	if err != nil {
+		logger.Error(`add click error: `, err)
+	}
+}
+
+// RedirectStatus Код ответа при перенаправлении.
+func RedirectStatus(settings *models.RedirectSettings) int {
+	if settings.StatusCode == 0 {
+		return DefaultRedirectStatus
+	}
+
+	return settings.StatusCode
+}
+
+// RedirectLocation Адрес перенаправления с учетом настроек: параметры запроса сокращенного URL rawQuery
+// добавляются к исходному URL, если это разрешено, UTM параметры заменяют одноименные параметры исходного URL.
+func RedirectLocation(info *models.URLInfo, rawQuery string) string {
+	passQuery := info.Redirect.PassQuery && rawQuery != ``
+
+	if !passQuery && len(info.Redirect.UTMParams) == 0 {
+		return info.OriginalURL
+	}
+
+	target, err := url.Parse(info.OriginalURL)
+	if err != nil {
+		return info.OriginalURL
+	}
+
+	query := target.Query()
+
+	if passQuery {
+		incoming, err := url.ParseQuery(rawQuery)
+		if err == nil {
+			for name, values := range incoming {
+				for _, value := range values {
+					query.Add(name, value)
+				}
+			}
+		}
+	}
+
+	for name, value := range info.Redirect.UTMParams {
+		query.Set(name, value)
+	}
+
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
+
+// validateRedirectSettings проверка настроек перенаправления.
+func validateRedirectSettings(settings *models.RedirectSettings) error {
+	if _, allowed := allowedRedirectStatuses[settings.StatusCode]; settings.StatusCode != 0 && !allowed {
+		return &models.RedirectSettingsErr{Err: errors.New(`status code ` + strconv.Itoa(settings.StatusCode) + ` is not allowed, use 301, 302, 307 or 308`)}
+	}
+
+	for name, value := range settings.UTMParams {
+		if !strings.HasPrefix(name, utmParamPrefix) || len(name) == len(utmParamPrefix) {
+			return &models.RedirectSettingsErr{Err: errors.New(`parameter "` + name + `" is not an UTM parameter`)}
+		}
+
+		if value == `` {
+			return &models.RedirectSettingsErr{Err: errors.New(`parameter "` + name + `" is empty`)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		settings models.RedirectSettings
+		query    string
+		want     string
+	}{
+		{
+			name:  `default settings ignore query`,
+			url:   `https://example.com/path?b=2&a=1`,
+			query: `x=1`,
+			want:  `https://example.com/path?b=2&a=1`,
+		},
+		{
+			name:     `pass query`,
+			url:      `https://example.com/path?a=1`,
+			settings: models.RedirectSettings{PassQuery: true},
+			query:    `x=1&a=2`,
+			want:     `https://example.com/path?a=1&a=2&x=1`,
+		},
+		{
+			name:     `pass empty query`,
+			url:      `https://example.com/path?b=2&a=1`,
+			settings: models.RedirectSettings{PassQuery: true},
+			want:     `https://example.com/path?b=2&a=1`,
+		},
+		{
+			name:     `utm params`,
+			url:      `https://example.com/path?utm_source=old#frag`,
+			settings: models.RedirectSettings{UTMParams: map[string]string{`utm_source`: `short`, `utm_medium`: `link`}},
+			query:    `x=1`,
+			want:     `https://example.com/path?utm_medium=link&utm_source=short#frag`,
+		},
+		{
+			name: `pass query and utm params`,
+			url:  `https://example.com/`,
+			settings: models.RedirectSettings{
+				PassQuery: true,
+				UTMParams: map[string]string{`utm_campaign`: `spring`},
+			},
+			query: `utm_campaign=user&id=5`,
+			want:  `https://example.com/?id=5&utm_campaign=spring`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &models.URLInfo{OriginalURL: tt.url, Redirect: tt.settings}
+			assert.Equal(t, tt.want, RedirectLocation(info, tt.query))
+		})
+	}
+}
+
+func TestRedirectStatus(t *testing.T) {
+	assert.Equal(t, http.StatusTemporaryRedirect, RedirectStatus(&models.RedirectSettings{}))
+	assert.Equal(t, http.StatusMovedPermanently, RedirectStatus(&models.RedirectSettings{StatusCode: http.StatusMovedPermanently}))
+}
+
+func TestValidateRedirectSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.RedirectSettings
+		wantErr  bool
+	}{
+		{name: `default`, settings: models.RedirectSettings{}},
+		{name: `301`, settings: models.RedirectSettings{StatusCode: 301}},
+		{name: `308 with utm`, settings: models.RedirectSettings{StatusCode: 308, UTMParams: map[string]string{`utm_source`: `x`}}},
+		{name: `200`, settings: models.RedirectSettings{StatusCode: 200}, wantErr: true},
+		{name: `303`, settings: models.RedirectSettings{StatusCode: 303}, wantErr: true},
+		{name: `not utm param`, settings: models.RedirectSettings{UTMParams: map[string]string{`ref`: `x`}}, wantErr: true},
+		{name: `utm prefix only`, settings: models.RedirectSettings{UTMParams: map[string]string{`utm_`: `x`}}, wantErr: true},
+		{name: `empty utm value`, settings: models.RedirectSettings{UTMParams: map[string]string{`utm_source`: ``}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRedirectSettings(&tt.settings)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+
+			var settingsErr *models.RedirectSettingsErr
+			assert.ErrorAs(t, err, &settingsErr)
+		})
+	}
+}
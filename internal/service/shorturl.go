@@ -5,20 +5,33 @@
 // Представляет собой набор функций - моделей бизнес процесса.
 //
 // • Добавление 1 URL и получение его сокращенной версии в ответ.
+// Если включена нормализация, то перед сокращением URL приводится к каноническому виду, исходный URL сохраняется для отображения.
 //
 // • Получение 1 URL по сокращенной версии.
 //
-// • Массовое добавление URL и получение их сокращенной версии в ответ.
+// • Массовое добавление URL и получение их сокращенной версии в ответ: целиком, либо с результатом для каждого URL.
 //
 // • Получение всех сокращенных URL.
 //
 // • Массовое удаление URL.
 //
+// • Импорт URL пользователя частями с результатом для каждой строки.
+//
+// • Получение количества сокращенных URL пользователя.
+//
+// • Перенос URL между пользователями.
+//
+// • Модерация URL администратором: поиск, блокировка, удаление пользователя. Действия записываются в журнал аудита.
+//
 // • Проверка работоспособности репозитория.
 package service
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Alheor/shorturl/internal/config"
@@ -26,28 +39,129 @@ import (
 	"github.com/Alheor/shorturl/internal/models"
 	"github.com/Alheor/shorturl/internal/repository"
 	"github.com/Alheor/shorturl/internal/urlhasher"
+	"github.com/Alheor/shorturl/internal/userauth"
+)
+
+// serviceSettings - параметры сервиса. При повторном вызове Init заменяются целиком.
+type serviceSettings struct {
+	baseHost string
+	// normalize - включена ли нормализация URL.
+	normalize bool
+	// trackingParams - удаляемые параметры отслеживания. Шаблон вида utm_* задает префикс параметра.
+	trackingParams []string
+}
+
+// Время сохранения списка URL одним обращением к репозиторию: базовое и дополнительное на каждый URL списка,
+// чтобы атомарное добавление большого списка не прерывалось по таймауту.
+const (
+	batchBaseTimeout = 5 * time.Second
+	batchURLTimeout  = time.Millisecond
 )
 
-var baseHost string
+// maxShortKeyAttempts - количество попыток подобрать свободный короткий ключ, если ключ URL занят.
+const maxShortKeyAttempts = 10
+
+// settings - текущие параметры сервиса.
+var settings atomic.Pointer[serviceSettings]
+
+func init() {
+	settings.Store(&serviceSettings{trackingParams: defaultTrackingParams})
+}
 
-// Init Подготовка сервиса к работе
+// Init Подготовка сервиса к работе. Может вызываться повторно при изменении конфигурации.
 func Init(config *config.Options) {
-	baseHost = config.BaseHost
+	st := &serviceSettings{
+		baseHost:       config.BaseHost,
+		normalize:      config.NormalizeURLs,
+		trackingParams: defaultTrackingParams,
+	}
+
+	if len(config.TrackingParams) > 0 {
+		st.trackingParams = make([]string, 0, len(config.TrackingParams))
+		for _, param := range config.TrackingParams {
+			st.trackingParams = append(st.trackingParams, strings.ToLower(strings.TrimSpace(param)))
+		}
+	}
+
+	settings.Store(st)
 }
 
 // Add Добавление 1 URL и получение его сокращенной версии в ответ.
 func Add(ctx context.Context, user *models.User, URL string) (string, error) {
+	return AddWithMeta(ctx, user, URL, nil)
+}
+
+// AddWithMeta Добавление URL с пользовательскими метаданными и получение его сокращенной версии в ответ.
+// Метаданные существующего URL не изменяются.
+func AddWithMeta(ctx context.Context, user *models.User, URL string, meta *models.URLMeta) (string, error) {
 
 	var err error
 	var shortURL string
-	if shortURL, err = repository.GetRepository().Add(ctx, user, URL); err != nil {
+
+	if meta != nil {
+		if err = normalizeMeta(meta); err != nil {
+			return ``, err
+		}
+	}
+
+	name, inputURL := canonical(URL)
+
+	shortURL, err = repository.GetRepository().Add(ctx, user, name, inputURL)
+	if errors.Is(err, repository.ErrShortKeyInUse) {
+		shortURL, err = addWithFreeKey(ctx, user, name, inputURL)
+	}
+
+	if err != nil {
 		logger.Error(`add url error: `, err)
 		return ``, err
 	}
 
+	if meta == nil || meta.IsEmpty() {
+		return shortURL, nil
+	}
+
+	if _, err = repository.GetRepository().SetURLMeta(ctx, user, shortURL, meta); err != nil {
+		logger.Error(`set url meta error: `, err)
+		return ``, err
+	}
+
 	return shortURL, nil
 }
 
+// addWithFreeKey добавление URL, ключ которого занят URL с измененным исходным URL либо URL другого пользователя.
+// Ключ подбирается повторным хешированием URL с номером попытки.
+func addWithFreeKey(ctx context.Context, user *models.User, name, inputURL string) (string, error) {
+	for attempt := 1; attempt <= maxShortKeyAttempts; attempt++ {
+		list := []models.BatchEl{{OriginalURL: name, InputURL: inputURL, ShortURL: shortKeyFor(name, attempt)}}
+
+		err := repository.GetRepository().AddBatch(ctx, user, &list)
+		if errors.Is(err, repository.ErrShortKeyInUse) {
+			continue
+		}
+
+		if err != nil {
+			return ``, err
+		}
+
+		if list[0].Exists {
+			return ``, &models.UniqueErr{Err: errors.New("url already exists"), ShortKey: list[0].ShortURL}
+		}
+
+		return list[0].ShortURL, nil
+	}
+
+	return ``, errors.New(`no free short key for url "` + name + `"`)
+}
+
+// shortKeyFor короткий ключ URL name для попытки attempt. Нулевая попытка - хеш самого URL.
+func shortKeyFor(name string, attempt int) string {
+	if attempt == 0 {
+		return urlhasher.GetHash(name)
+	}
+
+	return urlhasher.GetHash(strconv.Itoa(attempt) + `:` + name)
+}
+
 // Get Получение 1 URL по сокращенной версии.
 func Get(ctx context.Context, user *models.User, shortName string) (URL string, isRemoved bool) {
 	str, isRemoved, err := repository.GetRepository().GetByShortName(ctx, user, shortName)
@@ -62,41 +176,174 @@ func Get(ctx context.Context, user *models.User, shortName string) (URL string,
 // AddBatch Массовое добавление URL и получение их сокращенной версии в ответ.
 func AddBatch(ctx context.Context, user *models.User, batch []models.APIBatchRequestEl) ([]models.APIBatchResponseEl, error) {
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	list, err := addBatch(ctx, user, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	resList := make([]models.APIBatchResponseEl, 0, len(batch))
+	for _, v := range list {
+		resList = append(resList, models.APIBatchResponseEl{
+			CorrelationID: v.CorrelationID,
+			ShortURL:      settings.Load().baseHost + `/` + v.ShortURL,
+		})
+	}
+
+	return resList, nil
+}
+
+// addBatch сохранение URL одним обращением к репозиторию. Для уже сокращенных пользователем URL
+// репозиторий возвращает ключ существующего URL и отмечает их в поле Exists.
+// Если ключи части URL заняты, для них подбираются другие ключи и сохранение повторяется.
+func addBatch(ctx context.Context, user *models.User, batch []models.APIBatchRequestEl) ([]models.BatchEl, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, batchTimeout(len(batch)))
 	defer cancel()
 
 	list := make([]models.BatchEl, 0, len(batch))
 
 	for _, v := range batch {
+		if err := normalizeMeta(&v.URLMeta); err != nil {
+			return nil, &models.URLMetaErr{Err: errors.New(`correlation_id '` + v.CorrelationID + `': ` + err.Error())}
+		}
+
+		name, inputURL := canonical(v.OriginalURL)
+
 		list = append(list, models.BatchEl{
 			CorrelationID: v.CorrelationID,
-			OriginalURL:   v.OriginalURL,
-			ShortURL:      urlhasher.GetHash(v.OriginalURL),
+			OriginalURL:   name,
+			InputURL:      inputURL,
+			ShortURL:      shortKeyFor(name, 0),
+			Meta:          v.URLMeta,
 		})
 	}
 
 	err := repository.GetRepository().AddBatch(ctx, user, &list)
+	for attempt := 1; errors.Is(err, repository.ErrShortKeyInUse) && attempt <= maxShortKeyAttempts; attempt++ {
+		rehashTaken(list, attempt)
+		err = repository.GetRepository().AddBatch(ctx, user, &list)
+	}
+
 	if err != nil {
 		logger.Error(`add batch url error: `, err)
 		return nil, err
 	}
 
-	resList := make([]models.APIBatchResponseEl, 0, len(batch))
-	for _, v := range list {
-		resList = append(resList, models.APIBatchResponseEl{
-			CorrelationID: v.CorrelationID,
-			ShortURL:      baseHost + `/` + v.ShortURL,
-		})
+	return list, nil
+}
+
+// batchTimeout время сохранения списка из size URL.
+func batchTimeout(size int) time.Duration {
+	return batchBaseTimeout + time.Duration(size)*batchURLTimeout
+}
+
+// rehashTaken замена коротких ключей URL списка list, отмеченных репозиторием занятыми, ключами попытки attempt.
+func rehashTaken(list []models.BatchEl, attempt int) {
+	for i := range list {
+		if list[i].KeyInUse {
+			list[i].ShortURL = shortKeyFor(list[i].OriginalURL, attempt)
+			list[i].KeyInUse = false
+		}
 	}
+}
 
-	return resList, nil
+// AddBatchEach Массовое добавление URL с результатом для каждого URL.
+// URL с невалидными метаданными не добавляются и возвращаются со статусом invalid, остальные URL добавляются
+// одним обращением к репозиторию. Ошибка репозитория возвращается для всего списка.
+func AddBatchEach(ctx context.Context, user *models.User, batch []models.APIBatchRequestEl) ([]models.APIBatchResultEl, error) {
+
+	results := make([]models.APIBatchResultEl, len(batch))
+	valid := make([]models.APIBatchRequestEl, 0, len(batch))
+	index := make([]int, 0, len(batch))
+
+	for i := range batch {
+		results[i].CorrelationID = batch[i].CorrelationID
+
+		if err := normalizeMeta(&batch[i].URLMeta); err != nil {
+			results[i].Status = models.BatchStatusInvalid
+			results[i].Error = err.Error()
+			continue
+		}
+
+		valid = append(valid, batch[i])
+		index = append(index, i)
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	list, err := addBatch(ctx, user, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, el := range list {
+		results[index[j]].Status = models.BatchStatusCreated
+		results[index[j]].ShortURL = settings.Load().baseHost + `/` + el.ShortURL
+
+		if el.Exists {
+			results[index[j]].Status = models.BatchStatusExists
+		}
+	}
+
+	return results, nil
+}
+
+// canonical нормализованный URL и исходный URL, если он отличается от нормализованного.
+func canonical(URL string) (name string, inputURL string) {
+	name = normalizeURL(URL)
+	if name == URL {
+		return name, ``
+	}
+
+	return name, URL
 }
 
 // GetAll Получение всех сокращенных URL.
+// Канал URL нужно читать до закрытия, либо отменить ctx: после отмены отправка прекращается.
 func GetAll(ctx context.Context, user *models.User) (<-chan models.HistoryEl, <-chan error) {
 	return repository.GetRepository().GetAll(ctx, user)
 }
 
+// CountURLs Получение количества сокращенных URL пользователя.
+func CountURLs(ctx context.Context, user *models.User) (int, error) {
+	chList, chErr := repository.GetRepository().GetAll(ctx, user)
+
+	count := 0
+	for range chList {
+		count++
+	}
+
+	for err := range chErr {
+		var notFoundErr *models.HistoryNotFoundErr
+		if errors.As(err, &notFoundErr) {
+			return 0, nil
+		}
+
+		logger.Error(`count urls error: `, err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MoveUserURLs Перенос всех URL пользователя, выпустившего токен переноса token, пользователю to.
+// Если токен уже использован, то возвращается userauth.ErrInvalidTransferToken.
+func MoveUserURLs(ctx context.Context, token *models.TransferToken, to *models.User) (int, error) {
+	moved, err := repository.GetRepository().MoveUserURLs(ctx, token, to)
+	if errors.Is(err, repository.ErrTransferTokenUsed) {
+		return 0, userauth.ErrInvalidTransferToken
+	}
+
+	if err != nil {
+		logger.Error(`move user urls error: `, err)
+		return 0, err
+	}
+
+	return moved, nil
+}
+
 // RemoveBatch Массовое удаление URL.
 func RemoveBatch(ctx context.Context, user *models.User, list []string) error {
 	err := repository.GetRepository().RemoveBatch(ctx, user, list)
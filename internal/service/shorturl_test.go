@@ -9,6 +9,7 @@ import (
 	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
 	"github.com/Alheor/shorturl/internal/repository"
+	"github.com/Alheor/shorturl/internal/urlhasher"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -161,10 +162,9 @@ func TestAddBatchError(t *testing.T) {
 	list = append(list, models.APIBatchRequestEl{CorrelationID: `2`, OriginalURL: `https://example.com/?var2=value2&var3=value3`})
 	list = append(list, models.APIBatchRequestEl{CorrelationID: `3`, OriginalURL: `https://example.com/?var3=value3&var4=value4`})
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
-	defer cancel()
-
-	time.Sleep(11 * time.Millisecond)
+	// Запрос отменен до обращения к репозиторию
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
 
 	_, err = AddBatch(ctx, user, list)
 	require.Error(t, err)
@@ -244,10 +244,9 @@ func TestGetAllError(t *testing.T) {
 	var allList []models.HistoryEl
 	var errList []error
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
-	defer cancel()
-
-	time.Sleep(11 * time.Millisecond)
+	// Запрос отменен до обращения к репозиторию
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
 
 	chList, chErr := GetAll(ctx, user)
 
@@ -284,6 +283,10 @@ func TestRemoveBatchError(t *testing.T) {
 	list = append(list, `short_name2`)
 	list = append(list, `short_name3`)
 
+	// Запрос отменен до удаления
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
 	err = RemoveBatch(ctx, user, list)
 	require.Error(t, err)
 }
@@ -305,3 +308,92 @@ func TestIsDBReadySuccess(t *testing.T) {
 	isReady := IsDBReady(ctx)
 	require.True(t, isReady)
 }
+
+func TestAddBatchEach(t *testing.T) {
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	Init(&cfg)
+
+	user := &models.User{ID: `8c52c073-d8ce-85dc-b03e-7fc28a3d832a`}
+
+	results, err := AddBatchEach(ctx, user, []models.APIBatchRequestEl{
+		{CorrelationID: `1`, OriginalURL: `https://example.com/each1`},
+		{CorrelationID: `2`, OriginalURL: `https://example.com/each2`, URLMeta: models.URLMeta{Tags: []string{`bad tag`}}},
+		{CorrelationID: `3`, OriginalURL: `https://example.com/each3`},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, models.BatchStatusCreated, results[0].Status)
+	assert.Equal(t, cfg.BaseHost+`/`+urlhasher.GetHash(`https://example.com/each1`), results[0].ShortURL)
+
+	assert.Equal(t, `2`, results[1].CorrelationID)
+	assert.Equal(t, models.BatchStatusInvalid, results[1].Status)
+	assert.Empty(t, results[1].ShortURL)
+
+	assert.Equal(t, models.BatchStatusCreated, results[2].Status)
+	assert.Equal(t, cfg.BaseHost+`/`+urlhasher.GetHash(`https://example.com/each3`), results[2].ShortURL)
+}
+
+func TestAddBatchEachExists(t *testing.T) {
+	cfg := config.Load()
+	cfg.FileStoragePath = ``
+
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	err = repository.Init(ctx, &cfg, nil)
+	require.NoError(t, err)
+
+	Init(&cfg)
+
+	user := &models.User{ID: `8c52c073-d8ce-85dc-b03e-7fc28a3d832a`}
+
+	_, err = Add(ctx, user, `https://example.com/exists1`)
+	require.NoError(t, err)
+
+	// Ключ URL занят другим пользователем
+	other := &models.User{ID: `9d63dd88-e9ef-96ed-c14f-8ad39b4e943b`}
+
+	_, err = Add(ctx, other, `https://example.com/exists3`)
+	require.NoError(t, err)
+
+	results, err := AddBatchEach(ctx, user, []models.APIBatchRequestEl{
+		{CorrelationID: `1`, OriginalURL: `https://example.com/exists1`},
+		{CorrelationID: `2`, OriginalURL: `https://example.com/exists2`},
+		{CorrelationID: `3`, OriginalURL: `https://example.com/exists2`},
+		{CorrelationID: `4`, OriginalURL: `https://example.com/exists3`},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	assert.Equal(t, models.BatchStatusExists, results[0].Status)
+	assert.Equal(t, cfg.BaseHost+`/`+urlhasher.GetHash(`https://example.com/exists1`), results[0].ShortURL)
+
+	assert.Equal(t, models.BatchStatusCreated, results[1].Status)
+
+	assert.Equal(t, models.BatchStatusExists, results[2].Status)
+	assert.Equal(t, results[1].ShortURL, results[2].ShortURL)
+
+	assert.Equal(t, models.BatchStatusCreated, results[3].Status)
+	assert.Equal(t, cfg.BaseHost+`/`+shortKeyFor(`https://example.com/exists3`, 1), results[3].ShortURL)
+}
+
+func TestBatchTimeout(t *testing.T) {
+	assert.Equal(t, 5*time.Second, batchTimeout(0))
+	assert.Equal(t, 5*time.Second+100*time.Millisecond, batchTimeout(100))
+	assert.Equal(t, 105*time.Second, batchTimeout(100000))
+}
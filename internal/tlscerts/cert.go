@@ -2,20 +2,22 @@
 //
 // # Описание
 //
-// Формирует самоподписанный TLS сертификат с работы веб-сервера по протоколу HTTPS
+// Формирует в памяти самоподписанный TLS сертификат для работы веб-сервера по протоколу HTTPS.
+//
+// Manager хранит несколько пар сертификатов и ключей, выбирает сертификат по имени сервера (SNI)
+// и перезагружает их без перезапуска сервера и разрыва установленных соединений.
 package tlscerts
 
 import (
-	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/pem"
 	"math/big"
 	"net"
-	"os"
 	"time"
 )
 
@@ -28,15 +30,26 @@ var CertCountry = `RU`
 // CertLocality - город
 var CertLocality = `Moscow`
 
-var baseFilePath = `/tmp/shorturl/`
-var certFileName = `cert.pem`
-var keyFileName = `key.pem`
+// SelfSignedCert формирование самоподписанного сертификата TLS без сохранения в файлы.
+func SelfSignedCert() (*tls.Certificate, error) {
+	certPEM, keyPEM, err := generateSelfSigned()
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
 
-// GenerateCert формирование самоподписанного сертификата TLS
-func GenerateCert() (cert string, key string, err error) {
+// generateSelfSigned формирование самоподписанного сертификата и ключа в формате PEM.
+func generateSelfSigned() (certPEM []byte, keyPEM []byte, err error) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
 	if err != nil {
-		return "", "", err
+		return nil, nil, err
 	}
 
 	crt := &x509.Certificate{
@@ -56,104 +69,36 @@ func GenerateCert() (cert string, key string, err error) {
 
 	certBytes, err := x509.CreateCertificate(rand.Reader, crt, crt, &privateKey.PublicKey, privateKey)
 	if err != nil {
-		return "", "", err
-	}
-
-	var certPEM bytes.Buffer
-	err = pem.Encode(&certPEM, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	})
-
-	if err != nil {
-		return "", "", err
-	}
-
-	var privateKeyPEM bytes.Buffer
-	err = pem.Encode(&privateKeyPEM, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
-
-	if err != nil {
-		return "", "", err
+		return nil, nil, err
 	}
 
-	err = os.MkdirAll(baseFilePath, 0755)
-	if err != nil {
-		return "", "", err
-	}
-
-	certPath := baseFilePath + certFileName
-	keyPath := baseFilePath + keyFileName
-
-	certOut, err := os.Create(certPath)
-	if err != nil {
-		return "", "", err
-	}
-	defer certOut.Close()
-
-	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: certBytes}); err != nil {
-		return "", "", err
-	}
-
-	keyOut, err := os.Create(keyPath)
-	if err != nil {
-		return "", "", err
-	}
-	defer keyOut.Close()
-
 	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
 	if err != nil {
-		return "", "", err
+		return nil, nil, err
 	}
 
-	if err := pem.Encode(keyOut, &pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER}); err != nil {
-		return "", "", err
-	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER})
 
-	return certPath, keyPath, nil
+	return certPEM, keyPEM, nil
 }
 
-// LoadCert обработка пользовательских TLS сертификата и ключа в формате base64
-func LoadCert(certBase64, keyBase64 string) (cert string, key string, err error) {
-	certPath := baseFilePath + certFileName
-	keyPath := baseFilePath + keyFileName
-
-	// Декодируем сертификат из base64
+// ParseCert разбор пользовательских TLS сертификата и ключа в формате base64 без сохранения в файлы.
+func ParseCert(certBase64, keyBase64 string) (*tls.Certificate, error) {
 	certBytes, err := base64.StdEncoding.DecodeString(certBase64)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	// Декодируем ключ из base64
 	keyBytes, err := base64.StdEncoding.DecodeString(keyBase64)
 	if err != nil {
-		return "", "", err
-	}
-
-	// Создаем временные файлы для сертификата и ключа
-	certOut, err := os.Create(certPath)
-	if err != nil {
-		return "", "", err
-	}
-	defer certOut.Close()
-
-	_, err = certOut.Write(certBytes)
-	if err != nil {
-		return "", "", err
-	}
-
-	keyOut, err := os.Create(keyPath)
-	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	defer keyOut.Close()
 
-	_, err = keyOut.Write(keyBytes)
+	cert, err := tls.X509KeyPair(certBytes, keyBytes)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return certPath, keyPath, nil
+	return &cert, nil
 }
@@ -2,11 +2,9 @@ package tlscerts
 
 import (
 	"crypto/rsa"
-	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"net"
-	"os"
 	"testing"
 	"time"
 
@@ -14,53 +12,20 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var certFile = baseFilePath + certFileName
-var keyFile = baseFilePath + keyFileName
-
-func TestPrepareCert(t *testing.T) {
-	os.Remove(certFile)
-	os.Remove(keyFile)
-
-	// Проверка создания файлов
-	certPath, keyPath, err := GenerateCert()
-	require.NoError(t, err)
-	assert.Equal(t, certFile, certPath)
-	assert.Equal(t, keyFile, keyPath)
-
-	// Проверка наличия файлов
-	_, err = os.Stat(certPath)
-	require.NoError(t, err)
-
-	assert.False(t, os.IsNotExist(err))
-
-	_, err = os.Stat(keyPath)
-	require.NoError(t, err)
-
-	assert.False(t, os.IsNotExist(err))
-
-	// Проверка содержания файлов
-	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+func TestSelfSignedCert(t *testing.T) {
+	cert, err := SelfSignedCert()
 	require.NoError(t, err)
 
 	assert.NotEmpty(t, cert.Certificate)
-
-	os.Remove(certFile)
-	os.Remove(keyFile)
+	assert.NotNil(t, cert.PrivateKey)
 }
 
-func TestPrepareCert_CertificateProperties(t *testing.T) {
-	os.Remove(certFile)
-	os.Remove(keyFile)
-
-	certPath, _, err := GenerateCert()
-	require.NoError(t, err)
-
-	// Читаем и парсим сертификат
-	certPEM, err := os.ReadFile(certPath)
+func TestSelfSignedCert_CertificateProperties(t *testing.T) {
+	certPEM, _, err := generateSelfSigned()
 	require.NoError(t, err)
 
 	block, _ := pem.Decode(certPEM)
-	require.NoError(t, err)
+	require.NotNil(t, block)
 
 	cert, err := x509.ParseCertificate(block.Bytes)
 	require.NoError(t, err)
@@ -93,20 +58,10 @@ func TestPrepareCert_CertificateProperties(t *testing.T) {
 	if timeDiff < -time.Minute || timeDiff > time.Minute {
 		t.Errorf("certificate NotAfter is not approximately 1 year from now")
 	}
-
-	os.Remove(certFile)
-	os.Remove(keyFile)
 }
 
-func TestPrepareCert_PrivateKeyProperties(t *testing.T) {
-	os.Remove(certFile)
-	os.Remove(keyFile)
-
-	_, keyPath, err := GenerateCert()
-	require.NoError(t, err)
-
-	// Читаем и парсим приватный ключ
-	keyPEM, err := os.ReadFile(keyPath)
+func TestSelfSignedCert_PrivateKeyProperties(t *testing.T) {
+	_, keyPEM, err := generateSelfSigned()
 	require.NoError(t, err)
 
 	block, _ := pem.Decode(keyPEM)
@@ -124,35 +79,4 @@ func TestPrepareCert_PrivateKeyProperties(t *testing.T) {
 	} else {
 		t.Error("invalid RSA private key")
 	}
-
-	os.Remove(certFile)
-	os.Remove(keyFile)
-}
-
-func TestPrepareCert_FileCleanup(t *testing.T) {
-	err := os.WriteFile(certFile, []byte("fake cert"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create fake cert file: %v", err)
-	}
-	err = os.WriteFile(keyFile, []byte("fake key"), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create fake key file: %v", err)
-	}
-
-	_, _, err = GenerateCert()
-	if err != nil {
-		t.Fatalf("PrepareCert() returned error: %v", err)
-	}
-
-	// Проверяем что файлы были перезаписаны валидными данными
-	_, err = tls.LoadX509KeyPair(certFile, keyFile)
-	if err != nil {
-		t.Fatalf("Failed to load certificate and key after overwrite: %v", err)
-	}
-
-	err = os.Remove(certFile)
-	require.NoError(t, err)
-
-	err = os.Remove(keyFile)
-	require.NoError(t, err)
 }
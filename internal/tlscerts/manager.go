@@ -0,0 +1,243 @@
+package tlscerts
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+)
+
+// DefaultReloadInterval - период проверки изменений файлов сертификатов по умолчанию.
+const DefaultReloadInterval = 10 * time.Second
+
+// KeyPair - источник пары сертификата и ключа: файлы на диске, либо значения в формате base64.
+type KeyPair struct {
+	CertFile string
+	KeyFile  string
+
+	CertBase64 string
+	KeyBase64  string
+}
+
+// fileStamp - время изменения и размер файла для отслеживания его изменений.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certSet - загруженные сертификаты.
+type certSet struct {
+	// byName - сертификаты по имени сервера, в том числе шаблоны вида *.example.com.
+	byName map[string]*tls.Certificate
+	// fallback - сертификат для клиентов без SNI и неизвестных имен, первая пара в списке.
+	fallback *tls.Certificate
+	// files - состояние файлов сертификатов на момент загрузки.
+	files map[string]fileStamp
+}
+
+// Manager - выбор сертификата по имени сервера (SNI) и перезагрузка сертификатов без перезапуска сервера.
+// Новые сертификаты используются для новых соединений, установленные соединения не разрываются.
+// Если пары не заданы, то используется самоподписанный сертификат, который формируется в памяти.
+type Manager struct {
+	mu       sync.Mutex
+	pairs    []KeyPair
+	selfCert *tls.Certificate
+	current  atomic.Pointer[certSet]
+}
+
+// NewManager создание менеджера сертификатов.
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// SetPairs замена пар сертификатов и ключей с загрузкой. Если пары не изменились, то сертификаты перечитываются
+// из тех же источников. При ошибке продолжают действовать прежние сертификаты.
+func (m *Manager) SetPairs(pairs []KeyPair) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.load(pairs)
+}
+
+// Reload перечитывание сертификатов из текущих источников.
+func (m *Manager) Reload() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.load(m.pairs)
+}
+
+// GetCertificate выбор сертификата по имени сервера, реализация tls.Config.GetCertificate.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	set := m.current.Load()
+	if set == nil {
+		return nil, errors.New(`tls certificates are not loaded`)
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, `.`))
+	if cert, exists := set.byName[name]; exists {
+		return cert, nil
+	}
+
+	if _, parent, found := strings.Cut(name, `.`); found {
+		if cert, exists := set.byName[`*.`+parent]; exists {
+			return cert, nil
+		}
+	}
+
+	return set.fallback, nil
+}
+
+// Watch перечитывание сертификатов при изменении их файлов, пока не будет отменен контекст ctx.
+// Изменения файлов проверяются с периодом interval.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if !m.filesChanged() {
+				continue
+			}
+
+			if err := m.Reload(); err != nil {
+				logger.Error(`tls certificates reload error`, err)
+				continue
+			}
+
+			logger.Info(`tls certificates reloaded`)
+		}
+	}()
+}
+
+// filesChanged изменились ли файлы сертификатов с момента загрузки.
+func (m *Manager) filesChanged() bool {
+	set := m.current.Load()
+	if set == nil {
+		return false
+	}
+
+	for path, stamp := range set.files {
+		current, err := statFile(path)
+		if err != nil || !current.modTime.Equal(stamp.modTime) || current.size != stamp.size {
+			return true
+		}
+	}
+
+	return false
+}
+
+// load загрузка сертификатов из pairs и публикация их для новых соединений.
+func (m *Manager) load(pairs []KeyPair) error {
+	set := &certSet{byName: make(map[string]*tls.Certificate), files: make(map[string]fileStamp)}
+
+	for i, pair := range pairs {
+		cert, err := loadPair(pair, set.files)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			set.fallback = cert
+		}
+
+		for _, name := range certNames(cert) {
+			if _, exists := set.byName[name]; !exists {
+				set.byName[name] = cert
+			}
+		}
+	}
+
+	if len(pairs) == 0 {
+		if m.selfCert == nil {
+			cert, err := SelfSignedCert()
+			if err != nil {
+				return err
+			}
+
+			m.selfCert = cert
+		}
+
+		set.fallback = m.selfCert
+	}
+
+	m.pairs = append([]KeyPair(nil), pairs...)
+	m.current.Store(set)
+
+	return nil
+}
+
+// loadPair загрузка пары сертификата и ключа. Состояние прочитанных файлов сохраняется в files.
+func loadPair(pair KeyPair, files map[string]fileStamp) (*tls.Certificate, error) {
+	if pair.CertFile == `` && pair.KeyFile == `` {
+		return ParseCert(pair.CertBase64, pair.KeyBase64)
+	}
+
+	for _, path := range []string{pair.CertFile, pair.KeyFile} {
+		stamp, err := statFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files[path] = stamp
+	}
+
+	cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+	if err != nil {
+		return nil, errors.New(`tls key pair ` + pair.CertFile + `: ` + err.Error())
+	}
+
+	return &cert, nil
+}
+
+// certNames имена сервера, для которых выдан сертификат: DNS имена, либо CN, если их нет.
+func certNames(cert *tls.Certificate) []string {
+	leaf := cert.Leaf
+	if leaf == nil {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil
+		}
+
+		leaf = parsed
+	}
+
+	names := leaf.DNSNames
+	if len(names) == 0 && leaf.Subject.CommonName != `` {
+		names = []string{leaf.Subject.CommonName}
+	}
+
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, strings.ToLower(name))
+	}
+
+	return result
+}
+
+// statFile время изменения и размер файла path.
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
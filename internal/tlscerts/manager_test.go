@@ -0,0 +1,230 @@
+package tlscerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPair формирование сертификата для имен names в формате PEM.
+func testPair(t *testing.T, names ...string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: `CERTIFICATE`, Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: `PRIVATE KEY`, Bytes: keyDER})
+}
+
+// writeTestPair сохранение сертификата для имен names в файлы каталога dir.
+func writeTestPair(t *testing.T, dir string, prefix string, names ...string) KeyPair {
+	certPEM, keyPEM := testPair(t, names...)
+
+	pair := KeyPair{CertFile: filepath.Join(dir, prefix+`.crt`), KeyFile: filepath.Join(dir, prefix+`.key`)}
+
+	err := os.WriteFile(pair.CertFile, certPEM, 0644)
+	require.NoError(t, err)
+
+	err = os.WriteFile(pair.KeyFile, keyPEM, 0600)
+	require.NoError(t, err)
+
+	return pair
+}
+
+// servedName имя сервера из сертификата, выбранного для SNI serverName.
+func servedName(t *testing.T, m *Manager, serverName string) string {
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+func TestManagerSelectBySNI(t *testing.T) {
+	dir := t.TempDir()
+
+	m := NewManager()
+	err := m.SetPairs([]KeyPair{
+		writeTestPair(t, dir, `first`, `first.ru`, `www.first.ru`),
+		writeTestPair(t, dir, `second`, `*.second.ru`),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `first.ru`, servedName(t, m, `first.ru`))
+	assert.Equal(t, `first.ru`, servedName(t, m, `WWW.First.ru.`))
+	assert.Equal(t, `*.second.ru`, servedName(t, m, `go.second.ru`))
+	assert.Equal(t, `first.ru`, servedName(t, m, `a.b.second.ru`))
+	assert.Equal(t, `first.ru`, servedName(t, m, `unknown.ru`))
+	assert.Equal(t, `first.ru`, servedName(t, m, ``))
+}
+
+func TestManagerBase64Pair(t *testing.T) {
+	certPEM, keyPEM := testPair(t, `base64.ru`)
+
+	m := NewManager()
+	err := m.SetPairs([]KeyPair{{
+		CertBase64: base64.StdEncoding.EncodeToString(certPEM),
+		KeyBase64:  base64.StdEncoding.EncodeToString(keyPEM),
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, `base64.ru`, servedName(t, m, `base64.ru`))
+}
+
+func TestManagerNotLoaded(t *testing.T) {
+	_, err := NewManager().GetCertificate(&tls.ClientHelloInfo{ServerName: `first.ru`})
+	assert.Error(t, err)
+}
+
+func TestManagerKeepsCertificatesOnError(t *testing.T) {
+	dir := t.TempDir()
+
+	m := NewManager()
+	err := m.SetPairs([]KeyPair{writeTestPair(t, dir, `first`, `first.ru`)})
+	require.NoError(t, err)
+
+	err = m.SetPairs([]KeyPair{{CertFile: filepath.Join(dir, `not-exists.crt`), KeyFile: filepath.Join(dir, `not-exists.key`)}})
+	require.Error(t, err)
+	assert.Equal(t, `first.ru`, servedName(t, m, `first.ru`))
+
+	err = os.WriteFile(filepath.Join(dir, `first.key`), []byte(`broken key`), 0600)
+	require.NoError(t, err)
+
+	err = m.Reload()
+	require.Error(t, err)
+	assert.Equal(t, `first.ru`, servedName(t, m, `first.ru`))
+}
+
+func TestManagerSelfSigned(t *testing.T) {
+	m := NewManager()
+
+	err := m.SetPairs(nil)
+	require.NoError(t, err)
+
+	first, err := m.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	err = m.Reload()
+	require.NoError(t, err)
+
+	second, err := m.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+}
+
+func TestManagerWatchFileChange(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+
+	m := NewManager()
+	err = m.SetPairs([]KeyPair{writeTestPair(t, dir, `site`, `old.ru`)})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Watch(ctx, 10*time.Millisecond)
+
+	// Время изменения файлов может совпасть с предыдущим, поэтому новый сертификат выдается для имени другой длины
+	writeTestPair(t, dir, `site`, `renewed-site.ru`)
+
+	assert.Eventually(t, func() bool {
+		return servedName(t, m, `renewed-site.ru`) == `renewed-site.ru`
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestManagerReloadKeepsConnections(t *testing.T) {
+	dir := t.TempDir()
+
+	m := NewManager()
+	err := m.SetPairs([]KeyPair{writeTestPair(t, dir, `site`, `old.ru`)})
+	require.NoError(t, err)
+
+	ln, err := tls.Listen(`tcp`, `127.0.0.1:0`, &tls.Config{GetCertificate: m.GetCertificate})
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	dial := func() (*tls.Conn, string) {
+		conn, err := tls.Dial(`tcp`, ln.Addr().String(), &tls.Config{ServerName: `old.ru`, InsecureSkipVerify: true})
+		require.NoError(t, err)
+
+		return conn, conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	oldConn, name := dial()
+	defer oldConn.Close()
+	assert.Equal(t, `old.ru`, name)
+
+	writeTestPair(t, dir, `site`, `new.ru`)
+
+	err = m.Reload()
+	require.NoError(t, err)
+
+	newConn, name := dial()
+	defer newConn.Close()
+	assert.Equal(t, `new.ru`, name)
+
+	// Соединение, установленное до перезагрузки, продолжает работать
+	_, err = oldConn.Write([]byte(`ping`))
+	require.NoError(t, err)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(oldConn, buf)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal([]byte(`ping`), buf))
+}
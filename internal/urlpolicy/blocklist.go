@@ -0,0 +1,134 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// blocklist - список запрещенных доменов.
+type blocklist struct {
+	// domains - запрещенные домены.
+	domains map[string]struct{}
+	// wildcards - запрещенные домены с поддоменами в виде суффикса ".example.com" для шаблона *.example.com.
+	wildcards []string
+}
+
+var blocked atomic.Pointer[blocklist]
+
+func init() {
+	blocked.Store(&blocklist{})
+}
+
+// match проверка хоста по списку, возвращает сработавшее правило.
+func (b *blocklist) match(host string) (string, bool) {
+	if _, exists := b.domains[host]; exists {
+		return host, true
+	}
+
+	// Шаблон *.example.com запрещает и сам домен example.com
+	for _, suffix := range b.wildcards {
+		if host == suffix[1:] || strings.HasSuffix(host, suffix) {
+			return `*` + suffix, true
+		}
+	}
+
+	return ``, false
+}
+
+// loadBlocklist загрузка списка запрещенных доменов из файла.
+func loadBlocklist(path string) (*blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	list := &blocklist{domains: make(map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := normalizeHost(strings.TrimSpace(scanner.Text()))
+		if line == `` || strings.HasPrefix(line, `#`) {
+			continue
+		}
+
+		if strings.HasPrefix(line, `*.`) {
+			list.wildcards = append(list.wildcards, line[1:])
+			continue
+		}
+
+		list.domains[line] = struct{}{}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// watchBlocklist загрузка списка запрещенных доменов и перезагрузка при изменении файла.
+// При ошибке перезагрузки продолжает действовать ранее загруженный список.
+func watchBlocklist(ctx context.Context, path string, interval time.Duration) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	list, err := loadBlocklist(path)
+	if err != nil {
+		return err
+	}
+
+	blocked.Store(list)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		modTime, size := info.ModTime(), info.Size()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				logger.Error(`blocklist file stat error`, err)
+				continue
+			}
+
+			if info.ModTime().Equal(modTime) && info.Size() == size {
+				continue
+			}
+
+			list, err := loadBlocklist(path)
+			if err != nil {
+				logger.Error(`blocklist file reload error`, err)
+				continue
+			}
+
+			modTime, size = info.ModTime(), info.Size()
+			blocked.Store(list)
+
+			logger.Info(`blocklist reloaded`,
+				zap.Int("domains", len(list.domains)),
+				zap.Int("wildcards", len(list.wildcards)),
+			)
+		}
+	}()
+
+	return nil
+}
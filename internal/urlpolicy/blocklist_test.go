@@ -0,0 +1,87 @@
+package urlpolicy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `blocklist.txt`)
+
+	err := os.WriteFile(path, []byte("# comment\n\nevil.com\n*.Phishing.NET\n"), 0644)
+	require.NoError(t, err)
+
+	list, err := loadBlocklist(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		host        string
+		wantBlocked bool
+	}{
+		{host: `evil.com`, wantBlocked: true},
+		{host: `sub.evil.com`},
+		{host: `notevil.com`},
+		{host: `login.phishing.net`, wantBlocked: true},
+		{host: `a.b.phishing.net`, wantBlocked: true},
+		{host: `phishing.net`, wantBlocked: true},
+		{host: `notphishing.net`},
+		{host: `example.com`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			_, isBlocked := list.match(tt.host)
+			assert.Equal(t, tt.wantBlocked, isBlocked)
+		})
+	}
+}
+
+func TestBlocklistReload(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), `blocklist.txt`)
+
+	err = os.WriteFile(path, []byte("evil.com\n"), 0644)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = Init(ctx, &config.Options{BlocklistFile: path, BlocklistReloadInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer Init(context.Background(), &config.Options{})
+
+	assert.Error(t, Check(`https://evil.com/path`))
+	assert.NoError(t, Check(`https://bad.org/path`))
+
+	err = os.WriteFile(path, []byte("*.bad.org\nbad.org\n"), 0644)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return Check(`https://bad.org/path`) != nil && Check(`https://evil.com/path`) == nil
+	}, time.Second, 10*time.Millisecond)
+
+	// Удаление файла не сбрасывает загруженный список
+	err = os.Remove(path)
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Error(t, Check(`https://www.bad.org/path`))
+}
+
+func TestInitBlocklistNotFound(t *testing.T) {
+	err := Init(context.Background(), &config.Options{BlocklistFile: `/not/exists/blocklist.txt`})
+	assert.Error(t, err)
+
+	Init(context.Background(), &config.Options{})
+}
@@ -0,0 +1,275 @@
+// Package urlpolicy - политика допустимых URL для сокращения.
+//
+// # Описание
+//
+// Проверяет сокращаемый URL перед сохранением:
+//
+// • схема URL должна входить в список допустимых (по умолчанию http и https);
+//
+// • длина URL не должна превышать максимальную;
+//
+// • URL не должен указывать на сам сервис, а также на localhost, loopback, частные и link-local адреса;
+//
+// • домен URL не должен входить в список запрещенных доменов.
+//
+// Список запрещенных доменов загружается из файла и перечитывается при его изменении.
+// Шаблон вида *.example.com запрещает как сам домен example.com, так и все его поддомены.
+// Доменные имена не разрешаются в IP адреса, частные адреса проверяются только для IP, указанных в URL явно.
+// IPv4 адрес в хосте разбирается так же, как это делает inet_aton: допускаются сокращенные (127.1),
+// десятичные (2130706433), восьмеричные и шестнадцатеричные (0x7f.0.0.1) формы.
+// Хост, последняя часть которого числовая, но не является IPv4 адресом, отклоняется.
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/models"
+)
+
+// DefaultMaxURLLength - максимальная длина URL по умолчанию.
+const DefaultMaxURLLength = 2048
+
+// DefaultReloadInterval - период проверки изменений файла запрещенных доменов по умолчанию.
+const DefaultReloadInterval = 10 * time.Second
+
+// defaultSchemes - допустимые схемы URL по умолчанию.
+var defaultSchemes = []string{`http`, `https`}
+
+// policy - текущие параметры проверки URL.
+type policy struct {
+	schemes   map[string]struct{}
+	maxLength int
+	// selfHost - хост сервиса, ссылки на который запрещены.
+	selfHost string
+}
+
+var current atomic.Pointer[policy]
+
+// stopWatch останавливает слежение за файлом запрещенных доменов, запущенное предыдущим вызовом Init.
+var (
+	watchMu   sync.Mutex
+	stopWatch context.CancelFunc = func() {}
+)
+
+func init() {
+	current.Store(newPolicy(nil, 0, ``))
+}
+
+// Init Подготовка политики к работе. Если задан файл запрещенных доменов, то он загружается
+// и перечитывается при изменении, пока не будет отменен контекст ctx.
+// Может вызываться повторно при изменении конфигурации: при ошибке продолжают действовать прежние параметры.
+func Init(ctx context.Context, cfg *config.Options) error {
+	selfHost := ``
+	if cfg.BaseHost != `` {
+		baseURL, err := url.Parse(cfg.BaseHost)
+		if err != nil {
+			return err
+		}
+
+		selfHost = normalizeHost(baseURL.Hostname())
+	}
+
+	p := newPolicy(cfg.AllowedSchemes, cfg.MaxURLLength, selfHost)
+
+	watchMu.Lock()
+	defer watchMu.Unlock()
+
+	if cfg.BlocklistFile == `` {
+		stopWatch()
+		stopWatch = func() {}
+
+		current.Store(p)
+		blocked.Store(&blocklist{})
+
+		return nil
+	}
+
+	interval := cfg.BlocklistReloadInterval
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	if err := watchBlocklist(watchCtx, cfg.BlocklistFile, interval); err != nil {
+		cancel()
+		return err
+	}
+
+	stopWatch()
+	stopWatch = cancel
+
+	current.Store(p)
+
+	return nil
+}
+
+// Check Проверка URL на соответствие политике. Возвращает *models.URLPolicyErr, если URL отклонен.
+func Check(rawURL string) error {
+	p := current.Load()
+
+	if len(rawURL) > p.maxLength {
+		return reject(rawURL, `url is longer than `+strconv.Itoa(p.maxLength)+` characters`)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return reject(rawURL, `invalid url`)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if _, exists := p.schemes[scheme]; !exists {
+		return reject(rawURL, `scheme "`+scheme+`" is not allowed`)
+	}
+
+	host := normalizeHost(u.Hostname())
+	if host == `` {
+		return reject(rawURL, `host required`)
+	}
+
+	if p.selfHost != `` && host == p.selfHost {
+		return reject(rawURL, `links to the service itself are not allowed`)
+	}
+
+	if net.ParseIP(host) == nil && looksNumeric(host) && parseIPv4(host) == nil {
+		return reject(rawURL, `invalid numeric host "`+host+`"`)
+	}
+
+	if isLocalHost(host) {
+		return reject(rawURL, `private or loopback host "`+host+`" is not allowed`)
+	}
+
+	if pattern, isBlocked := blocked.Load().match(host); isBlocked {
+		return reject(rawURL, `domain "`+host+`" is blocked by rule "`+pattern+`"`)
+	}
+
+	return nil
+}
+
+func newPolicy(schemes []string, maxLength int, selfHost string) *policy {
+	if len(schemes) == 0 {
+		schemes = defaultSchemes
+	}
+
+	if maxLength <= 0 {
+		maxLength = DefaultMaxURLLength
+	}
+
+	p := &policy{schemes: make(map[string]struct{}, len(schemes)), maxLength: maxLength, selfHost: selfHost}
+	for _, scheme := range schemes {
+		p.schemes[strings.ToLower(strings.TrimSpace(scheme))] = struct{}{}
+	}
+
+	return p
+}
+
+// isLocalHost хост указывает на localhost, loopback, частный, link-local или неопределенный адрес.
+func isLocalHost(host string) bool {
+	if host == `localhost` || strings.HasSuffix(host, `.localhost`) {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ip = parseIPv4(host)
+	}
+
+	if ip == nil {
+		return false
+	}
+
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// looksNumeric последняя часть хоста числовая: такой хост браузеры разбирают как IPv4 адрес.
+func looksNumeric(host string) bool {
+	last := host[strings.LastIndexByte(host, '.')+1:]
+	if last == `` {
+		return false
+	}
+
+	if strings.HasPrefix(last, `0x`) {
+		last = last[2:]
+		if last == `` {
+			return true
+		}
+
+		_, err := strconv.ParseUint(last, 16, 64)
+		return err == nil || errors.Is(err, strconv.ErrRange)
+	}
+
+	for _, c := range last {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseIPv4 разбор IPv4 адреса по правилам inet_aton: от одной до четырех частей в десятичной, восьмеричной (с ведущим 0)
+// или шестнадцатеричной (с префиксом 0x) форме, последняя часть заполняет оставшиеся байты адреса.
+// Возвращает nil, если хост не является IPv4 адресом.
+func parseIPv4(host string) net.IP {
+	parts := strings.Split(host, `.`)
+	if len(parts) > 4 {
+		return nil
+	}
+
+	values := make([]uint64, len(parts))
+	for i, part := range parts {
+		base := 10
+		switch {
+		case strings.HasPrefix(part, `0x`):
+			part, base = part[2:], 16
+			if part == `` {
+				part = `0`
+			}
+		case len(part) > 1 && part[0] == '0':
+			part, base = part[1:], 8
+		}
+
+		value, err := strconv.ParseUint(part, base, 32)
+		if err != nil {
+			return nil
+		}
+
+		values[i] = value
+	}
+
+	last := len(values) - 1
+	for _, value := range values[:last] {
+		if value > 0xff {
+			return nil
+		}
+	}
+
+	if values[last] >= 1<<(8*(4-last)) {
+		return nil
+	}
+
+	addr := values[last]
+	for i, value := range values[:last] {
+		addr |= value << (8 * (3 - i))
+	}
+
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr))
+}
+
+// normalizeHost приведение хоста к нижнему регистру без завершающей точки.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), `.`)
+}
+
+func reject(rawURL string, reason string) error {
+	return &models.URLPolicyErr{URL: rawURL, Reason: reason}
+}
@@ -0,0 +1,79 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	err := Init(context.Background(), &config.Options{BaseHost: `http://short.example.com:8080`, MaxURLLength: 64})
+	require.NoError(t, err)
+	defer Init(context.Background(), &config.Options{})
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: `https`, url: `https://practicum.yandex.ru/path?query=1`},
+		{name: `http`, url: `http://practicum.yandex.ru`},
+		{name: `upper case scheme`, url: `HTTPS://practicum.yandex.ru`},
+		{name: `public ip`, url: `http://8.8.8.8/`},
+		{name: `javascript`, url: `javascript:alert(1)`, wantErr: true},
+		{name: `file`, url: `file:///etc/passwd`, wantErr: true},
+		{name: `ftp`, url: `ftp://example.com/file`, wantErr: true},
+		{name: `too long`, url: `https://example.com/` + strings.Repeat(`a`, 64), wantErr: true},
+		{name: `self host`, url: `https://short.example.com/abc`, wantErr: true},
+		{name: `self host with dot`, url: `https://SHORT.example.com./abc`, wantErr: true},
+		{name: `localhost`, url: `http://localhost:8080/`, wantErr: true},
+		{name: `localhost subdomain`, url: `http://app.localhost/`, wantErr: true},
+		{name: `loopback`, url: `http://127.0.0.1/`, wantErr: true},
+		{name: `loopback ipv6`, url: `http://[::1]/`, wantErr: true},
+		{name: `private`, url: `http://192.168.1.1/`, wantErr: true},
+		{name: `link local`, url: `http://169.254.169.254/latest/meta-data`, wantErr: true},
+		{name: `unspecified`, url: `http://0.0.0.0/`, wantErr: true},
+		{name: `empty host`, url: `http:///path`, wantErr: true},
+		{name: `short loopback`, url: `http://127.1/`, wantErr: true},
+		{name: `decimal loopback`, url: `http://2130706433/`, wantErr: true},
+		{name: `hex loopback`, url: `http://0x7f.0.0.1/`, wantErr: true},
+		{name: `octal loopback`, url: `http://0177.0.0.01/`, wantErr: true},
+		{name: `decimal private`, url: `http://10.65535/`, wantErr: true},
+		{name: `decimal public`, url: `http://134744072/`},
+		{name: `invalid numeric host`, url: `http://1.2.3.4.5/`, wantErr: true},
+		{name: `numeric host overflow`, url: `http://4294967296/`, wantErr: true},
+		{name: `numeric subdomain`, url: `http://1.example.com/`},
+		{name: `mapped ipv6 public`, url: `http://[::ffff:8.8.8.8]/`},
+		{name: `mapped ipv6 loopback`, url: `http://[::ffff:127.0.0.1]/`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.url)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *models.URLPolicyErr
+			assert.True(t, errors.As(err, &policyErr))
+		})
+	}
+}
+
+func TestCheckAllowedSchemes(t *testing.T) {
+	err := Init(context.Background(), &config.Options{AllowedSchemes: []string{`https`, `FTP`}})
+	require.NoError(t, err)
+	defer Init(context.Background(), &config.Options{})
+
+	assert.NoError(t, Check(`https://example.com`))
+	assert.NoError(t, Check(`ftp://example.com`))
+	assert.Error(t, Check(`http://example.com`))
+}
@@ -2,8 +2,15 @@
 //
 // # Описание
 //
-// Авторизует пользователя по наличию специально подписанной cookie.
-// Если у пользователя нет cookie или данные в ней невалидно подписаны, то выдается новая cookie.
+// Авторизует пользователя по наличию специально подписанного токена.
+// Токен принимается из cookie, либо из заголовка "Authorization: Bearer <token>".
+// Если у пользователя нет токена или он невалидно подписан, то выдается новый токен.
+//
+// Формат токена задается конфигурацией: подписанная HMAC-SHA256 cookie (по умолчанию) или JWT (HS256/ES256),
+// содержащий sub, exp, iat и jti. Для JWT поддерживается ротация ключей: токены, подписанные предыдущими ключами,
+// принимаются в течение заданного времени и перевыпускаются с основным ключом.
+//
+// Для переноса URL между клиентами выпускается короткоживущий одноразовый подписанный токен переноса.
 package userauth
 
 import (
@@ -13,18 +20,117 @@ import (
 	"encoding/base64"
 	"errors"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
 
 	"github.com/google/uuid"
 )
 
-var signatureKey []byte
+// HTTP заголовки
+const (
+	// HeaderAuthorization header "Authorization" name.
+	HeaderAuthorization = `Authorization`
+
+	// HeaderSetCookie header "Set-Cookie" name.
+	HeaderSetCookie = `Set-Cookie`
+)
+
+const bearerPrefix = `Bearer `
+
+// authState - параметры авторизации. При повторном вызове Init заменяются целиком.
+type authState struct {
+	// signatureKeys - все ключи подписи cookie, первый ключ основной.
+	signatureKeys [][]byte
+	// signatureKeyIDs - идентификаторы ключей подписи cookie для учета их использования.
+	signatureKeyIDs []string
+	// codec - формат токена пользователя.
+	codec TokenCodec
+	// cookiePolicy - атрибуты выдаваемой cookie пользователя.
+	cookiePolicy http.Cookie
+	// transferTokenTTL - время жизни токена переноса.
+	transferTokenTTL time.Duration
+}
+
+// state - текущие параметры авторизации. До вызова Init используется пустой ключ.
+var state atomic.Pointer[authState]
+
+func init() {
+	state.Store(&authState{
+		signatureKeys:   [][]byte{nil},
+		signatureKeyIDs: []string{keyID(nil)},
+		codec:           &cookieCodec{},
+		cookiePolicy: http.Cookie{
+			Name:     models.CookiesName,
+			Path:     `/`,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		transferTokenTTL: defaultTransferTokenTTL,
+	})
+}
 
 // Init Подготовка сервиса к работе.
-func Init(config *config.Options) {
-	signatureKey = []byte(config.SignatureKey)
+// Может вызываться повторно при изменении конфигурации: при ошибке продолжают действовать прежние параметры.
+func Init(cfg *config.Options) error {
+	st := &authState{}
+
+	for _, key := range cfg.GetSignatureKeys() {
+		if key == `` {
+			return errors.New(`signature key is empty`)
+		}
+
+		st.signatureKeys = append(st.signatureKeys, []byte(key))
+		st.signatureKeyIDs = append(st.signatureKeyIDs, keyID([]byte(key)))
+	}
+
+	st.transferTokenTTL = cfg.TransferTokenTTL
+	if st.transferTokenTTL <= 0 {
+		st.transferTokenTTL = defaultTransferTokenTTL
+	}
+
+	sameSite, err := parseSameSite(cfg.CookieSameSite)
+	if err != nil {
+		return err
+	}
+
+	if sameSite == http.SameSiteNoneMode && !cfg.EnableHTTPS {
+		return errors.New(`cookie SameSite=None requires HTTPS`)
+	}
+
+	st.cookiePolicy = http.Cookie{
+		Name:     models.CookiesName,
+		Path:     `/`,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   int(cfg.CookieMaxAge.Seconds()),
+		Secure:   cfg.EnableHTTPS,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+
+	switch cfg.AuthTokenFormat {
+	case ``, config.AuthTokenFormatCookie:
+		st.codec = &cookieCodec{}
+
+	case config.AuthTokenFormatJWT:
+		jwt, err := newJWTCodec(cfg.JWTAlgorithm, cfg.GetJWTKeys(), cfg.JWTTTL, cfg.JWTKeyGracePeriod)
+		if err != nil {
+			return err
+		}
+
+		st.codec = jwt
+
+	default:
+		return errors.New(`unknown auth token format "` + cfg.AuthTokenFormat + `"`)
+	}
+
+	state.Store(st)
+
+	return nil
 }
 
 // GetUser get user from context.
@@ -41,12 +147,14 @@ func GetUser(ctx context.Context) *models.User {
 func AuthHTTPHandler(f http.HandlerFunc) http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
 
-		var userCookie *models.UserCookie
+		var user *models.User
+		var reissue bool
 		var err error
 
-		cookie, _ := req.Cookie(models.CookiesName)
-		if cookie != nil {
-			userCookie, err = parseCookie(cookie)
+		codec := state.Load().codec
+
+		if token := getToken(req); token != `` {
+			user, reissue, err = codec.Decode(token)
 		}
 
 		if err != nil {
@@ -57,27 +165,81 @@ func AuthHTTPHandler(f http.HandlerFunc) http.HandlerFunc {
 			}
 		}
 
-		if userCookie == nil {
+		if user == nil || reissue {
+			if user == nil {
+				user = &models.User{ID: generateUserUUID()}
+			}
 
-			userCookie = getUserCookie()
-			cookieValue := string(userCookie.Sign) + userCookie.User.ID
+			token, err := codec.Encode(user)
+			if err != nil {
+				logger.Error(`user token encode error`, err)
+				resp.WriteHeader(http.StatusInternalServerError)
+				return
+			}
 
-			http.SetCookie(resp,
-				&http.Cookie{
-					Name:  models.CookiesName,
-					Value: base64.StdEncoding.EncodeToString([]byte(cookieValue)),
-				},
-			)
+			setToken(resp, token)
 		}
 
-		ctxWithUser := context.WithValue(req.Context(), models.ContextValueName, &userCookie.User)
+		ctxWithUser := context.WithValue(req.Context(), models.ContextValueName, user)
 		f(resp, req.Clone(ctxWithUser))
 	}
 }
 
-// GetSignature Получение подписи.
+// ClearToken удаление токена пользователя из ответа и cookie клиента.
+func ClearToken(resp http.ResponseWriter) {
+	resp.Header().Del(HeaderSetCookie)
+	resp.Header().Del(HeaderAuthorization)
+
+	cookie := state.Load().cookiePolicy
+	cookie.MaxAge = -1
+
+	http.SetCookie(resp, &cookie)
+}
+
+// setToken выдача токена пользователю.
+func setToken(resp http.ResponseWriter, token string) {
+	cookie := state.Load().cookiePolicy
+	cookie.Value = token
+
+	http.SetCookie(resp, &cookie)
+	resp.Header().Set(HeaderAuthorization, bearerPrefix+token)
+}
+
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case ``, `lax`:
+		return http.SameSiteLaxMode, nil
+	case `strict`:
+		return http.SameSiteStrictMode, nil
+	case `none`:
+		return http.SameSiteNoneMode, nil
+	}
+
+	return 0, errors.New(`unknown cookie SameSite policy "` + value + `"`)
+}
+
+// getToken получение токена из cookie, либо из заголовка Authorization.
+func getToken(req *http.Request) string {
+	cookie, _ := req.Cookie(models.CookiesName)
+	if cookie != nil && cookie.Value != `` {
+		return cookie.Value
+	}
+
+	header := req.Header.Get(HeaderAuthorization)
+	if len(header) > len(bearerPrefix) && strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(header[len(bearerPrefix):])
+	}
+
+	return ``
+}
+
+// GetSignature Получение подписи основным ключом.
 func GetSignature(uuid string) []byte {
-	h := hmac.New(sha256.New, signatureKey)
+	return sign(state.Load().signatureKeys[0], uuid)
+}
+
+func sign(key []byte, uuid string) []byte {
+	h := hmac.New(sha256.New, key)
 	h.Write([]byte(models.CookiesName))
 	h.Write([]byte(uuid))
 
@@ -91,7 +253,7 @@ func parseCookie(cookie *http.Cookie) (userCookie *models.UserCookie, error erro
 	}
 
 	cookieValue, err := base64.StdEncoding.DecodeString(cookie.Value)
-	if err != nil {
+	if err != nil || len(cookieValue) < sha256.Size {
 		return nil, nil
 	}
 
@@ -102,18 +264,16 @@ func parseCookie(cookie *http.Cookie) (userCookie *models.UserCookie, error erro
 		return nil, &models.EmptyUserIDErr{Err: err}
 	}
 
-	expectedSignature := GetSignature(value.String())
-
-	if !hmac.Equal(signature, expectedSignature) {
-		return nil, nil
+	// Подпись проверяется всеми ключами, начиная с основного
+	st := state.Load()
+	for i, key := range st.signatureKeys {
+		if hmac.Equal(signature, sign(key, value.String())) {
+			usage.record(st.signatureKeyIDs[i], i == 0)
+			return &models.UserCookie{User: models.User{ID: value.String()}, Sign: signature}, nil
+		}
 	}
 
-	return &models.UserCookie{User: models.User{ID: value.String()}, Sign: signature}, nil
-}
-
-func getUserCookie() *models.UserCookie {
-	userID := generateUserUUID()
-	return &models.UserCookie{User: models.User{ID: userID}, Sign: GetSignature(userID)}
+	return nil, nil
 }
 
 func generateUserUUID() string {
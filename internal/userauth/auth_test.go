@@ -5,18 +5,22 @@ import (
 	"encoding/base64"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
 	"github.com/Alheor/shorturl/internal/models"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInitSuccess(t *testing.T) {
 	cfg := config.Options{SignatureKey: `test_key`}
 	Init(&cfg)
-	assert.NotEmpty(t, signatureKey)
+	assert.NotEmpty(t, state.Load().signatureKeys[0])
 }
 
 func TestGetUserSuccess(t *testing.T) {
@@ -30,7 +34,7 @@ func TestGetUserSuccess(t *testing.T) {
 func TestGetSignatureSuccess(t *testing.T) {
 	cfg := config.Options{SignatureKey: `test_key`}
 	Init(&cfg)
-	assert.NotEmpty(t, signatureKey)
+	assert.NotEmpty(t, state.Load().signatureKeys[0])
 
 	sig := GetSignature(`test`)
 
@@ -103,3 +107,112 @@ func TestParseCookieValidSignature(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, userCookie)
 }
+
+func TestParseCookiePreviousKeySignature(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	userID := `6a30af51-b6ac-63ba-9e1c-5da06e1b610e`
+
+	err = Init(&config.Options{SignatureKey: `old_key`})
+	require.NoError(t, err)
+
+	cookie := &http.Cookie{Name: models.CookiesName}
+	cookie.Value = base64.StdEncoding.EncodeToString([]byte(string(GetSignature(userID)) + userID))
+
+	err = Init(&config.Options{SignatureKeys: []string{`new_key`, `old_key`}})
+	require.NoError(t, err)
+	defer Init(&config.Options{SignatureKey: `test_key`})
+
+	before := GetKeyUsage()[keyID([]byte(`old_key`))]
+
+	userCookie, err := parseCookie(cookie)
+	assert.Nil(t, err)
+	require.NotNil(t, userCookie)
+	assert.Equal(t, userID, userCookie.User.ID)
+	assert.Equal(t, before+1, GetKeyUsage()[keyID([]byte(`old_key`))])
+
+	keys := GetConfiguredKeyUsage()
+	assert.Equal(t, config.AuthTokenFormatCookie, keys.Format)
+	require.Len(t, keys.Keys, 2)
+	assert.Equal(t, keyID([]byte(`new_key`)), keys.Keys[0].KeyID)
+	assert.True(t, keys.Keys[0].Primary)
+	assert.Equal(t, keyID([]byte(`old_key`)), keys.Keys[1].KeyID)
+	assert.False(t, keys.Keys[1].Primary)
+	assert.Equal(t, before+1, keys.Keys[1].Validations)
+
+	var got *models.User
+	h := AuthHTTPHandler(func(resp http.ResponseWriter, req *http.Request) {
+		got = GetUser(req.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, `/`, nil)
+	req.AddCookie(cookie)
+	resp := httptest.NewRecorder()
+
+	h(resp, req)
+
+	require.NotNil(t, got)
+	assert.Equal(t, userID, got.ID)
+
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(string(GetSignature(userID))+userID)), cookies[0].Value)
+}
+
+func TestInitEmptySignatureKey(t *testing.T) {
+	err := Init(&config.Options{SignatureKeys: []string{`key`, ``}})
+	assert.Error(t, err)
+
+	err = Init(&config.Options{SignatureKey: `test_key`})
+	assert.NoError(t, err)
+}
+
+func TestAuthHTTPHandlerCookiePolicy(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	err = Init(&config.Options{
+		SignatureKey:   `test_key`,
+		CookieDomain:   `example.com`,
+		CookieMaxAge:   time.Hour,
+		CookieSameSite: `strict`,
+		EnableHTTPS:    true,
+	})
+	require.NoError(t, err)
+	defer Init(&config.Options{SignatureKey: `test_key`})
+
+	h := AuthHTTPHandler(func(resp http.ResponseWriter, req *http.Request) {})
+
+	resp := httptest.NewRecorder()
+	h(resp, httptest.NewRequest(http.MethodGet, `/`, nil))
+
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	assert.Equal(t, `example.com`, cookies[0].Domain)
+	assert.Equal(t, `/`, cookies[0].Path)
+	assert.Equal(t, 3600, cookies[0].MaxAge)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.True(t, cookies[0].Secure)
+	assert.True(t, cookies[0].HttpOnly)
+
+	resp = httptest.NewRecorder()
+	ClearToken(resp)
+
+	cookies = resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Empty(t, cookies[0].Value)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+}
+
+func TestInitInvalidSameSite(t *testing.T) {
+	err := Init(&config.Options{SignatureKey: `test_key`, CookieSameSite: `unknown`})
+	assert.Error(t, err)
+
+	err = Init(&config.Options{SignatureKey: `test_key`, CookieSameSite: `none`})
+	assert.Error(t, err)
+
+	err = Init(&config.Options{SignatureKey: `test_key`})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,345 @@
+package userauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var _ TokenCodec = (*jwtCodec)(nil)
+
+// Поддерживаемые алгоритмы подписи JWT.
+const (
+	// JWTAlgHS256 - HMAC-SHA256.
+	JWTAlgHS256 = `HS256`
+
+	// JWTAlgES256 - ECDSA P-256 SHA-256.
+	JWTAlgES256 = `ES256`
+)
+
+// Допустимое расхождение часов при проверке времени выпуска токена.
+const jwtClockSkew = time.Minute
+
+var jwtEncoding = base64.RawURLEncoding
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+type jwtClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+	Iat int64  `json:"iat"`
+	Jti string `json:"jti"`
+}
+
+// jwtKey - ключ подписи. Для ключей, которые используются только при проверке, sign равен nil.
+type jwtKey struct {
+	kid    string
+	sign   func(data []byte) ([]byte, error)
+	verify func(data, sig []byte) bool
+	// retireAt - время, после которого токены, подписанные предыдущим ключом, не принимаются.
+	retireAt time.Time
+}
+
+// jwtCodec - токен в формате JWT.
+type jwtCodec struct {
+	alg string
+	// keys - ключи подписи, первый ключ основной.
+	keys []jwtKey
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// newJWTCodec создание JWT кодека. Токены, подписанные предыдущими ключами, принимаются в течение gracePeriod
+// после ротации ключа.
+func newJWTCodec(alg string, rawKeys []config.JWTKey, ttl, gracePeriod time.Duration) (*jwtCodec, error) {
+	if len(rawKeys) == 0 {
+		return nil, errors.New(`JWT keys are empty`)
+	}
+
+	if ttl <= 0 {
+		return nil, errors.New(`JWT ttl must be positive`)
+	}
+
+	codec := &jwtCodec{alg: alg, ttl: ttl, now: time.Now}
+
+	for i, raw := range rawKeys {
+		var key jwtKey
+		var err error
+
+		switch alg {
+		case JWTAlgHS256:
+			key, err = newHS256Key(raw.Key)
+		case JWTAlgES256:
+			key, err = newES256Key(raw.Key)
+		default:
+			return nil, errors.New(`unsupported JWT algorithm "` + alg + `"`)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 && key.sign == nil {
+			return nil, errors.New(`primary JWT key must be able to sign`)
+		}
+
+		if i > 0 {
+			if raw.RotatedAt.IsZero() {
+				return nil, errors.New(`JWT key #` + strconv.Itoa(i+1) + ` requires rotation time`)
+			}
+
+			key.retireAt = raw.RotatedAt.Add(gracePeriod)
+		}
+
+		codec.keys = append(codec.keys, key)
+	}
+
+	return codec, nil
+}
+
+// Encode выпуск токена.
+func (c *jwtCodec) Encode(user *models.User) (string, error) {
+	now := c.now()
+	key := c.keys[0]
+
+	header, err := json.Marshal(jwtHeader{Alg: c.alg, Typ: `JWT`, Kid: key.kid})
+	if err != nil {
+		return ``, err
+	}
+
+	claims, err := json.Marshal(jwtClaims{
+		Sub: user.ID,
+		Exp: now.Add(c.ttl).Unix(),
+		Iat: now.Unix(),
+		Jti: uuid.New().String(),
+	})
+
+	if err != nil {
+		return ``, err
+	}
+
+	signingInput := jwtEncoding.EncodeToString(header) + `.` + jwtEncoding.EncodeToString(claims)
+
+	sig, err := key.sign([]byte(signingInput))
+	if err != nil {
+		return ``, err
+	}
+
+	return signingInput + `.` + jwtEncoding.EncodeToString(sig), nil
+}
+
+// Decode проверка токена.
+func (c *jwtCodec) Decode(token string) (*models.User, bool, error) {
+	parts := strings.Split(token, `.`)
+	if len(parts) != 3 {
+		return nil, false, nil
+	}
+
+	rawHeader, err := jwtEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, false, nil
+	}
+
+	var header jwtHeader
+	if err = json.Unmarshal(rawHeader, &header); err != nil || header.Alg != c.alg {
+		return nil, false, nil
+	}
+
+	sig, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, false, nil
+	}
+
+	keyIndex := c.verify(header.Kid, []byte(parts[0]+`.`+parts[1]), sig)
+	if keyIndex < 0 {
+		return nil, false, nil
+	}
+
+	now := c.now()
+
+	if keyIndex > 0 && now.After(c.keys[keyIndex].retireAt) {
+		return nil, false, nil
+	}
+
+	rawClaims, err := jwtEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false, nil
+	}
+
+	var claims jwtClaims
+	if err = json.Unmarshal(rawClaims, &claims); err != nil {
+		return nil, false, nil
+	}
+
+	expiresAt := time.Unix(claims.Exp, 0)
+	if !now.Before(expiresAt) || time.Unix(claims.Iat, 0).After(now.Add(jwtClockSkew)) {
+		return nil, false, nil
+	}
+
+	userID, err := uuid.Parse(claims.Sub)
+	if err != nil {
+		return nil, false, &models.EmptyUserIDErr{Err: err}
+	}
+
+	usage.record(c.keys[keyIndex].kid, keyIndex == 0)
+
+	// Токен подписан предыдущим ключом, либо истекает - выдаем новый
+	reissue := keyIndex > 0 || expiresAt.Sub(now) < c.ttl/2
+
+	return &models.User{ID: userID.String()}, reissue, nil
+}
+
+// verify проверка подписи, возвращает индекс ключа или -1.
+func (c *jwtCodec) verify(kid string, data, sig []byte) int {
+	for i, key := range c.keys {
+		if kid != `` && key.kid != kid {
+			continue
+		}
+
+		if key.verify(data, sig) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func newHS256Key(secret string) (jwtKey, error) {
+	if secret == `` {
+		return jwtKey{}, errors.New(`empty HS256 key`)
+	}
+
+	sign := func(data []byte) ([]byte, error) {
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write(data)
+
+		return h.Sum(nil), nil
+	}
+
+	return jwtKey{
+		kid:  keyID([]byte(secret)),
+		sign: sign,
+		verify: func(data, sig []byte) bool {
+			expected, _ := sign(data)
+			return hmac.Equal(sig, expected)
+		},
+	}, nil
+}
+
+func newES256Key(encoded string) (jwtKey, error) {
+	rawPEM, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return jwtKey{}, err
+	}
+
+	block, _ := pem.Decode(rawPEM)
+	if block == nil {
+		return jwtKey{}, errors.New(`invalid ES256 key: PEM block not found`)
+	}
+
+	var privateKey *ecdsa.PrivateKey
+	var publicKey *ecdsa.PublicKey
+
+	switch block.Type {
+	case `EC PRIVATE KEY`:
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case `PRIVATE KEY`:
+		var key any
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if privateKey, ok = key.(*ecdsa.PrivateKey); !ok {
+				err = errors.New(`invalid ES256 key: not an ECDSA key`)
+			}
+		}
+	case `PUBLIC KEY`:
+		var key any
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err == nil {
+			var ok bool
+			if publicKey, ok = key.(*ecdsa.PublicKey); !ok {
+				err = errors.New(`invalid ES256 key: not an ECDSA key`)
+			}
+		}
+	default:
+		err = errors.New(`invalid ES256 key: unsupported PEM type "` + block.Type + `"`)
+	}
+
+	if err != nil {
+		return jwtKey{}, err
+	}
+
+	if privateKey != nil {
+		publicKey = &privateKey.PublicKey
+	}
+
+	if publicKey.Curve != elliptic.P256() {
+		return jwtKey{}, errors.New(`invalid ES256 key: curve must be P-256`)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return jwtKey{}, err
+	}
+
+	key := jwtKey{
+		kid: keyID(publicDER),
+		verify: func(data, sig []byte) bool {
+			if len(sig) != 64 {
+				return false
+			}
+
+			digest := sha256.Sum256(data)
+			r := new(big.Int).SetBytes(sig[:32])
+			s := new(big.Int).SetBytes(sig[32:])
+
+			return ecdsa.Verify(publicKey, digest[:], r, s)
+		},
+	}
+
+	if privateKey != nil {
+		key.sign = func(data []byte) ([]byte, error) {
+			digest := sha256.Sum256(data)
+
+			r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+			if err != nil {
+				return nil, err
+			}
+
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+
+			return sig, nil
+		}
+	}
+
+	return key, nil
+}
+
+// keyID идентификатор ключа для заголовка kid.
+func keyID(material []byte) string {
+	sum := sha256.Sum256(material)
+	return hex.EncodeToString(sum[:8])
+}
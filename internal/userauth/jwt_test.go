@@ -0,0 +1,213 @@
+package userauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserID = `6a30af51-b6ac-63ba-9e1c-5da06e1b610e`
+
+func TestJWTHS256EncodeDecodeSuccess(t *testing.T) {
+	codec, err := newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `test_key`}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token, err := codec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(token, `.`), 3)
+
+	user, reissue, err := codec.Decode(token)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, testUserID, user.ID)
+	assert.False(t, reissue)
+}
+
+func TestJWTES256EncodeDecodeSuccess(t *testing.T) {
+	codec, err := newJWTCodec(JWTAlgES256, []config.JWTKey{{Key: generateES256Key(t)}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token, err := codec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	user, reissue, err := codec.Decode(token)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, testUserID, user.ID)
+	assert.False(t, reissue)
+}
+
+func TestJWTDecodeInvalidToken(t *testing.T) {
+	codec, err := newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `test_key`}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token, err := codec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	parts := strings.Split(token, `.`)
+	none := jwtEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: `not a jwt`, token: `invalid`},
+		{name: `tampered claims`, token: parts[0] + `.` + jwtEncoding.EncodeToString([]byte(`{"sub":"x"}`)) + `.` + parts[2]},
+		{name: `invalid signature`, token: parts[0] + `.` + parts[1] + `.` + jwtEncoding.EncodeToString([]byte(`signature`))},
+		{name: `alg none`, token: none + `.` + parts[1] + `.`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, _, err := codec.Decode(tt.token)
+			assert.NoError(t, err)
+			assert.Nil(t, user)
+		})
+	}
+}
+
+func TestJWTDecodeExpiredToken(t *testing.T) {
+	codec, err := newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `test_key`}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token, err := codec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	codec.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	user, _, err := codec.Decode(token)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+}
+
+func TestJWTDecodeReissueHalfExpiredToken(t *testing.T) {
+	codec, err := newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `test_key`}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token, err := codec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	codec.now = func() time.Time { return time.Now().Add(40 * time.Minute) }
+
+	user, reissue, err := codec.Decode(token)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.True(t, reissue)
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	oldCodec, err := newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `old_key`}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	token, err := oldCodec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	rotatedAt := time.Now().Add(-30 * time.Minute)
+
+	codec, err := newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `new_key`}, {Key: `old_key`, RotatedAt: rotatedAt}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	user, reissue, err := codec.Decode(token)
+	require.NoError(t, err)
+	require.NotNil(t, user)
+	assert.Equal(t, testUserID, user.ID)
+	assert.True(t, reissue)
+
+	codec.now = func() time.Time { return rotatedAt.Add(time.Hour + time.Second) }
+
+	user, _, err = codec.Decode(token)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+
+	// Срок приема отсчитывается от ротации ключа, а не от создания кодека при перезапуске
+	codec, err = newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `new_key`}, {Key: `old_key`, RotatedAt: time.Now().Add(-2 * time.Hour)}}, time.Hour, time.Hour)
+	require.NoError(t, err)
+
+	user, _, err = codec.Decode(token)
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+}
+
+func TestJWTInvalidConfig(t *testing.T) {
+	_, err := newJWTCodec(JWTAlgHS256, nil, time.Hour, time.Hour)
+	assert.Error(t, err)
+
+	_, err = newJWTCodec(`RS256`, []config.JWTKey{{Key: `key`}}, time.Hour, time.Hour)
+	assert.Error(t, err)
+
+	_, err = newJWTCodec(JWTAlgES256, []config.JWTKey{{Key: `not a key`}}, time.Hour, time.Hour)
+	assert.Error(t, err)
+
+	_, err = newJWTCodec(JWTAlgHS256, []config.JWTKey{{Key: `new_key`}, {Key: `old_key`}}, time.Hour, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestAuthHTTPHandlerJWTFromHeader(t *testing.T) {
+	err := logger.Init(nil)
+	require.NoError(t, err)
+
+	cfg := config.Options{
+		SignatureKey:      `test_key`,
+		AuthTokenFormat:   config.AuthTokenFormatJWT,
+		JWTAlgorithm:      JWTAlgHS256,
+		JWTTTL:            time.Hour,
+		JWTKeyGracePeriod: time.Hour,
+	}
+
+	err = Init(&cfg)
+	require.NoError(t, err)
+	defer Init(&config.Options{SignatureKey: `test_key`})
+
+	token, err := state.Load().codec.Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	var got *models.User
+	h := AuthHTTPHandler(func(resp http.ResponseWriter, req *http.Request) {
+		got = GetUser(req.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, `/`, nil)
+	req.Header.Set(HeaderAuthorization, `Bearer `+token)
+	resp := httptest.NewRecorder()
+
+	h(resp, req)
+
+	require.NotNil(t, got)
+	assert.Equal(t, testUserID, got.ID)
+	assert.Empty(t, resp.Header().Get(`Set-Cookie`))
+
+	req = httptest.NewRequest(http.MethodGet, `/`, nil)
+	resp = httptest.NewRecorder()
+
+	h(resp, req)
+
+	require.NotNil(t, got)
+	assert.NotEqual(t, testUserID, got.ID)
+	assert.NotEmpty(t, resp.Header().Get(`Set-Cookie`))
+	assert.True(t, strings.HasPrefix(resp.Header().Get(HeaderAuthorization), `Bearer `))
+}
+
+func generateES256Key(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: `EC PRIVATE KEY`, Bytes: der}))
+}
@@ -0,0 +1,95 @@
+package userauth
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/logger"
+	"github.com/Alheor/shorturl/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// Каждая n-я проверка предыдущим ключом попадает в лог.
+const keyUsageLogEvery = 100
+
+// keyUsage - счетчики успешных проверок токенов по ключам подписи.
+type keyUsage struct {
+	mu       sync.RWMutex
+	counters map[string]*atomic.Int64
+}
+
+var usage = &keyUsage{counters: make(map[string]*atomic.Int64)}
+
+// GetKeyUsage - количество успешных проверок токенов по идентификаторам ключей подписи.
+// Позволяет понять, когда предыдущий ключ больше не используется и его можно удалить из конфигурации.
+func GetKeyUsage() map[string]int64 {
+	usage.mu.RLock()
+	defer usage.mu.RUnlock()
+
+	res := make(map[string]int64, len(usage.counters))
+	for kid, counter := range usage.counters {
+		res[kid] = counter.Load()
+	}
+
+	return res
+}
+
+// GetConfiguredKeyUsage - количество успешных проверок токенов по настроенным ключам подписи текущего формата токена.
+// Ключи перечисляются в порядке конфигурации, первый ключ основной.
+func GetConfiguredKeyUsage() models.APIAdminKeyUsageResponse {
+	st := state.Load()
+
+	res := models.APIAdminKeyUsageResponse{Format: config.AuthTokenFormatCookie}
+	kids := st.signatureKeyIDs
+
+	if codec, isJWT := st.codec.(*jwtCodec); isJWT {
+		res.Format = config.AuthTokenFormatJWT
+		kids = make([]string, 0, len(codec.keys))
+
+		for _, key := range codec.keys {
+			kids = append(kids, key.kid)
+		}
+	}
+
+	usage.mu.RLock()
+	defer usage.mu.RUnlock()
+
+	res.Keys = make([]models.APIAdminKeyUsage, 0, len(kids))
+	for i, kid := range kids {
+		item := models.APIAdminKeyUsage{KeyID: kid, Primary: i == 0}
+		if counter, exists := usage.counters[kid]; exists {
+			item.Validations = counter.Load()
+		}
+
+		res.Keys = append(res.Keys, item)
+	}
+
+	return res
+}
+
+// record учет успешной проверки токена ключом kid.
+func (u *keyUsage) record(kid string, primary bool) {
+	u.mu.RLock()
+	counter, exists := u.counters[kid]
+	u.mu.RUnlock()
+
+	if !exists {
+		u.mu.Lock()
+		if counter, exists = u.counters[kid]; !exists {
+			counter = &atomic.Int64{}
+			u.counters[kid] = counter
+		}
+		u.mu.Unlock()
+	}
+
+	count := counter.Add(1)
+
+	if !primary && (count == 1 || count%keyUsageLogEvery == 0) {
+		logger.Info(`user token validated by previous signature key`,
+			zap.String("kid", kid),
+			zap.Int64("validations", count),
+		)
+	}
+}
@@ -0,0 +1,44 @@
+package userauth
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/Alheor/shorturl/internal/models"
+)
+
+var _ TokenCodec = (*cookieCodec)(nil)
+
+// TokenCodec - формат токена пользователя.
+type TokenCodec interface {
+	// Encode - выпуск токена для пользователя.
+	Encode(user *models.User) (string, error)
+
+	// Decode - проверка токена и получение пользователя.
+	// Если токен невалиден, то возвращается nil без ошибки.
+	// Признак reissue означает, что токен валиден, но его нужно перевыпустить (например, подписан старым ключом).
+	Decode(token string) (user *models.User, reissue bool, err error)
+}
+
+// cookieCodec - токен в виде подписи HMAC-SHA256 и UUID пользователя, закодированных в base64.
+// Cookie, подписанные предыдущими ключами, перевыпускаются с основным ключом.
+type cookieCodec struct{}
+
+// Encode выпуск токена.
+func (c *cookieCodec) Encode(user *models.User) (string, error) {
+	cookieValue := string(GetSignature(user.ID)) + user.ID
+	return base64.StdEncoding.EncodeToString([]byte(cookieValue)), nil
+}
+
+// Decode проверка токена.
+func (c *cookieCodec) Decode(token string) (*models.User, bool, error) {
+	userCookie, err := parseCookie(&http.Cookie{Name: models.CookiesName, Value: token})
+	if err != nil || userCookie == nil {
+		return nil, false, err
+	}
+
+	reissue := !hmac.Equal(userCookie.Sign, GetSignature(userCookie.User.ID))
+
+	return &userCookie.User, reissue, nil
+}
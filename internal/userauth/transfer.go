@@ -0,0 +1,101 @@
+package userauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Назначение подписи токена переноса, чтобы его нельзя было использовать как cookie.
+const transferTokenPurpose = `transfer`
+
+// Время жизни токена переноса по умолчанию.
+const defaultTransferTokenTTL = 10 * time.Minute
+
+// ErrInvalidTransferToken - токен переноса невалиден, истек или уже использован.
+var ErrInvalidTransferToken = errors.New(`invalid or expired transfer token`)
+
+// ErrOwnTransferToken - токен переноса выпущен пользователем, который его предъявил.
+var ErrOwnTransferToken = errors.New(`token belongs to current user`)
+
+// NewTransferToken выпуск короткоживущего одноразового токена переноса URL пользователя.
+// Токен подписывается основным ключом подписи cookie.
+func NewTransferToken(user *models.User) (token string, expiresAt time.Time) {
+	st := state.Load()
+	expiresAt = time.Now().Add(st.transferTokenTTL)
+
+	payload := user.ID + `|` + strconv.FormatInt(expiresAt.Unix(), 10) + `|` + uuid.New().String()
+	sig := signTransfer(st.signatureKeys[0], payload)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + `.` + base64.RawURLEncoding.EncodeToString(sig), expiresAt
+}
+
+// ParseTransferToken проверка подписи и срока действия токена переноса, предъявленного пользователем claimer.
+// Токен, предъявленный выпустившим его пользователем, не принимается.
+// Использованным токен отмечает репозиторий при переносе URL, поэтому повторное использование
+// проверяется там же.
+func ParseTransferToken(token string, claimer *models.User) (*models.TransferToken, error) {
+	rawPayload, rawSig, found := strings.Cut(token, `.`)
+	if !found {
+		return nil, ErrInvalidTransferToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(rawPayload)
+	if err != nil {
+		return nil, ErrInvalidTransferToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(rawSig)
+	if err != nil {
+		return nil, ErrInvalidTransferToken
+	}
+
+	valid := false
+	for _, key := range state.Load().signatureKeys {
+		if hmac.Equal(sig, signTransfer(key, string(payload))) {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		return nil, ErrInvalidTransferToken
+	}
+
+	fields := strings.Split(string(payload), `|`)
+	if len(fields) != 3 {
+		return nil, ErrInvalidTransferToken
+	}
+
+	exp, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || !time.Now().Before(time.Unix(exp, 0)) {
+		return nil, ErrInvalidTransferToken
+	}
+
+	id, err := uuid.Parse(fields[0])
+	if err != nil {
+		return nil, ErrInvalidTransferToken
+	}
+
+	if id.String() == claimer.ID {
+		return nil, ErrOwnTransferToken
+	}
+
+	return &models.TransferToken{ID: fields[2], From: &models.User{ID: id.String()}, ExpiresAt: time.Unix(exp, 0)}, nil
+}
+
+func signTransfer(key []byte, payload string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(transferTokenPurpose))
+	h.Write([]byte(payload))
+
+	return h.Sum(nil)
+}
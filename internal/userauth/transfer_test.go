@@ -0,0 +1,95 @@
+package userauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Alheor/shorturl/internal/config"
+	"github.com/Alheor/shorturl/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// claimer - пользователь, предъявляющий токен переноса.
+var claimer = &models.User{ID: `1c7a5f3e-40a2-4b8e-9d6f-2e5b8c9a0d13`}
+
+func TestTransferTokenSuccess(t *testing.T) {
+	err := Init(&config.Options{SignatureKey: `test_key`, TransferTokenTTL: time.Minute})
+	require.NoError(t, err)
+
+	token, expiresAt := NewTransferToken(&models.User{ID: testUserID})
+	assert.True(t, expiresAt.After(time.Now()))
+
+	_, err = ParseTransferToken(token, &models.User{ID: testUserID})
+	assert.ErrorIs(t, err, ErrOwnTransferToken)
+
+	parsed, err := ParseTransferToken(token, claimer)
+	require.NoError(t, err)
+	assert.Equal(t, testUserID, parsed.From.ID)
+	assert.Equal(t, expiresAt.Unix(), parsed.ExpiresAt.Unix())
+	assert.NotEmpty(t, parsed.ID)
+
+	// Использованным токен отмечает репозиторий при переносе URL
+	again, err := ParseTransferToken(token, claimer)
+	require.NoError(t, err)
+	assert.Equal(t, parsed.ID, again.ID)
+
+	other, _ := NewTransferToken(&models.User{ID: testUserID})
+
+	parsed, err = ParseTransferToken(other, claimer)
+	require.NoError(t, err)
+	assert.NotEqual(t, again.ID, parsed.ID)
+}
+
+func TestTransferTokenInvalid(t *testing.T) {
+	err := Init(&config.Options{SignatureKey: `test_key`, TransferTokenTTL: time.Minute})
+	require.NoError(t, err)
+
+	token, _ := NewTransferToken(&models.User{ID: testUserID})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: `empty`, token: ``},
+		{name: `without signature`, token: `payload`},
+		{name: `tampered`, token: token + `a`},
+		{name: `user cookie`, token: getCookieValue(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTransferToken(tt.token, claimer)
+			assert.ErrorIs(t, err, ErrInvalidTransferToken)
+		})
+	}
+
+	err = Init(&config.Options{SignatureKey: `other_key`})
+	require.NoError(t, err)
+	defer Init(&config.Options{SignatureKey: `test_key`})
+
+	_, err = ParseTransferToken(token, claimer)
+	assert.ErrorIs(t, err, ErrInvalidTransferToken)
+}
+
+func TestTransferTokenExpired(t *testing.T) {
+	err := Init(&config.Options{SignatureKey: `test_key`})
+	require.NoError(t, err)
+
+	st := *state.Load()
+	st.transferTokenTTL = -time.Second
+	state.Store(&st)
+
+	token, _ := NewTransferToken(&models.User{ID: testUserID})
+
+	_, err = ParseTransferToken(token, claimer)
+	assert.ErrorIs(t, err, ErrInvalidTransferToken)
+}
+
+func getCookieValue(t *testing.T) string {
+	token, err := (&cookieCodec{}).Encode(&models.User{ID: testUserID})
+	require.NoError(t, err)
+
+	return token
+}